@@ -24,6 +24,11 @@ type batch struct {
 	reqs   []*request
 	clear  func(b *batch)
 	locker sync.Locker
+
+	// timerStarted is true once the batch is set to trigger after the
+	// scheduler's regular duration. It is guarded by the scheduler's
+	// mutex.
+	timerStarted bool
 }
 
 // trigger is the entry point for the batch and ensures that run is started at
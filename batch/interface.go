@@ -30,6 +30,11 @@ type Request struct {
 	// request when it comes in. This means that it can be scheduled later,
 	// allowing larger batches.
 	lazy bool
+
+	// bulk should be true if the request is part of a bulk import, such
+	// as the initial sync of the channel graph. Bulk requests are lazy
+	// and may be held back for longer to build even larger batches.
+	bulk bool
 }
 
 // SchedulerOption is a type that can be used to supply options to a scheduled
@@ -44,6 +49,16 @@ func LazyAdd() SchedulerOption {
 	}
 }
 
+// BulkAdd will make the request be executed lazily as part of a bulk import.
+// Batches that start with a bulk request wait for the scheduler's bulk
+// duration, if one is set, before they are committed.
+func BulkAdd() SchedulerOption {
+	return func(r *Request) {
+		r.lazy = true
+		r.bulk = true
+	}
+}
+
 // Scheduler abstracts a generic batching engine that accumulates an incoming
 // set of Requests, executes them, and returns the error from the operation.
 type Scheduler interface {
@@ -17,23 +17,57 @@ type TimeScheduler struct {
 	locker   sync.Locker
 	duration time.Duration
 
+	// bulkDuration is the duration that a batch started by a bulk request
+	// waits before it is committed. If zero, duration is used.
+	bulkDuration time.Duration
+
+	// maxBatchSize is the number of requests after which a batch is
+	// committed without waiting for its duration to elapse. If zero,
+	// batches aren't limited in size.
+	maxBatchSize int
+
 	mu sync.Mutex
 	b  *batch
 }
 
+// TimeSchedulerOption is a functional option that can be used to modify the
+// default behavior of a TimeScheduler.
+type TimeSchedulerOption func(s *TimeScheduler)
+
+// WithBulkDuration sets the duration that a batch started by a bulk request
+// waits for other requests to join before it is committed.
+func WithBulkDuration(duration time.Duration) TimeSchedulerOption {
+	return func(s *TimeScheduler) {
+		s.bulkDuration = duration
+	}
+}
+
+// WithMaxBatchSize sets the number of requests after which a batch is
+// committed right away, regardless of how long it has been waiting.
+func WithMaxBatchSize(size int) TimeSchedulerOption {
+	return func(s *TimeScheduler) {
+		s.maxBatchSize = size
+	}
+}
+
 // NewTimeScheduler initializes a new TimeScheduler with a fixed duration at
 // which to schedule batches. If the operation needs to modify a higher-level
 // cache, the cache's lock should be provided to so that external consistency
 // can be maintained, as successful db operations will cause a request's
 // OnCommit method to be executed while holding this lock.
 func NewTimeScheduler(db kvdb.Backend, locker sync.Locker,
-	duration time.Duration) *TimeScheduler {
+	duration time.Duration, opts ...TimeSchedulerOption) *TimeScheduler {
 
-	return &TimeScheduler{
+	s := &TimeScheduler{
 		db:       db,
 		locker:   locker,
 		duration: duration,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 // Execute schedules the provided request for batch execution along with other
@@ -49,7 +83,9 @@ func (s *TimeScheduler) Execute(r *Request) error {
 	}
 
 	// Add the request to the current batch. If the batch has been cleared
-	// or no batch exists, create a new one.
+	// or no batch exists, create a new one. A batch started by a bulk
+	// request waits for the bulk duration instead, as long as no regular
+	// requests join it.
 	s.mu.Lock()
 	if s.b == nil {
 		s.b = &batch{
@@ -57,13 +93,32 @@ func (s *TimeScheduler) Execute(r *Request) error {
 			clear:  s.clear,
 			locker: s.locker,
 		}
-		time.AfterFunc(s.duration, s.b.trigger)
+
+		if r.bulk && s.bulkDuration > 0 {
+			time.AfterFunc(s.bulkDuration, s.b.trigger)
+		} else {
+			s.b.timerStarted = true
+			time.AfterFunc(s.duration, s.b.trigger)
+		}
 	}
 	s.b.reqs = append(s.b.reqs, &req)
 
+	switch {
 	// If this is a non-lazy request, we'll execute the batch immediately.
-	if !r.lazy {
+	case !r.lazy:
 		go s.b.trigger()
+
+	// If the batch has reached its maximum size, we'll execute it right
+	// away and start a new batch for the next request.
+	case s.maxBatchSize > 0 && len(s.b.reqs) >= s.maxBatchSize:
+		go s.b.trigger()
+		s.b = nil
+
+	// A regular lazy request shouldn't wait longer than the regular
+	// duration, even if it joined a bulk batch.
+	case !r.bulk && !s.b.timerStarted:
+		s.b.timerStarted = true
+		time.AfterFunc(s.duration, s.b.trigger)
 	}
 
 	s.mu.Unlock()
@@ -0,0 +1,280 @@
+package batch
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/stretchr/testify/require"
+)
+
+var testBucket = []byte("test-bucket")
+
+// countingBackend is a kvdb.Backend that counts the number of write
+// transactions and can be set to fail them, simulating a crash before the
+// transaction is committed.
+type countingBackend struct {
+	kvdb.Backend
+
+	updates atomic.Int32
+
+	failCommit atomic.Bool
+}
+
+var errCommitFailed = errors.New("commit failed")
+
+// Update counts the write transaction and executes it, unless the backend is
+// set to fail, in which case the transaction is rolled back.
+func (c *countingBackend) Update(f func(tx kvdb.RwTx) error,
+	reset func()) error {
+
+	c.updates.Add(1)
+
+	return c.Backend.Update(func(tx kvdb.RwTx) error {
+		if err := f(tx); err != nil {
+			return err
+		}
+
+		if c.failCommit.Load() {
+			return errCommitFailed
+		}
+
+		return nil
+	}, reset)
+}
+
+// newCountingBackend creates a new test backend with an empty test bucket.
+func newCountingBackend(t testing.TB) *countingBackend {
+	t.Helper()
+
+	backend, cleanup, err := kvdb.GetTestBackend(t.TempDir(), "batch")
+	require.NoError(t, err)
+	t.Cleanup(cleanup)
+
+	err = kvdb.Update(backend, func(tx kvdb.RwTx) error {
+		_, err := tx.CreateTopLevelBucket(testBucket)
+		return err
+	}, func() {})
+	require.NoError(t, err)
+
+	return &countingBackend{Backend: backend}
+}
+
+// putRequest returns a request that stores the given key in the test bucket.
+func putRequest(key uint64, opts ...SchedulerOption) *Request {
+	var k [8]byte
+	binary.BigEndian.PutUint64(k[:], key)
+
+	r := &Request{
+		Update: func(tx kvdb.RwTx) error {
+			return tx.ReadWriteBucket(testBucket).Put(k[:], k[:])
+		},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// numKeys returns the number of keys stored in the test bucket.
+func numKeys(t *testing.T, db kvdb.Backend) int {
+	t.Helper()
+
+	var n int
+	err := kvdb.View(db, func(tx kvdb.RTx) error {
+		return tx.ReadBucket(testBucket).ForEach(func(_, _ []byte) error {
+			n++
+			return nil
+		})
+	}, func() {
+		n = 0
+	})
+	require.NoError(t, err)
+
+	return n
+}
+
+// executeConcurrently executes the given number of requests concurrently and
+// returns the errors they resulted in. If an interval is given, the requests
+// are started one after the other, with the interval in between.
+func executeConcurrently(s Scheduler, numRequests int, interval time.Duration,
+	opts ...SchedulerOption) []error {
+
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, numRequests)
+	)
+	for i := 0; i < numRequests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			errs[i] = s.Execute(putRequest(uint64(i), opts...))
+		}(i)
+
+		if interval > 0 {
+			time.Sleep(interval)
+		}
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// TestTimeSchedulerMaxBatchSize tests that a batch is committed as soon as it
+// reaches the maximum batch size, without waiting for its duration.
+func TestTimeSchedulerMaxBatchSize(t *testing.T) {
+	t.Parallel()
+
+	const (
+		numRequests  = 100
+		maxBatchSize = 10
+	)
+
+	db := newCountingBackend(t)
+	scheduler := NewTimeScheduler(
+		db, nil, time.Hour, WithMaxBatchSize(maxBatchSize),
+	)
+
+	errs := executeConcurrently(scheduler, numRequests, 0, LazyAdd())
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	require.EqualValues(t, numRequests/maxBatchSize, db.updates.Load())
+	require.Equal(t, numRequests, numKeys(t, db))
+}
+
+// TestTimeSchedulerBulkDuration tests that bulk requests wait for the bulk
+// duration, while regular lazy requests that join a bulk batch don't wait
+// longer than the regular duration.
+func TestTimeSchedulerBulkDuration(t *testing.T) {
+	t.Parallel()
+
+	db := newCountingBackend(t)
+	scheduler := NewTimeScheduler(
+		db, nil, 10*time.Millisecond,
+		WithBulkDuration(time.Hour),
+	)
+
+	// Start a bulk request, which shouldn't be committed for an hour.
+	bulkErr := make(chan error, 1)
+	go func() {
+		bulkErr <- scheduler.Execute(putRequest(0, BulkAdd()))
+	}()
+
+	require.Eventually(t, func() bool {
+		scheduler.mu.Lock()
+		defer scheduler.mu.Unlock()
+
+		return scheduler.b != nil && len(scheduler.b.reqs) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	select {
+	case err := <-bulkErr:
+		t.Fatalf("bulk request executed early: %v", err)
+
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// A regular lazy request joins the batch, which should now be
+	// committed after the regular duration, including the bulk request.
+	require.NoError(t, scheduler.Execute(putRequest(1, LazyAdd())))
+
+	select {
+	case err := <-bulkErr:
+		require.NoError(t, err)
+
+	case <-time.After(time.Second):
+		t.Fatalf("bulk request not executed")
+	}
+
+	require.EqualValues(t, 1, db.updates.Load())
+	require.Equal(t, 2, numKeys(t, db))
+}
+
+// TestTimeSchedulerCommitFailure tests that no request of a batch returns
+// successfully if the batch fails to commit, so that callers never consider
+// data persisted that wasn't.
+func TestTimeSchedulerCommitFailure(t *testing.T) {
+	t.Parallel()
+
+	const numRequests = 10
+
+	db := newCountingBackend(t)
+	db.failCommit.Store(true)
+
+	scheduler := NewTimeScheduler(
+		db, nil, time.Hour, WithMaxBatchSize(numRequests),
+	)
+
+	errs := executeConcurrently(scheduler, numRequests, 0, BulkAdd())
+	for _, err := range errs {
+		require.ErrorIs(t, err, errCommitFailed)
+	}
+
+	require.EqualValues(t, 1, db.updates.Load())
+	require.Zero(t, numKeys(t, db))
+}
+
+// BenchmarkTimeScheduler compares the number of transactions needed to write
+// a steady stream of requests, such as the announcements received during the
+// initial graph sync, with regular lazy batching and with bulk batching of
+// size-limited batches.
+func BenchmarkTimeScheduler(b *testing.B) {
+	const (
+		numRequests = 1000
+		interval    = 50 * time.Microsecond
+	)
+
+	benchmarks := []struct {
+		name string
+		opts []TimeSchedulerOption
+		op   SchedulerOption
+	}{
+		{
+			name: "lazy",
+			op:   LazyAdd(),
+		},
+		{
+			name: "bulk",
+			opts: []TimeSchedulerOption{
+				WithBulkDuration(time.Second),
+				WithMaxBatchSize(numRequests / 4),
+			},
+			op: BulkAdd(),
+		},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			db := newCountingBackend(b)
+
+			for i := 0; i < b.N; i++ {
+				scheduler := NewTimeScheduler(
+					db, nil, time.Millisecond, bm.opts...,
+				)
+
+				errs := executeConcurrently(
+					scheduler, numRequests, interval, bm.op,
+				)
+				for _, err := range errs {
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+
+			b.ReportMetric(
+				float64(db.updates.Load())/float64(b.N),
+				fmt.Sprintf("txns/%d-requests", numRequests),
+			)
+		})
+	}
+}
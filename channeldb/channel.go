@@ -12,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcec/v2/schnorr/musig2"
@@ -25,6 +26,7 @@ import (
 	"github.com/lightningnetwork/lnd/input"
 	"github.com/lightningnetwork/lnd/keychain"
 	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/shachain"
 	"github.com/lightningnetwork/lnd/tlv"
@@ -132,6 +134,12 @@ var (
 	// shutdown on re-establish.
 	shutdownInfoKey = []byte("shutdown-info-key")
 
+	// coopCloseTelemetryKey points to the serialised telemetry of the
+	// cooperative close fee negotiation of a channel. It's written once
+	// the negotiation has completed, and copied into the close summary
+	// once the closing transaction confirms.
+	coopCloseTelemetryKey = []byte("coop-close-telemetry-key")
+
 	// commitDiffKey stores the current pending commitment state we've
 	// extended to the remote party (if any). Each time we propose a new
 	// state, we store the information necessary to reconstruct this state
@@ -203,6 +211,10 @@ var (
 	// persisted for a channel.
 	ErrNoShutdownInfo = errors.New("no shutdown info")
 
+	// ErrNoCoopCloseTelemetry is returned when no cooperative close
+	// telemetry has been persisted for a channel.
+	ErrNoCoopCloseTelemetry = errors.New("no coop close telemetry")
+
 	// ErrNoRestoredChannelMutation is returned when a caller attempts to
 	// mutate a channel that's been recovered.
 	ErrNoRestoredChannelMutation = fmt.Errorf("cannot mutate restored " +
@@ -3494,6 +3506,78 @@ type ChannelCloseSummary struct {
 	// LastChanSyncMsg is the ChannelReestablish message for this channel
 	// for the state at the point where it was closed.
 	LastChanSyncMsg *lnwire.ChannelReestablish
+
+	// CoopCloseTelemetry describes how the fee negotiation of a
+	// cooperative close went. It's only set for cooperative closes that
+	// were negotiated by this node after the telemetry was introduced.
+	CoopCloseTelemetry *CoopCloseTelemetry
+}
+
+// MarkCoopCloseTelemetry persists the telemetry of the completed cooperative
+// close fee negotiation of this channel, so it can be added to the close
+// summary once the closing transaction confirms.
+func (c *OpenChannel) MarkCoopCloseTelemetry(t *CoopCloseTelemetry) error {
+	c.Lock()
+	defer c.Unlock()
+
+	var b bytes.Buffer
+	if err := t.encode(&b); err != nil {
+		return err
+	}
+
+	return kvdb.Update(c.Db.backend, func(tx kvdb.RwTx) error {
+		chanBucket, err := fetchChanBucketRw(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		return chanBucket.Put(coopCloseTelemetryKey, b.Bytes())
+	}, func() {})
+}
+
+// CoopCloseTelemetry decodes the cooperative close telemetry stored for this
+// channel and returns the result. If no telemetry has been persisted for this
+// channel then the ErrNoCoopCloseTelemetry error is returned.
+func (c *OpenChannel) CoopCloseTelemetry() (*CoopCloseTelemetry, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	var telemetry *CoopCloseTelemetry
+	err := kvdb.View(c.Db.backend, func(tx kvdb.RTx) error {
+		chanBucket, err := fetchChanBucket(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrNoChanDBExists),
+			errors.Is(err, ErrNoActiveChannels),
+			errors.Is(err, ErrChannelNotFound):
+
+			return ErrNoCoopCloseTelemetry
+		default:
+			return err
+		}
+
+		telemetryBytes := chanBucket.Get(coopCloseTelemetryKey)
+		if telemetryBytes == nil {
+			return ErrNoCoopCloseTelemetry
+		}
+
+		telemetry, err = decodeCoopCloseTelemetry(
+			bytes.NewReader(telemetryBytes),
+		)
+
+		return err
+	}, func() {
+		telemetry = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return telemetry, nil
 }
 
 // CloseChannel closes a previously active Lightning channel. Closing a channel
@@ -3876,6 +3960,17 @@ func serializeChannelCloseSummary(w io.Writer, cs *ChannelCloseSummary) error {
 		}
 	}
 
+	// Write whether the coop close telemetry is present.
+	err = WriteElements(w, cs.CoopCloseTelemetry != nil)
+	if err != nil {
+		return err
+	}
+
+	// Write the coop close telemetry, if present.
+	if cs.CoopCloseTelemetry != nil {
+		return cs.CoopCloseTelemetry.encode(w)
+	}
+
 	return nil
 }
 
@@ -3957,6 +4052,23 @@ func deserializeCloseChannelSummary(r io.Reader) (*ChannelCloseSummary, error) {
 		c.LastChanSyncMsg = chanSync
 	}
 
+	// Check if we have coop close telemetry to read. Summaries written
+	// before the telemetry was introduced end here.
+	var hasCoopCloseTelemetry bool
+	err = ReadElements(r, &hasCoopCloseTelemetry)
+	if err == io.EOF {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if hasCoopCloseTelemetry {
+		c.CoopCloseTelemetry, err = decodeCoopCloseTelemetry(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return c, nil
 }
 
@@ -4516,3 +4628,166 @@ func decodeShutdownInfo(b []byte) (*ShutdownInfo, error) {
 
 	return &info, err
 }
+
+// CoopCloseTelemetry records how the fee negotiation of a cooperative close
+// went, so operators can tell why a close took many rounds or settled on a
+// high fee.
+type CoopCloseTelemetry struct {
+	// Rounds is the number of ClosingSigned messages we received from the
+	// remote party during the fee negotiation.
+	Rounds uint32
+
+	// LocalProposals are the absolute fees we proposed, in the order we
+	// proposed them.
+	LocalProposals []btcutil.Amount
+
+	// RemoteProposals are the absolute fees the remote party proposed, in
+	// the order they proposed them.
+	RemoteProposals []btcutil.Amount
+
+	// FinalFeeRate is the fee rate of the closing transaction both
+	// parties agreed on.
+	FinalFeeRate chainfee.SatPerKWeight
+
+	// Duration is the wall-clock time the fee negotiation took.
+	Duration time.Duration
+}
+
+// encode serialises the CoopCloseTelemetry to the given io.Writer as a length
+// prefixed TLV stream.
+func (t *CoopCloseTelemetry) encode(w io.Writer) error {
+	var (
+		rounds       = t.Rounds
+		feeRate      = uint64(t.FinalFeeRate)
+		duration     = uint64(t.Duration)
+		local        = feeProposals(t.LocalProposals)
+		remote       = feeProposals(t.RemoteProposals)
+		b            bytes.Buffer
+		lengthPrefix [8]byte
+	)
+
+	stream, err := tlv.NewStream(
+		tlv.MakePrimitiveRecord(0, &rounds),
+		local.record(1),
+		remote.record(2),
+		tlv.MakePrimitiveRecord(3, &feeRate),
+		tlv.MakePrimitiveRecord(4, &duration),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := stream.Encode(&b); err != nil {
+		return err
+	}
+
+	err = tlv.WriteVarInt(w, uint64(b.Len()), &lengthPrefix)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b.Bytes())
+
+	return err
+}
+
+// decodeCoopCloseTelemetry decodes a length prefixed CoopCloseTelemetry TLV
+// stream from the given io.Reader.
+func decodeCoopCloseTelemetry(r io.Reader) (*CoopCloseTelemetry, error) {
+	var (
+		rounds        uint32
+		feeRate       uint64
+		duration      uint64
+		local, remote feeProposals
+		lengthPrefix  [8]byte
+	)
+
+	length, err := tlv.ReadVarInt(r, &lengthPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := tlv.NewStream(
+		tlv.MakePrimitiveRecord(0, &rounds),
+		local.record(1),
+		remote.record(2),
+		tlv.MakePrimitiveRecord(3, &feeRate),
+		tlv.MakePrimitiveRecord(4, &duration),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = stream.Decode(io.LimitReader(r, int64(length)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &CoopCloseTelemetry{
+		Rounds:          rounds,
+		LocalProposals:  local,
+		RemoteProposals: remote,
+		FinalFeeRate:    chainfee.SatPerKWeight(feeRate),
+		Duration:        time.Duration(duration),
+	}, nil
+}
+
+// feeProposals is a list of absolute fees proposed during a cooperative close
+// fee negotiation.
+type feeProposals []btcutil.Amount
+
+// record returns a TLV record of the given type that encodes the fees as a
+// sequence of big endian uint64 values.
+func (f *feeProposals) record(typ tlv.Type) tlv.Record {
+	sizeFunc := func() uint64 {
+		return uint64(len(*f)) * 8
+	}
+
+	return tlv.MakeDynamicRecord(
+		typ, f, sizeFunc, encodeFeeProposals, decodeFeeProposals,
+	)
+}
+
+// encodeFeeProposals is the TLV encoder of feeProposals.
+func encodeFeeProposals(w io.Writer, val interface{}, buf *[8]byte) error {
+	v, ok := val.(*feeProposals)
+	if !ok {
+		return tlv.NewTypeForEncodingErr(val, "feeProposals")
+	}
+
+	for _, fee := range *v {
+		fee := uint64(fee)
+		if err := tlv.EUint64(w, &fee, buf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeFeeProposals is the TLV decoder of feeProposals.
+func decodeFeeProposals(r io.Reader, val interface{}, buf *[8]byte,
+	l uint64) error {
+
+	v, ok := val.(*feeProposals)
+	if !ok || l%8 != 0 {
+		return tlv.NewTypeForDecodingErr(val, "feeProposals", l, l)
+	}
+
+	if l == 0 {
+		return nil
+	}
+
+	fees := make(feeProposals, 0, l/8)
+	for i := uint64(0); i < l/8; i++ {
+		var fee uint64
+		if err := tlv.DUint64(r, &fee, buf, 8); err != nil {
+			return err
+		}
+
+		fees = append(fees, btcutil.Amount(fee))
+	}
+	*v = fees
+
+	return nil
+}
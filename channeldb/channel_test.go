@@ -8,6 +8,7 @@ import (
 	"runtime"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
@@ -21,6 +22,7 @@ import (
 	"github.com/lightningnetwork/lnd/kvdb"
 	"github.com/lightningnetwork/lnd/lnmock"
 	"github.com/lightningnetwork/lnd/lntest/channels"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/shachain"
 	"github.com/lightningnetwork/lnd/tlv"
@@ -1219,6 +1221,61 @@ func testShutdownInfo(t *testing.T, locallyInitiated bool) {
 	})
 }
 
+// TestCoopCloseTelemetry tests that a channel's coop close telemetry can be
+// persisted, and that it's carried over to the close summary.
+func TestCoopCloseTelemetry(t *testing.T) {
+	t.Parallel()
+
+	fullDB, err := MakeTestDB(t)
+	require.NoError(t, err, "unable to make test database")
+
+	cdb := fullDB.ChannelStateDB()
+
+	channel := createTestChannel(t, cdb, openChannelOption())
+
+	// We haven't persisted any telemetry for this channel yet.
+	_, err = channel.CoopCloseTelemetry()
+	require.ErrorIs(t, err, ErrNoCoopCloseTelemetry)
+
+	telemetry := &CoopCloseTelemetry{
+		Rounds:          3,
+		LocalProposals:  []btcutil.Amount{1000, 1100, 1210},
+		RemoteProposals: []btcutil.Amount{2000, 1500, 1210},
+		FinalFeeRate:    chainfee.SatPerKWeight(1720),
+		Duration:        3 * time.Second,
+	}
+	require.NoError(t, channel.MarkCoopCloseTelemetry(telemetry))
+
+	stored, err := channel.CoopCloseTelemetry()
+	require.NoError(t, err)
+	require.Equal(t, telemetry, stored)
+
+	// The telemetry should survive the round trip through the close
+	// summary.
+	summary := &ChannelCloseSummary{
+		ChanPoint:               channel.FundingOutpoint,
+		ClosingTXID:             rev,
+		RemotePub:               channel.IdentityPub,
+		Capacity:                channel.Capacity,
+		CloseType:               CooperativeClose,
+		IsPending:               true,
+		RemoteCurrentRevocation: channel.RemoteCurrentRevocation,
+		LocalChanConfig:         channel.LocalChanCfg,
+		CoopCloseTelemetry:      stored,
+	}
+	require.NoError(t, channel.CloseChannel(summary))
+
+	closed, err := cdb.FetchClosedChannels(false)
+	require.NoError(t, err)
+	require.Len(t, closed, 1)
+	require.Equal(t, telemetry, closed[0].CoopCloseTelemetry)
+
+	// Once the channel is closed, its telemetry is gone with the rest of
+	// its open channel state.
+	_, err = channel.CoopCloseTelemetry()
+	require.ErrorIs(t, err, ErrNoCoopCloseTelemetry)
+}
+
 // TestRefresh asserts that Refresh updates the in-memory state of another
 // OpenChannel to reflect a preceding call to MarkOpen on a different
 // OpenChannel.
@@ -12,6 +12,7 @@ import (
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcwallet/walletdb"
 	"github.com/go-errors/errors"
+	"github.com/lightningnetwork/lnd/batch"
 	mig "github.com/lightningnetwork/lnd/channeldb/migration"
 	"github.com/lightningnetwork/lnd/channeldb/migration12"
 	"github.com/lightningnetwork/lnd/channeldb/migration13"
@@ -403,6 +404,8 @@ func CreateWithBackend(backend kvdb.Backend,
 		backend, opts.RejectCacheSize, opts.ChannelCacheSize,
 		opts.BatchCommitInterval, opts.PreAllocCacheNumNodes,
 		opts.UseGraphCache, opts.NoMigration,
+		batch.WithBulkDuration(opts.SyncBatchCommitInterval),
+		batch.WithMaxBatchSize(opts.BatchMaxSize),
 	)
 	if err != nil {
 		return nil, err
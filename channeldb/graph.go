@@ -189,10 +189,13 @@ type ChannelGraph struct {
 }
 
 // NewChannelGraph allocates a new ChannelGraph backed by a DB instance. The
-// returned instance has its own unique reject cache and channel cache.
+// returned instance has its own unique reject cache and channel cache. The
+// passed scheduler options are applied to the schedulers that batch writes to
+// the graph.
 func NewChannelGraph(db kvdb.Backend, rejectCacheSize, chanCacheSize int,
 	batchCommitInterval time.Duration, preAllocCacheNumNodes int,
-	useGraphCache, noMigrations bool) (*ChannelGraph, error) {
+	useGraphCache, noMigrations bool,
+	schedulerOpts ...batch.TimeSchedulerOption) (*ChannelGraph, error) {
 
 	if !noMigrations {
 		if err := initChannelGraph(db); err != nil {
@@ -206,10 +209,10 @@ func NewChannelGraph(db kvdb.Backend, rejectCacheSize, chanCacheSize int,
 		chanCache:   newChannelCache(chanCacheSize),
 	}
 	g.chanScheduler = batch.NewTimeScheduler(
-		db, &g.cacheMu, batchCommitInterval,
+		db, &g.cacheMu, batchCommitInterval, schedulerOpts...,
 	)
 	g.nodeScheduler = batch.NewTimeScheduler(
-		db, nil, batchCommitInterval,
+		db, nil, batchCommitInterval, schedulerOpts...,
 	)
 
 	// The graph cache can be turned off (e.g. for mobile users) for a
@@ -0,0 +1,280 @@
+package channeldb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+
+	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// ErrGraphCacheDisabled is returned when the graph cache is audited while it
+// is turned off.
+var ErrGraphCacheDisabled = errors.New("graph cache is disabled")
+
+// GraphCacheAuditSummary summarizes a consistency audit of the graph cache
+// against the persistent graph store, counting the divergences found by
+// class.
+type GraphCacheAuditSummary struct {
+	// NodesAudited is the number of nodes that were sampled.
+	NodesAudited int
+
+	// ChannelsAudited is the number of directed channels of the sampled
+	// nodes that were compared.
+	ChannelsAudited int
+
+	// MissingNodes is the number of sampled nodes that are unknown to the
+	// cache.
+	MissingNodes int
+
+	// MismatchedFeatures is the number of sampled nodes whose cached
+	// features differ from the stored ones.
+	MismatchedFeatures int
+
+	// MissingChannels is the number of stored channels that are absent
+	// from the cache.
+	MissingChannels int
+
+	// StaleChannels is the number of cached channels that no longer exist
+	// in the store.
+	StaleChannels int
+
+	// MismatchedChannels is the number of cached channels whose capacity,
+	// counterparty or policies differ from the stored ones.
+	MismatchedChannels int
+
+	// RepairedNodes is the number of nodes that were re-primed from the
+	// store because they diverged.
+	RepairedNodes int
+}
+
+// NumDivergences returns the total number of divergences found.
+func (s *GraphCacheAuditSummary) NumDivergences() int {
+	return s.MissingNodes + s.MismatchedFeatures + s.MissingChannels +
+		s.StaleChannels + s.MismatchedChannels
+}
+
+// String returns a human-readable summary of the audit.
+func (s *GraphCacheAuditSummary) String() string {
+	return fmt.Sprintf("nodes_audited=%d, channels_audited=%d, "+
+		"missing_nodes=%d, mismatched_features=%d, "+
+		"missing_channels=%d, stale_channels=%d, "+
+		"mismatched_channels=%d, repaired_nodes=%d", s.NodesAudited,
+		s.ChannelsAudited, s.MissingNodes, s.MismatchedFeatures,
+		s.MissingChannels, s.StaleChannels, s.MismatchedChannels,
+		s.RepairedNodes)
+}
+
+// AuditGraphCache compares the graph cache entries of up to numNodes randomly
+// sampled nodes against the persistent graph store. Every node whose cached
+// features or channels diverge from the store is removed from the cache and
+// re-primed from the store.
+func (c *ChannelGraph) AuditGraphCache(numNodes int) (*GraphCacheAuditSummary,
+	error) {
+
+	if c.graphCache == nil {
+		return nil, ErrGraphCacheDisabled
+	}
+
+	// We sample the nodes before acquiring the cache mutex, as walking the
+	// whole node bucket may take a while on a large graph.
+	sample, err := c.sampleNodes(numNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Block all writes to the graph while the sampled nodes are compared
+	// and repaired, so that the cache and the store can't change under
+	// us.
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	var summary *GraphCacheAuditSummary
+	err = kvdb.View(c.db, func(tx kvdb.RTx) error {
+		nodes := tx.ReadBucket(nodeBucket)
+		if nodes == nil {
+			return ErrGraphNotFound
+		}
+
+		for _, pubKey := range sample {
+			nodeBytes := nodes.Get(pubKey[:])
+
+			// The node may have been deleted since we sampled it.
+			if nodeBytes == nil {
+				continue
+			}
+
+			node, err := deserializeLightningNodeCacheable(
+				bytes.NewReader(nodeBytes),
+			)
+			if err != nil {
+				return err
+			}
+
+			err = c.auditCachedNode(tx, node, summary)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func() {
+		summary = &GraphCacheAuditSummary{}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// sampleNodes returns up to numNodes public keys picked uniformly at random
+// from the nodes in the store.
+func (c *ChannelGraph) sampleNodes(numNodes int) ([]route.Vertex, error) {
+	var (
+		sample []route.Vertex
+		seen   int
+	)
+	err := kvdb.View(c.db, func(tx kvdb.RTx) error {
+		nodes := tx.ReadBucket(nodeBucket)
+		if nodes == nil {
+			return ErrGraphNotFound
+		}
+
+		// We use reservoir sampling so that we only need a single pass
+		// over the node keys.
+		return nodes.ForEach(func(pubKey, _ []byte) error {
+			if bytes.Equal(pubKey, sourceKey) || len(pubKey) != 33 {
+				return nil
+			}
+
+			seen++
+			switch {
+			case len(sample) < numNodes:
+				sample = append(sample, route.Vertex(pubKey))
+
+			default:
+				i := rand.Intn(seen)
+				if i < numNodes {
+					sample[i] = route.Vertex(pubKey)
+				}
+			}
+
+			return nil
+		})
+	}, func() {
+		sample = nil
+		seen = 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sample, nil
+}
+
+// auditCachedNode compares the cached features and channels of the given node
+// against the store, recording any divergences in the summary. If the node
+// diverges, it is re-primed from the store.
+//
+// NOTE: The cacheMu MUST be held when calling this method.
+func (c *ChannelGraph) auditCachedNode(tx kvdb.RTx, node *graphCacheNode,
+	summary *GraphCacheAuditSummary) error {
+
+	pubKey := node.PubKey()
+	summary.NodesAudited++
+
+	// Build the entries the node would have in a freshly primed cache,
+	// and remember all of its stored channels. Channels with both policies
+	// disabled aren't added when priming, but may still be cached if they
+	// were disabled later on, so we must not treat those as stale.
+	expected := NewGraphCache(1)
+	stored := make(map[uint64]struct{})
+	err := node.ForEachChannel(tx, func(_ kvdb.RTx,
+		info *models.ChannelEdgeInfo, p1,
+		p2 *models.ChannelEdgePolicy) error {
+
+		stored[info.ChannelID] = struct{}{}
+		expected.AddChannel(info, p1, p2)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	c.graphCache.mtx.RLock()
+	cachedFeatures, knownNode := c.graphCache.nodeFeatures[pubKey]
+	cachedChannels := c.graphCache.nodeChannels[pubKey]
+
+	diverged := false
+	switch {
+	case !knownNode:
+		summary.MissingNodes++
+		diverged = true
+
+	case !featuresEqual(cachedFeatures, node.Features()):
+		summary.MismatchedFeatures++
+		diverged = true
+	}
+
+	for chanID, want := range expected.nodeChannels[pubKey] {
+		summary.ChannelsAudited++
+
+		have, ok := cachedChannels[chanID]
+		switch {
+		case !ok:
+			summary.MissingChannels++
+			diverged = true
+
+		case !reflect.DeepEqual(have, want):
+			summary.MismatchedChannels++
+			diverged = true
+		}
+	}
+
+	for chanID := range cachedChannels {
+		if _, ok := stored[chanID]; !ok {
+			summary.StaleChannels++
+			diverged = true
+		}
+	}
+	c.graphCache.mtx.RUnlock()
+
+	if !diverged {
+		return nil
+	}
+
+	log.Warnf("Graph cache entry of node %x diverged from the graph "+
+		"store, re-priming it", pubKey[:])
+
+	c.graphCache.RemoveNode(pubKey)
+	if err := c.graphCache.AddNode(tx, node); err != nil {
+		return err
+	}
+	summary.RepairedNodes++
+
+	return nil
+}
+
+// featuresEqual returns true if both feature vectors have the same bits set.
+func featuresEqual(a, b *lnwire.FeatureVector) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	var bufA, bufB bytes.Buffer
+	if err := a.Encode(&bufA); err != nil {
+		return false
+	}
+	if err := b.Encode(&bufB); err != nil {
+		return false
+	}
+
+	return bytes.Equal(bufA.Bytes(), bufB.Bytes())
+}
@@ -0,0 +1,91 @@
+package channeldb
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAuditGraphCache tests that the graph cache audit detects entries that
+// diverge from the store, and that it repairs them.
+func TestAuditGraphCache(t *testing.T) {
+	t.Parallel()
+
+	graph, err := MakeTestGraph(t)
+	require.NoError(t, err)
+
+	// Create a chain of nodes with two channels between each neighbouring
+	// pair of nodes.
+	const (
+		numNodes    = 10
+		numChannels = 2
+	)
+	_, nodes := fillTestGraph(t, graph, numNodes, numChannels)
+
+	// A freshly primed cache must not diverge from the store.
+	summary, err := graph.AuditGraphCache(numNodes)
+	require.NoError(t, err)
+	require.Equal(t, numNodes, summary.NodesAudited)
+	require.Equal(t, 2*numChannels*(numNodes-1), summary.ChannelsAudited)
+	require.Zero(t, summary.NumDivergences())
+	require.Zero(t, summary.RepairedNodes)
+
+	// Sampling fewer nodes than exist only audits the sampled ones.
+	summary, err = graph.AuditGraphCache(numNodes / 2)
+	require.NoError(t, err)
+	require.Equal(t, numNodes/2, summary.NodesAudited)
+	require.Zero(t, summary.NumDivergences())
+
+	// Now we poison the cache. Re-priming a node also rewrites the cache
+	// entries of its neighbours, so we only poison every other node to
+	// make the expected counts independent of the audit order. The IDs
+	// of the channels between nodes n and n+1 start at (n << 8) + 1.
+	cache := graph.graphCache
+	vertex := func(i int) route.Vertex {
+		return nodes[i].PubKeyBytes
+	}
+
+	// Forget the first node entirely.
+	delete(cache.nodeFeatures, vertex(0))
+
+	// Forget one of the channels of the third node, leaving the entry of
+	// its peer in place.
+	delete(cache.nodeChannels[vertex(2)], 1<<8+1)
+
+	// Change the capacity of one of the channels of the fifth node.
+	cache.nodeChannels[vertex(4)][3<<8+1].Capacity++
+
+	// Add a channel to the seventh node that doesn't exist in the store.
+	stale := *cache.nodeChannels[vertex(6)][6<<8+1]
+	stale.ChannelID = 1 << 32
+	cache.nodeChannels[vertex(6)][stale.ChannelID] = &stale
+
+	// Change the features of the ninth node.
+	cache.nodeFeatures[vertex(8)] = lnwire.EmptyFeatureVector()
+
+	summary, err = graph.AuditGraphCache(numNodes)
+	require.NoError(t, err)
+	require.Equal(t, &GraphCacheAuditSummary{
+		NodesAudited:       numNodes,
+		ChannelsAudited:    2 * numChannels * (numNodes - 1),
+		MissingNodes:       1,
+		MismatchedFeatures: 1,
+		MissingChannels:    1,
+		StaleChannels:      1,
+		MismatchedChannels: 1,
+		RepairedNodes:      5,
+	}, summary)
+
+	// All divergences should have been repaired.
+	summary, err = graph.AuditGraphCache(numNodes)
+	require.NoError(t, err)
+	require.Zero(t, summary.NumDivergences())
+	require.Zero(t, summary.RepairedNodes)
+
+	require.Contains(t, cache.nodeFeatures, vertex(0))
+	require.Contains(t, cache.nodeChannels[vertex(2)], uint64(1<<8+1))
+	require.NotContains(t, cache.nodeChannels[vertex(6)], stale.ChannelID)
+	require.Equal(t, testFeatures, cache.nodeFeatures[vertex(8)])
+}
@@ -0,0 +1,148 @@
+package channeldb
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	invpkg "github.com/lightningnetwork/lnd/invoices"
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/lntypes"
+)
+
+var (
+	// idempotencyKeyBucket is the name of the sub-bucket within the
+	// invoiceBucket that maps the idempotency keys of invoice creation
+	// requests to the invoice that was created for them. The keys are
+	// stored under their scoped identifier.
+	//
+	// maps: keyID => payHash || expiry
+	idempotencyKeyBucket = []byte("idempotency-keys")
+)
+
+// A compile-time check to ensure that DB implements the
+// invpkg.IdempotencyKeyStore interface.
+var _ invpkg.IdempotencyKeyStore = (*DB)(nil)
+
+// FetchIdempotencyKey returns the payment hash of the invoice that was created
+// for the given key. If no invoice was created for the key, or if the key
+// expired, invpkg.ErrIdempotencyKeyNotFound is returned.
+func (d *DB) FetchIdempotencyKey(key invpkg.IdempotencyKey) (lntypes.Hash,
+	error) {
+
+	var (
+		id          = key.ID()
+		paymentHash lntypes.Hash
+		expiry      time.Time
+	)
+	err := kvdb.View(d, func(tx kvdb.RTx) error {
+		invoices := tx.ReadBucket(invoiceBucket)
+		if invoices == nil {
+			return invpkg.ErrIdempotencyKeyNotFound
+		}
+
+		keys := invoices.NestedReadBucket(idempotencyKeyBucket)
+		if keys == nil {
+			return invpkg.ErrIdempotencyKeyNotFound
+		}
+
+		entry := keys.Get(id[:])
+		if entry == nil {
+			return invpkg.ErrIdempotencyKeyNotFound
+		}
+
+		var err error
+		paymentHash, expiry, err = deserializeIdempotencyEntry(entry)
+
+		return err
+	}, func() {})
+	if err != nil {
+		return lntypes.Hash{}, err
+	}
+
+	if !d.clock.Now().Before(expiry) {
+		return lntypes.Hash{}, invpkg.ErrIdempotencyKeyNotFound
+	}
+
+	return paymentHash, nil
+}
+
+// PutIdempotencyKey stores the payment hash of the invoice created for the
+// given key, until the key expires at the given time. An existing entry for the
+// key is replaced. Entries of other keys that expired are deleted, so that the
+// bucket only grows with the number of active keys.
+func (d *DB) PutIdempotencyKey(key invpkg.IdempotencyKey,
+	paymentHash lntypes.Hash, expiry time.Time) error {
+
+	var b bytes.Buffer
+	if _, err := b.Write(paymentHash[:]); err != nil {
+		return err
+	}
+	if err := serializeTime(&b, expiry); err != nil {
+		return err
+	}
+
+	id := key.ID()
+	now := d.clock.Now()
+
+	return kvdb.Update(d, func(tx kvdb.RwTx) error {
+		invoices, err := tx.CreateTopLevelBucket(invoiceBucket)
+		if err != nil {
+			return err
+		}
+
+		keys, err := invoices.CreateBucketIfNotExists(
+			idempotencyKeyBucket,
+		)
+		if err != nil {
+			return err
+		}
+
+		// Collect the expired entries first, as we can't delete them
+		// while iterating over the bucket.
+		var expired [][]byte
+		err = keys.ForEach(func(k, v []byte) error {
+			_, expiry, err := deserializeIdempotencyEntry(v)
+			if err != nil {
+				return err
+			}
+
+			if !now.Before(expiry) {
+				expired = append(expired, k)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := keys.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return keys.Put(id[:], b.Bytes())
+	}, func() {})
+}
+
+// deserializeIdempotencyEntry deserializes the payment hash and expiry of an
+// idempotency key entry.
+func deserializeIdempotencyEntry(entry []byte) (lntypes.Hash, time.Time,
+	error) {
+
+	var paymentHash lntypes.Hash
+
+	r := bytes.NewReader(entry)
+	if _, err := io.ReadFull(r, paymentHash[:]); err != nil {
+		return lntypes.Hash{}, time.Time{}, err
+	}
+
+	expiry, err := deserializeTime(r)
+	if err != nil {
+		return lntypes.Hash{}, time.Time{}, err
+	}
+
+	return paymentHash, expiry, nil
+}
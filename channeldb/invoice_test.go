@@ -6,7 +6,10 @@ import (
 	"time"
 
 	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/clock"
 	invpkg "github.com/lightningnetwork/lnd/invoices"
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/tlv"
 	"github.com/stretchr/testify/require"
@@ -103,3 +106,78 @@ func TestEncodeDecodeAmpInvoiceState(t *testing.T) {
 	// The two states should match.
 	require.Equal(t, ampState, ampState2)
 }
+
+// TestIdempotencyKeys tests that idempotency keys are scoped, that they are
+// treated as absent once they expired, and that expired keys are pruned.
+func TestIdempotencyKeys(t *testing.T) {
+	t.Parallel()
+
+	testClock := clock.NewTestClock(testNow)
+	db, err := MakeTestDB(t, OptionClock(testClock))
+	require.NoError(t, err)
+
+	var (
+		key = invpkg.IdempotencyKey{
+			Scope: []byte("scope"),
+			Key:   []byte("key"),
+		}
+		hash1 = lntypes.Hash{1}
+		hash2 = lntypes.Hash{2}
+	)
+
+	_, err = db.FetchIdempotencyKey(key)
+	require.ErrorIs(t, err, invpkg.ErrIdempotencyKeyNotFound)
+
+	expiry := testNow.Add(time.Hour)
+	require.NoError(t, db.PutIdempotencyKey(key, hash1, expiry))
+
+	hash, err := db.FetchIdempotencyKey(key)
+	require.NoError(t, err)
+	require.Equal(t, hash1, hash)
+
+	// The same key in another scope is a different key. Moving the scope
+	// boundary must not result in the same key either.
+	otherScope := key
+	otherScope.Scope = []byte("other")
+	_, err = db.FetchIdempotencyKey(otherScope)
+	require.ErrorIs(t, err, invpkg.ErrIdempotencyKeyNotFound)
+
+	shifted := invpkg.IdempotencyKey{
+		Scope: []byte("scopek"),
+		Key:   []byte("ey"),
+	}
+	_, err = db.FetchIdempotencyKey(shifted)
+	require.ErrorIs(t, err, invpkg.ErrIdempotencyKeyNotFound)
+
+	require.NoError(t, db.PutIdempotencyKey(otherScope, hash2, expiry))
+
+	// Once expired, the keys are treated as absent.
+	testClock.SetTime(expiry)
+	_, err = db.FetchIdempotencyKey(key)
+	require.ErrorIs(t, err, invpkg.ErrIdempotencyKeyNotFound)
+
+	// Storing a key prunes the expired ones.
+	require.NoError(t, db.PutIdempotencyKey(
+		key, hash2, expiry.Add(time.Hour),
+	))
+
+	hash, err = db.FetchIdempotencyKey(key)
+	require.NoError(t, err)
+	require.Equal(t, hash2, hash)
+
+	var numKeys int
+	err = kvdb.View(db, func(tx kvdb.RTx) error {
+		keys := tx.ReadBucket(invoiceBucket).NestedReadBucket(
+			idempotencyKeyBucket,
+		)
+
+		return keys.ForEach(func(_, _ []byte) error {
+			numKeys++
+			return nil
+		})
+	}, func() {
+		numKeys = 0
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, numKeys)
+}
@@ -150,76 +150,78 @@ func (d *DB) AddInvoice(_ context.Context, newInvoice *invpkg.Invoice,
 
 	var invoiceAddIndex uint64
 	err := kvdb.Update(d, func(tx kvdb.RwTx) error {
-		invoices, err := tx.CreateTopLevelBucket(invoiceBucket)
-		if err != nil {
-			return err
-		}
+		var err error
+		invoiceAddIndex, err = addInvoice(tx, newInvoice, paymentHash)
 
-		invoiceIndex, err := invoices.CreateBucketIfNotExists(
-			invoiceIndexBucket,
-		)
-		if err != nil {
-			return err
-		}
-		addIndex, err := invoices.CreateBucketIfNotExists(
-			addIndexBucket,
-		)
-		if err != nil {
-			return err
-		}
+		return err
+	}, func() {
+		invoiceAddIndex = 0
+	})
+	if err != nil {
+		return 0, err
+	}
 
-		// Ensure that an invoice an identical payment hash doesn't
-		// already exist within the index.
-		if invoiceIndex.Get(paymentHash[:]) != nil {
-			return invpkg.ErrDuplicateInvoice
-		}
+	return invoiceAddIndex, err
+}
 
-		// Check that we aren't inserting an invoice with a duplicate
-		// payment address. The all-zeros payment address is
-		// special-cased to support legacy keysend invoices which don't
-		// assign one. This is safe since later we also will avoid
-		// indexing them and avoid collisions.
-		payAddrIndex := tx.ReadWriteBucket(payAddrIndexBucket)
-		if newInvoice.Terms.PaymentAddr != invpkg.BlankPayAddr {
-			paymentAddr := newInvoice.Terms.PaymentAddr[:]
-			if payAddrIndex.Get(paymentAddr) != nil {
-				return invpkg.ErrDuplicatePayAddr
-			}
-		}
+// addInvoice inserts the passed invoice using the passed db transaction and
+// returns its add index.
+func addInvoice(tx kvdb.RwTx, newInvoice *invpkg.Invoice,
+	paymentHash lntypes.Hash) (uint64, error) {
 
-		// If the current running payment ID counter hasn't yet been
-		// created, then create it now.
-		var invoiceNum uint32
-		invoiceCounter := invoiceIndex.Get(numInvoicesKey)
-		if invoiceCounter == nil {
-			var scratch [4]byte
-			byteOrder.PutUint32(scratch[:], invoiceNum)
-			err := invoiceIndex.Put(numInvoicesKey, scratch[:])
-			if err != nil {
-				return err
-			}
-		} else {
-			invoiceNum = byteOrder.Uint32(invoiceCounter)
+	invoices, err := tx.CreateTopLevelBucket(invoiceBucket)
+	if err != nil {
+		return 0, err
+	}
+
+	invoiceIndex, err := invoices.CreateBucketIfNotExists(
+		invoiceIndexBucket,
+	)
+	if err != nil {
+		return 0, err
+	}
+	addIndex, err := invoices.CreateBucketIfNotExists(addIndexBucket)
+	if err != nil {
+		return 0, err
+	}
+
+	// Ensure that an invoice an identical payment hash doesn't already
+	// exist within the index.
+	if invoiceIndex.Get(paymentHash[:]) != nil {
+		return 0, invpkg.ErrDuplicateInvoice
+	}
+
+	// Check that we aren't inserting an invoice with a duplicate payment
+	// address. The all-zeros payment address is special-cased to support
+	// legacy keysend invoices which don't assign one. This is safe since
+	// later we also will avoid indexing them and avoid collisions.
+	payAddrIndex := tx.ReadWriteBucket(payAddrIndexBucket)
+	if newInvoice.Terms.PaymentAddr != invpkg.BlankPayAddr {
+		paymentAddr := newInvoice.Terms.PaymentAddr[:]
+		if payAddrIndex.Get(paymentAddr) != nil {
+			return 0, invpkg.ErrDuplicatePayAddr
 		}
+	}
 
-		newIndex, err := putInvoice(
-			invoices, invoiceIndex, payAddrIndex, addIndex,
-			newInvoice, invoiceNum, paymentHash,
-		)
+	// If the current running payment ID counter hasn't yet been created,
+	// then create it now.
+	var invoiceNum uint32
+	invoiceCounter := invoiceIndex.Get(numInvoicesKey)
+	if invoiceCounter == nil {
+		var scratch [4]byte
+		byteOrder.PutUint32(scratch[:], invoiceNum)
+		err := invoiceIndex.Put(numInvoicesKey, scratch[:])
 		if err != nil {
-			return err
+			return 0, err
 		}
-
-		invoiceAddIndex = newIndex
-		return nil
-	}, func() {
-		invoiceAddIndex = 0
-	})
-	if err != nil {
-		return 0, err
+	} else {
+		invoiceNum = byteOrder.Uint32(invoiceCounter)
 	}
 
-	return invoiceAddIndex, err
+	return putInvoice(
+		invoices, invoiceIndex, payAddrIndex, addIndex, newInvoice,
+		invoiceNum, paymentHash,
+	)
 }
 
 // InvoicesAddedSince can be used by callers to seek into the event time series
@@ -616,73 +618,122 @@ func (d *DB) UpdateInvoice(_ context.Context, ref invpkg.InvoiceRef,
 
 	var updatedInvoice *invpkg.Invoice
 	err := kvdb.Update(d, func(tx kvdb.RwTx) error {
-		invoices, err := tx.CreateTopLevelBucket(invoiceBucket)
-		if err != nil {
-			return err
-		}
-		invoiceIndex, err := invoices.CreateBucketIfNotExists(
-			invoiceIndexBucket,
-		)
-		if err != nil {
-			return err
-		}
-		settleIndex, err := invoices.CreateBucketIfNotExists(
-			settleIndexBucket,
+		var err error
+		updatedInvoice, err = d.updateInvoice(
+			tx, ref, setIDHint, callback,
 		)
-		if err != nil {
-			return err
-		}
-		payAddrIndex := tx.ReadBucket(payAddrIndexBucket)
-		setIDIndex := tx.ReadWriteBucket(setIDIndexBucket)
 
-		// Retrieve the invoice number for this invoice using the
-		// provided invoice reference.
-		invoiceNum, err := fetchInvoiceNumByRef(
-			invoiceIndex, payAddrIndex, setIDIndex, ref,
-		)
-		if err != nil {
-			return err
-		}
+		return err
+	}, func() {
+		updatedInvoice = nil
+	})
 
-		// If the set ID hint is non-nil, then we'll use that to filter
-		// out the HTLCs for AMP invoice so we don't need to read them
-		// all out to satisfy the invoice callback below. If it's nil,
-		// then we pass in the zero set ID which means no HTLCs will be
-		// read out.
-		var invSetID invpkg.SetID
+	return updatedInvoice, err
+}
 
-		if setIDHint != nil {
-			invSetID = *setIDHint
-		}
-		invoice, err := fetchInvoice(invoiceNum, invoices, &invSetID)
+// updateInvoice applies the update obtained from the passed callback to the
+// invoice identified by the passed reference using the passed db transaction.
+func (d *DB) updateInvoice(tx kvdb.RwTx, ref invpkg.InvoiceRef,
+	setIDHint *invpkg.SetID, callback invpkg.InvoiceUpdateCallback) (
+	*invpkg.Invoice, error) {
+
+	invoices, err := tx.CreateTopLevelBucket(invoiceBucket)
+	if err != nil {
+		return nil, err
+	}
+	invoiceIndex, err := invoices.CreateBucketIfNotExists(
+		invoiceIndexBucket,
+	)
+	if err != nil {
+		return nil, err
+	}
+	settleIndex, err := invoices.CreateBucketIfNotExists(
+		settleIndexBucket,
+	)
+	if err != nil {
+		return nil, err
+	}
+	payAddrIndex := tx.ReadBucket(payAddrIndexBucket)
+	setIDIndex := tx.ReadWriteBucket(setIDIndexBucket)
+
+	// Retrieve the invoice number for this invoice using the
+	// provided invoice reference.
+	invoiceNum, err := fetchInvoiceNumByRef(
+		invoiceIndex, payAddrIndex, setIDIndex, ref,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// If the set ID hint is non-nil, then we'll use that to filter
+	// out the HTLCs for AMP invoice so we don't need to read them
+	// all out to satisfy the invoice callback below. If it's nil,
+	// then we pass in the zero set ID which means no HTLCs will be
+	// read out.
+	var invSetID invpkg.SetID
+
+	if setIDHint != nil {
+		invSetID = *setIDHint
+	}
+	invoice, err := fetchInvoice(invoiceNum, invoices, &invSetID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := d.clock.Now()
+	updater := &kvInvoiceUpdater{
+		db:                d,
+		invoicesBucket:    invoices,
+		settleIndexBucket: settleIndex,
+		setIDIndexBucket:  setIDIndex,
+		updateTime:        now,
+		invoiceNum:        invoiceNum,
+		invoice:           &invoice,
+		updatedAmpHtlcs:   make(ampHTLCsMap),
+		settledSetIDs:     make(map[invpkg.SetID]struct{}),
+	}
+
+	return invpkg.UpdateInvoice(
+		ref.PayHash(), updater.invoice, now, callback, updater,
+	)
+}
+
+// ReplaceInvoice cancels the open invoice identified by the passed reference
+// and adds the new invoice in its place within a single database transaction.
+// Only invoices in the ContractOpen state can be replaced. The canceled
+// invoice is returned.
+//
+// NOTE: A side effect of this function is that it sets AddIndex on newInvoice.
+func (d *DB) ReplaceInvoice(_ context.Context, ref invpkg.InvoiceRef,
+	newInvoice *invpkg.Invoice, newPaymentHash lntypes.Hash) (
+	*invpkg.Invoice, error) {
+
+	err := invpkg.ValidateInvoice(newInvoice, newPaymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	var canceledInvoice *invpkg.Invoice
+	err = kvdb.Update(d, func(tx kvdb.RwTx) error {
+		var err error
+		canceledInvoice, err = d.updateInvoice(
+			tx, ref, nil, invpkg.CancelOpenInvoice,
+		)
 		if err != nil {
 			return err
 		}
 
-		now := d.clock.Now()
-		updater := &kvInvoiceUpdater{
-			db:                d,
-			invoicesBucket:    invoices,
-			settleIndexBucket: settleIndex,
-			setIDIndexBucket:  setIDIndex,
-			updateTime:        now,
-			invoiceNum:        invoiceNum,
-			invoice:           &invoice,
-			updatedAmpHtlcs:   make(ampHTLCsMap),
-			settledSetIDs:     make(map[invpkg.SetID]struct{}),
-		}
-
-		payHash := ref.PayHash()
-		updatedInvoice, err = invpkg.UpdateInvoice(
-			payHash, updater.invoice, now, callback, updater,
-		)
+		_, err = addInvoice(tx, newInvoice, newPaymentHash)
 
 		return err
 	}, func() {
-		updatedInvoice = nil
+		canceledInvoice = nil
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	return updatedInvoice, err
+	return canceledInvoice, nil
 }
 
 // ampHTLCsMap is a map of AMP HTLCs affected by an invoice update.
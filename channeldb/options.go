@@ -50,6 +50,16 @@ type Options struct {
 	// wait before attempting to commit a pending set of updates.
 	BatchCommitInterval time.Duration
 
+	// SyncBatchCommitInterval is the maximum duration the batch schedulers
+	// will wait before committing a batch of updates received during the
+	// initial graph sync. If zero, BatchCommitInterval is used.
+	SyncBatchCommitInterval time.Duration
+
+	// BatchMaxSize is the number of pending updates after which the batch
+	// schedulers commit a batch right away. If zero, batches aren't
+	// limited in size.
+	BatchMaxSize int
+
 	// PreAllocCacheNumNodes is the number of nodes we expect to be in the
 	// graph cache, so we can pre-allocate the map accordingly.
 	PreAllocCacheNumNodes int
@@ -172,6 +182,22 @@ func OptionSetBatchCommitInterval(interval time.Duration) OptionModifier {
 	}
 }
 
+// OptionSetSyncBatchCommitInterval sets the batch commit interval used for
+// updates received during the initial graph sync.
+func OptionSetSyncBatchCommitInterval(interval time.Duration) OptionModifier {
+	return func(o *Options) {
+		o.SyncBatchCommitInterval = interval
+	}
+}
+
+// OptionSetBatchMaxSize sets the maximum number of updates in a batch of the
+// internal batch schedulers.
+func OptionSetBatchMaxSize(size int) OptionModifier {
+	return func(o *Options) {
+		o.BatchMaxSize = size
+	}
+}
+
 // OptionNoMigration allows the database to be opened in read only mode by
 // disabling migrations.
 func OptionNoMigration(b bool) OptionModifier {
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"time"
 
 	"github.com/lightningnetwork/lnd/kvdb"
@@ -20,6 +22,7 @@ var (
 	//      |
 	//      |-- <peer-pubkey>
 	//      |        |--flap-count-key: <ts><flap count>
+	//      |        |--addr-override-key: <expiry><num addrs><addrs>
 	//      |
 	//      |-- <peer-pubkey>
 	//      |        |--flap-count-key: <ts><flap count>
@@ -29,12 +32,21 @@ var (
 	// the timestamp of a peer's last flap count and its all time flap
 	// count.
 	flapCountKey = []byte("flap-count")
+
+	// addrOverrideKey is a key used in the peer pubkey sub-bucket that
+	// stores the addresses set by the user to reach the peer at, which
+	// take precedence over the addresses the peer announced.
+	addrOverrideKey = []byte("addr-override")
 )
 
 var (
 	// ErrNoPeerBucket is returned when we try to read entries for a peer
 	// that is not tracked.
 	ErrNoPeerBucket = errors.New("peer bucket not found")
+
+	// ErrNoAddrOverride is returned when we try to read the address
+	// override of a peer that has none, or whose override expired.
+	ErrNoAddrOverride = errors.New("no address override found")
 )
 
 // FlapCount contains information about a peer's flap count.
@@ -121,3 +133,202 @@ func (d *DB) ReadFlapCount(pubkey route.Vertex) (*FlapCount, error) {
 
 	return &flapCount, nil
 }
+
+// AddrOverride is a set of addresses that the user knows a peer to be
+// reachable at. They take precedence over the addresses the peer announced,
+// which may be stale, and are never announced to the network.
+type AddrOverride struct {
+	// Addresses is the list of addresses to connect to the peer at.
+	Addresses []net.Addr
+
+	// Expiry is the time after which the override no longer applies. A
+	// zero expiry means that the override never expires.
+	Expiry time.Time
+}
+
+// IsExpired returns true if the override no longer applies at the given time.
+func (a *AddrOverride) IsExpired(now time.Time) bool {
+	return !a.Expiry.IsZero() && !now.Before(a.Expiry)
+}
+
+// SetAddrOverride stores the address override for a peer, replacing any
+// existing one.
+func (d *DB) SetAddrOverride(pubkey route.Vertex,
+	override *AddrOverride) error {
+
+	if len(override.Addresses) == 0 {
+		return errors.New("address override must contain at least " +
+			"one address")
+	}
+
+	var b bytes.Buffer
+	if err := serializeAddrOverride(&b, override); err != nil {
+		return err
+	}
+
+	return kvdb.Update(d, func(tx kvdb.RwTx) error {
+		peers := tx.ReadWriteBucket(peersBucket)
+
+		peerBucket, err := peers.CreateBucketIfNotExists(pubkey[:])
+		if err != nil {
+			return err
+		}
+
+		return peerBucket.Put(addrOverrideKey, b.Bytes())
+	}, func() {})
+}
+
+// FetchAddrOverride returns the address override for a peer. If the peer has
+// no override, or its override expired, ErrNoAddrOverride is returned.
+func (d *DB) FetchAddrOverride(pubkey route.Vertex) (*AddrOverride, error) {
+	var override *AddrOverride
+	err := kvdb.View(d, func(tx kvdb.RTx) error {
+		peers := tx.ReadBucket(peersBucket)
+
+		peerBucket := peers.NestedReadBucket(pubkey[:])
+		if peerBucket == nil {
+			return ErrNoAddrOverride
+		}
+
+		overrideBytes := peerBucket.Get(addrOverrideKey)
+		if overrideBytes == nil {
+			return ErrNoAddrOverride
+		}
+
+		var err error
+		override, err = deserializeAddrOverride(
+			bytes.NewReader(overrideBytes),
+		)
+
+		return err
+	}, func() {
+		override = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if override.IsExpired(d.clock.Now()) {
+		return nil, ErrNoAddrOverride
+	}
+
+	return override, nil
+}
+
+// FetchAddrOverrides returns the address overrides of all peers that have one
+// that hasn't expired.
+func (d *DB) FetchAddrOverrides() (map[route.Vertex]*AddrOverride, error) {
+	var overrides map[route.Vertex]*AddrOverride
+	err := kvdb.View(d, func(tx kvdb.RTx) error {
+		peers := tx.ReadBucket(peersBucket)
+
+		return peers.ForEach(func(k, _ []byte) error {
+			peerBucket := peers.NestedReadBucket(k)
+			if peerBucket == nil {
+				return nil
+			}
+
+			overrideBytes := peerBucket.Get(addrOverrideKey)
+			if overrideBytes == nil {
+				return nil
+			}
+
+			override, err := deserializeAddrOverride(
+				bytes.NewReader(overrideBytes),
+			)
+			if err != nil {
+				return err
+			}
+
+			pubkey, err := route.NewVertexFromBytes(k)
+			if err != nil {
+				return err
+			}
+
+			overrides[pubkey] = override
+
+			return nil
+		})
+	}, func() {
+		overrides = make(map[route.Vertex]*AddrOverride)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := d.clock.Now()
+	for pubkey, override := range overrides {
+		if override.IsExpired(now) {
+			delete(overrides, pubkey)
+		}
+	}
+
+	return overrides, nil
+}
+
+// DeleteAddrOverride removes the address override of a peer. If the peer has
+// no override, ErrNoAddrOverride is returned.
+func (d *DB) DeleteAddrOverride(pubkey route.Vertex) error {
+	return kvdb.Update(d, func(tx kvdb.RwTx) error {
+		peers := tx.ReadWriteBucket(peersBucket)
+
+		peerBucket := peers.NestedReadWriteBucket(pubkey[:])
+		if peerBucket == nil || peerBucket.Get(addrOverrideKey) == nil {
+			return ErrNoAddrOverride
+		}
+
+		return peerBucket.Delete(addrOverrideKey)
+	}, func() {})
+}
+
+// serializeAddrOverride serializes an address override as its expiry,
+// followed by the number of addresses and the addresses themselves.
+func serializeAddrOverride(w io.Writer, override *AddrOverride) error {
+	if err := serializeTime(w, override.Expiry); err != nil {
+		return err
+	}
+
+	var buf [4]byte
+	byteOrder.PutUint32(buf[:], uint32(len(override.Addresses)))
+	if _, err := w.Write(buf[:]); err != nil {
+		return err
+	}
+
+	for _, addr := range override.Addresses {
+		if err := serializeAddr(w, addr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deserializeAddrOverride deserializes an address override that was
+// serialized with serializeAddrOverride.
+func deserializeAddrOverride(r io.Reader) (*AddrOverride, error) {
+	var (
+		override AddrOverride
+		err      error
+	)
+
+	override.Expiry, err = deserializeTime(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf [4]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return nil, err
+	}
+	numAddrs := byteOrder.Uint32(buf[:])
+
+	override.Addresses = make([]net.Addr, numAddrs)
+	for i := uint32(0); i < numAddrs; i++ {
+		override.Addresses[i], err = deserializeAddr(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &override, nil
+}
@@ -1,9 +1,11 @@
 package channeldb
 
 import (
+	"net"
 	"testing"
 	"time"
 
+	"github.com/lightningnetwork/lnd/clock"
 	"github.com/lightningnetwork/lnd/routing/route"
 	"github.com/stretchr/testify/require"
 )
@@ -47,3 +49,80 @@ func TestFlapCount(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, peer2FlapCount, count)
 }
+
+// TestAddrOverride tests setting, fetching, expiring and deleting address
+// overrides.
+func TestAddrOverride(t *testing.T) {
+	now := time.Unix(1000, 0)
+	testClock := clock.NewTestClock(now)
+
+	db, err := MakeTestDB(t, OptionClock(testClock))
+	require.NoError(t, err)
+
+	// Peers without an override have none, whether or not we track any
+	// other information about them.
+	_, err = db.FetchAddrOverride(testPub)
+	require.ErrorIs(t, err, ErrNoAddrOverride)
+	require.ErrorIs(t, db.DeleteAddrOverride(testPub), ErrNoAddrOverride)
+
+	err = db.SetAddrOverride(testPub, &AddrOverride{})
+	require.Error(t, err)
+
+	var (
+		testPub2 = route.Vertex{2, 2, 2}
+		override = &AddrOverride{
+			Addresses: []net.Addr{testAddr, anotherAddr},
+		}
+		expiringOverride = &AddrOverride{
+			Addresses: []net.Addr{anotherAddr},
+			Expiry:    now.Add(time.Hour),
+		}
+	)
+
+	require.NoError(t, db.SetAddrOverride(testPub, override))
+	require.NoError(t, db.SetAddrOverride(testPub2, expiringOverride))
+
+	fetched, err := db.FetchAddrOverride(testPub)
+	require.NoError(t, err)
+	require.Equal(t, override, fetched)
+
+	overrides, err := db.FetchAddrOverrides()
+	require.NoError(t, err)
+	require.Equal(t, map[route.Vertex]*AddrOverride{
+		testPub:  override,
+		testPub2: expiringOverride,
+	}, overrides)
+
+	// Once the second override expires, it is no longer returned.
+	testClock.SetTime(expiringOverride.Expiry)
+
+	_, err = db.FetchAddrOverride(testPub2)
+	require.ErrorIs(t, err, ErrNoAddrOverride)
+
+	overrides, err = db.FetchAddrOverrides()
+	require.NoError(t, err)
+	require.Equal(t, map[route.Vertex]*AddrOverride{
+		testPub: override,
+	}, overrides)
+
+	// Setting an override replaces the existing one.
+	require.NoError(t, db.SetAddrOverride(testPub, expiringOverride))
+
+	_, err = db.FetchAddrOverride(testPub)
+	require.ErrorIs(t, err, ErrNoAddrOverride)
+
+	// Overrides are stored alongside the flap count, which they must not
+	// affect.
+	flapCount := &FlapCount{Count: 1, LastFlap: time.Unix(100, 0)}
+	err = db.WriteFlapCounts(map[route.Vertex]*FlapCount{
+		testPub2: flapCount,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, db.DeleteAddrOverride(testPub2))
+	require.ErrorIs(t, db.DeleteAddrOverride(testPub2), ErrNoAddrOverride)
+
+	count, err := db.ReadFlapCount(testPub2)
+	require.NoError(t, err)
+	require.Equal(t, flapCount, count)
+}
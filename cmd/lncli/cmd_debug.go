@@ -41,6 +41,44 @@ func getDebugInfo(ctx *cli.Context) error {
 	return nil
 }
 
+var auditGraphCacheCommand = cli.Command{
+	Name:     "auditgraphcache",
+	Category: "Debug",
+	Usage: "Audit the in-memory graph cache against the graph " +
+		"store.",
+	Description: `
+	Compares the graph cache entries of a random sample of nodes against
+	the persistent graph store right away and re-primes every node whose
+	cache entries diverged. Prints a summary of the divergences found.`,
+	Flags: []cli.Flag{
+		cli.Uint64Flag{
+			Name: "num_nodes",
+			Usage: "the number of random nodes to audit, if not " +
+				"set the configured number of graph cache " +
+				"audit samples is used",
+		},
+	},
+	Action: actionDecorator(auditGraphCache),
+}
+
+func auditGraphCache(ctx *cli.Context) error {
+	ctxc := getContext()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	req := &lnrpc.AuditGraphCacheRequest{
+		NumNodes: uint32(ctx.Uint64("num_nodes")),
+	}
+	resp, err := client.AuditGraphCache(ctxc, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
+
 type DebugPackage struct {
 	EphemeralPubKey  string `json:"ephemeral_public_key"`
 	EncryptedPayload string `json:"encrypted_payload"`
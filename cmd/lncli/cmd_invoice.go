@@ -82,6 +82,13 @@ var addInvoiceCommand = cli.Command{
 			Usage: "creates an AMP invoice. If true, preimage " +
 				"should not be set.",
 		},
+		cli.StringFlag{
+			Name: "idempotency_key",
+			Usage: "an optional key that makes this call " +
+				"idempotent; retrying it with the same key " +
+				"returns the invoice that was created first " +
+				"instead of creating a new one",
+		},
 	},
 	Action: actionDecorator(addInvoice),
 }
@@ -138,6 +145,7 @@ func addInvoice(ctx *cli.Context) error {
 		CltvExpiry:      ctx.Uint64("cltv_expiry_delta"),
 		Private:         ctx.Bool("private"),
 		IsAmp:           ctx.Bool("amp"),
+		IdempotencyKey:  []byte(ctx.String("idempotency_key")),
 	}
 
 	resp, err := client.AddInvoice(ctxc, invoice)
@@ -461,6 +461,7 @@ func main() {
 		channelBalanceCommand,
 		getInfoCommand,
 		getDebugInfoCommand,
+		auditGraphCacheCommand,
 		encryptDebugPackageCommand,
 		decryptDebugPackageCommand,
 		getRecoveryInfoCommand,
@@ -33,6 +33,7 @@ import (
 	"github.com/lightningnetwork/lnd/htlcswitch"
 	"github.com/lightningnetwork/lnd/htlcswitch/hodl"
 	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/invoices"
 	"github.com/lightningnetwork/lnd/lncfg"
 	"github.com/lightningnetwork/lnd/lnrpc"
 	"github.com/lightningnetwork/lnd/lnrpc/peersrpc"
@@ -168,6 +169,16 @@ const (
 	defaultRSBackoff  = time.Second * 30
 	defaultRSAttempts = 1
 
+	// Set defaults for a health check which ensures that the node-wide
+	// peer connectivity, as rolled up from the ping stats of all our
+	// peers, is not degraded. This check is off by default as a degraded
+	// network doesn't necessarily warrant shutting down, but we still set
+	// the other default values so that it can be easily enabled.
+	defaultPLInterval = time.Minute
+	defaultPLTimeout  = time.Second * 5
+	defaultPLBackoff  = time.Minute * 5
+	defaultPLAttempts = 0
+
 	// defaultRemoteMaxHtlcs specifies the default limit for maximum
 	// concurrent HTLCs the remote party may add to commitment transactions.
 	// This value can be overridden with --default-remote-max-htlcs.
@@ -336,6 +347,9 @@ type Config struct {
 	MaxBackoff        time.Duration `long:"maxbackoff" description:"Longest backoff when reconnecting to persistent peers. Valid time units are {s, m, h}."`
 	ConnectionTimeout time.Duration `long:"connectiontimeout" description:"The timeout value for network connections. Valid time units are {ms, s, m, h}."`
 
+	ConnectionTimeoutTor      time.Duration `long:"connectiontimeout-tor" description:"The timeout value for connections to onion addresses, as well as to clearnet addresses if they are proxied through Tor. If not set, connectiontimeout is used. Valid time units are {ms, s, m, h}."`
+	ConnectionTimeoutClearnet time.Duration `long:"connectiontimeout-clearnet" description:"The timeout value for connections to clearnet addresses that aren't proxied through Tor. If not set, connectiontimeout is used. Valid time units are {ms, s, m, h}."`
+
 	DebugLevel string `short:"d" long:"debuglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <global-level>,<subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
 
 	CPUProfile string `long:"cpuprofile" description:"Write CPU profile to the specified file"`
@@ -657,6 +671,10 @@ func DefaultConfig() Config {
 		Caches: &lncfg.Caches{
 			RejectCacheSize:  channeldb.DefaultRejectCacheSize,
 			ChannelCacheSize: channeldb.DefaultChannelCacheSize,
+			GraphCacheAuditInterval: lncfg.
+				DefaultGraphCacheAuditInterval,
+			GraphCacheAuditSamples: lncfg.
+				DefaultGraphCacheAuditSamples,
 		},
 		Prometheus: lncfg.DefaultPrometheus(),
 		Watchtower: lncfg.DefaultWatchtowerCfg(defaultTowerDir),
@@ -694,6 +712,12 @@ func DefaultConfig() Config {
 				Attempts: defaultRSAttempts,
 				Backoff:  defaultRSBackoff,
 			},
+			PeerLatency: &lncfg.CheckConfig{
+				Interval: defaultPLInterval,
+				Timeout:  defaultPLTimeout,
+				Attempts: defaultPLAttempts,
+				Backoff:  defaultPLBackoff,
+			},
 		},
 		Gossip: &lncfg.Gossip{
 			MaxChannelUpdateBurst: discovery.DefaultMaxChannelUpdateBurst,
@@ -701,7 +725,9 @@ func DefaultConfig() Config {
 			SubBatchDelay:         discovery.DefaultSubBatchDelay,
 		},
 		Invoices: &lncfg.Invoices{
-			HoldExpiryDelta: lncfg.DefaultHoldInvoiceExpiryDelta,
+			HoldExpiryDelta:   lncfg.DefaultHoldInvoiceExpiryDelta,
+			MaxMemoSize:       lncfg.DefaultMaxInvoiceMemoSize,
+			IdempotencyKeyTTL: invoices.DefaultIdempotencyKeyTTL,
 		},
 		MaxOutgoingCltvExpiry:     htlcswitch.DefaultMaxOutgoingCltvExpiry,
 		MaxChannelFeeAllocation:   htlcswitch.DefaultMaxLinkFeeAllocation,
@@ -1594,6 +1620,12 @@ func ValidateConfig(cfg Config, interceptor signal.Interceptor, fileParser,
 		return nil, mkErr("maxbackoff must be greater than minbackoff")
 	}
 
+	// Ensure that the per address family connection timeouts are sane.
+	dialTimeouts := cfg.dialTimeouts()
+	if err := dialTimeouts.Validate(); err != nil {
+		return nil, mkErr("invalid connection timeouts: %v", err)
+	}
+
 	// Newer versions of lnd added a new sub-config for bolt-specific
 	// parameters. However, we want to also allow existing users to use the
 	// value on the top-level config. If the outer config value is set,
@@ -1688,6 +1720,21 @@ func ValidateConfig(cfg Config, interceptor signal.Interceptor, fileParser,
 			lncfg.DefaultIncomingBroadcastDelta)
 	}
 
+	// The memo of an invoice is stored alongside it, so we can't allow
+	// memos larger than the invoice database accepts.
+	if cfg.Invoices.MaxMemoSize == 0 ||
+		cfg.Invoices.MaxMemoSize > invoices.MaxMemoSize {
+
+		return nil, mkErr("invoices.maxmemosize must be between 1 "+
+			"and %v, got %v", invoices.MaxMemoSize,
+			cfg.Invoices.MaxMemoSize)
+	}
+
+	if cfg.Invoices.IdempotencyKeyTTL <= 0 {
+		return nil, mkErr("invoices.idempotencykeyttl must be "+
+			"positive, got %v", cfg.Invoices.IdempotencyKeyTTL)
+	}
+
 	// If the experimental protocol options specify any protocol messages
 	// that we want to handle as custom messages, set them now.
 	customMsg := cfg.ProtocolOptions.CustomMessageOverrides()
@@ -1727,6 +1774,18 @@ func ValidateConfig(cfg Config, interceptor signal.Interceptor, fileParser,
 	return &cfg, nil
 }
 
+// dialTimeouts returns the timeouts to use when dialing peers, depending on
+// the address family of the peer's address.
+func (c *Config) dialTimeouts() *lncfg.DialTimeouts {
+	return &lncfg.DialTimeouts{
+		Default:  c.ConnectionTimeout,
+		Tor:      c.ConnectionTimeoutTor,
+		Clearnet: c.ConnectionTimeoutClearnet,
+		ClearnetViaTor: c.Tor.Active &&
+			!c.Tor.SkipProxyForClearNetTargets,
+	}
+}
+
 // graphDatabaseDir returns the default directory where the local bolt graph db
 // files are stored.
 func (c *Config) graphDatabaseDir() string {
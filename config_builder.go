@@ -969,6 +969,10 @@ func (d *DefaultDatabaseBuilder) BuildDatabase(
 		channeldb.OptionSetBatchCommitInterval(
 			cfg.DB.BatchCommitInterval,
 		),
+		channeldb.OptionSetSyncBatchCommitInterval(
+			cfg.DB.SyncBatchCommitInterval,
+		),
+		channeldb.OptionSetBatchMaxSize(cfg.DB.BatchCommitMaxSize),
 		channeldb.OptionDryRunMigration(cfg.DryRunMigration),
 		channeldb.OptionSetUseGraphCache(!cfg.DB.NoGraphCache),
 		channeldb.OptionKeepFailedPaymentAttempts(
@@ -2,6 +2,7 @@ package contractcourt
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"slices"
 	"sync"
@@ -1079,6 +1080,18 @@ func (c *chainWatcher) dispatchCooperativeClose(commitSpend *chainntnfs.SpendDet
 		closeSummary.LastChanSyncMsg = chanSync
 	}
 
+	// Attach the telemetry of the fee negotiation, if we negotiated this
+	// close ourselves.
+	telemetry, err := c.cfg.chanState.CoopCloseTelemetry()
+	switch {
+	case err == nil:
+		closeSummary.CoopCloseTelemetry = telemetry
+
+	case !errors.Is(err, channeldb.ErrNoCoopCloseTelemetry):
+		log.Errorf("ChannelPoint(%v): unable to fetch coop close "+
+			"telemetry: %v", c.cfg.chanState.FundingOutpoint, err)
+	}
+
 	// Create a summary of all the information needed to handle the
 	// cooperative closure.
 	closeInfo := &CooperativeCloseInfo{
@@ -247,6 +247,13 @@ type Config struct {
 	// use to determine which messages need to be resent for a given peer.
 	MessageStore GossipMessageStore
 
+	// Outbox is a persistent storage of our own node announcements and
+	// channel updates that couldn't be delivered to any peer, because we
+	// weren't connected to any when they were signed. They are sent to the
+	// first peer that connects, even across restarts. If nil, such
+	// announcements are only sent out again on the next rebroadcast.
+	Outbox GossipOutbox
+
 	// AnnSigner is an instance of the MessageSigner interface which will
 	// be used to manually sign any outgoing channel updates. The signer
 	// implementation should be backed by the public key of the backing
@@ -481,6 +488,11 @@ type AuthenticatedGossiper struct {
 	// network.
 	reliableSender *reliableSender
 
+	// outboxMtx serializes storing undelivered announcements in the outbox
+	// with delivering them to newly connected peers, so that they are
+	// delivered exactly once.
+	outboxMtx sync.Mutex
+
 	// chanUpdateRateLimiter contains rate limiters for each direction of
 	// a channel update we've processed. We'll use these to determine
 	// whether we should accept a new update for a specific channel and
@@ -1302,6 +1314,93 @@ func (d *AuthenticatedGossiper) sendLocalBatch(annBatch []msgWithSenders) {
 	if err != nil {
 		log.Errorf("Unable to send local batch announcements: %v", err)
 	}
+
+	if d.cfg.Outbox != nil {
+		d.storeUndelivered(msgsToSend)
+	}
+}
+
+// storeUndelivered stores our node announcement and channel updates among the
+// given messages in the outbox if we aren't connected to any peer that we can
+// gossip with, as the broadcast didn't reach anyone in that case.
+func (d *AuthenticatedGossiper) storeUndelivered(msgs []lnwire.Message) {
+	d.outboxMtx.Lock()
+	defer d.outboxMtx.Unlock()
+
+	if len(d.syncMgr.GossipSyncers()) > 0 {
+		return
+	}
+
+	for _, msg := range msgs {
+		err := d.cfg.Outbox.PutMessage(msg)
+		switch {
+		// Other announcements, such as channel announcements, are not
+		// kept in the outbox.
+		case errors.Is(err, ErrUnsupportedMessage):
+
+		case err != nil:
+			log.Errorf("Unable to store undelivered %v in "+
+				"outbox: %v", msg.MsgType(), err)
+
+		default:
+			log.Debugf("No peers to deliver %v to, stored it in "+
+				"outbox", msg.MsgType())
+		}
+	}
+}
+
+// flushOutbox sends all announcements in the outbox that are still fresh to
+// the given peer, and removes them from the outbox once they were sent.
+//
+// NOTE: This MUST be run as a goroutine.
+func (d *AuthenticatedGossiper) flushOutbox(peer lnpeer.Peer) {
+	defer d.wg.Done()
+
+	d.outboxMtx.Lock()
+	defer d.outboxMtx.Unlock()
+
+	msgs, err := d.cfg.Outbox.Messages()
+	if err != nil {
+		log.Errorf("Unable to fetch messages from outbox: %v", err)
+		return
+	}
+
+	// Channel updates that have been superseded in the meantime are
+	// dropped rather than sent.
+	msgsToSend := make([]lnwire.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		if !d.isMsgStale(msg) {
+			msgsToSend = append(msgsToSend, msg)
+			continue
+		}
+
+		if err := d.cfg.Outbox.DeleteMessage(msg); err != nil {
+			log.Errorf("Unable to delete stale %v from outbox: %v",
+				msg.MsgType(), err)
+		}
+	}
+
+	if len(msgsToSend) == 0 {
+		return
+	}
+
+	log.Debugf("Sending %d undelivered announcements to peer %x",
+		len(msgsToSend), peer.PubKey())
+
+	// We wait for the messages to be written to the peer, so that we only
+	// remove them from the outbox once they were actually sent.
+	if err := peer.SendMessage(true, msgsToSend...); err != nil {
+		log.Debugf("Unable to send undelivered announcements to peer "+
+			"%x: %v", peer.PubKey(), err)
+		return
+	}
+
+	for _, msg := range msgsToSend {
+		if err := d.cfg.Outbox.DeleteMessage(msg); err != nil {
+			log.Errorf("Unable to delete %v from outbox: %v",
+				msg.MsgType(), err)
+		}
+	}
 }
 
 // sendRemoteBatch broadcasts a list of remotely generated announcements to our
@@ -1545,9 +1644,17 @@ func (d *AuthenticatedGossiper) handleNetworkMessages(nMsg *networkMsg,
 // InitSyncState is called by outside sub-systems when a connection is
 // established to a new peer that understands how to perform channel range
 // queries. We'll allocate a new gossip syncer for it, and start any goroutines
-// needed to handle new queries.
+// needed to handle new queries. Any announcements in the outbox are sent to
+// the peer.
 func (d *AuthenticatedGossiper) InitSyncState(syncPeer lnpeer.Peer) {
 	d.syncMgr.InitSyncState(syncPeer)
+
+	// Now that we can gossip with the peer, deliver any of our own
+	// announcements that didn't reach anyone yet.
+	if d.cfg.Outbox != nil {
+		d.wg.Add(1)
+		go d.flushOutbox(syncPeer)
+	}
 }
 
 // PruneSyncState is called by outside sub-systems once a peer that we were
@@ -1994,9 +2101,16 @@ func (d *AuthenticatedGossiper) processNetworkAnnouncement(
 	nMsg *networkMsg) ([]networkMsg, bool) {
 
 	// If this is a remote update, we set the scheduler option to lazily
-	// add it to the graph.
+	// add it to the graph. While we're still performing our initial
+	// historical sync, the update is added as part of a bulk import so
+	// that it can be committed along with even more updates. Once the
+	// graph is synced, we go back to the regular lazy batching.
 	var schedulerOp []batch.SchedulerOption
-	if nMsg.isRemote {
+	switch {
+	case nMsg.isRemote && !d.syncMgr.IsGraphSynced():
+		schedulerOp = append(schedulerOp, batch.BulkAdd())
+
+	case nMsg.isRemote:
 		schedulerOp = append(schedulerOp, batch.LazyAdd())
 	}
 
@@ -4139,3 +4139,119 @@ func TestFutureMsgCacheEviction(t *testing.T) {
 	require.NoError(t, err)
 	require.EqualValues(t, 2, item.height, "should be the second item")
 }
+
+// TestOutboxDeliveryAfterRestart tests that our own announcements that are
+// signed while we aren't connected to any peer are stored in the outbox, and
+// delivered exactly once as soon as the first peer connects, even after a
+// restart.
+func TestOutboxDeliveryAfterRestart(t *testing.T) {
+	t.Parallel()
+
+	const timestamp = 123456
+	nodeAnn, err := createNodeAnnouncement(selfKeyPriv, timestamp, nil)
+	require.NoError(t, err)
+	chanUpd, err := createUpdateAnnouncement(0, 0, selfKeyPriv, timestamp)
+	require.NoError(t, err)
+
+	// newGossiper creates a gossiper whose outbox is stored in the given
+	// database and that knows about the channel of our update.
+	dbDir := t.TempDir()
+	newGossiper := func() (*AuthenticatedGossiper, *channeldb.DB) {
+		ctx, err := createTestCtx(t, 0)
+		require.NoError(t, err)
+
+		db, err := channeldb.Open(dbDir)
+		require.NoError(t, err)
+
+		outbox, err := NewMessageOutbox(db)
+		require.NoError(t, err)
+
+		ctx.router.mu.Lock()
+		ctx.router.infos[chanUpd.ShortChannelID.ToUint64()] =
+			models.ChannelEdgeInfo{}
+		ctx.router.mu.Unlock()
+
+		ctx.gossiper.cfg.Outbox = outbox
+
+		// The gossip syncers of connecting peers need a channel
+		// series to query.
+		ctx.gossiper.syncMgr.cfg.ChanSeries =
+			newMockChannelGraphTimeSeries(lnwire.ShortChannelID{})
+
+		return ctx.gossiper, db
+	}
+
+	// We sign our announcements while we aren't connected to any peer, so
+	// they should end up in the outbox.
+	gossiper, db := newGossiper()
+	gossiper.sendLocalBatch([]msgWithSenders{
+		{msg: nodeAnn}, {msg: chanUpd},
+	})
+
+	msgs, err := gossiper.cfg.Outbox.Messages()
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+
+	// Restart with the same database.
+	require.NoError(t, gossiper.Stop())
+	require.NoError(t, db.Close())
+
+	gossiper, db = newGossiper()
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+
+	// receivedAnns returns the announcements of ours that the peer
+	// received within the given time, ignoring the messages sent by its
+	// gossip syncer.
+	receivedAnns := func(peer *mockPeer,
+		wait time.Duration) []lnwire.Message {
+
+		var anns []lnwire.Message
+		timeout := time.After(wait)
+		for {
+			select {
+			case msg := <-peer.sentMsgs:
+				switch msg.(type) {
+				case *lnwire.NodeAnnouncement,
+					*lnwire.ChannelUpdate:
+
+					anns = append(anns, msg)
+				}
+
+			case <-timeout:
+				return anns
+			}
+		}
+	}
+
+	// Once the first peer connects, it should receive both announcements
+	// and the outbox should be cleared.
+	peer1 := &mockPeer{
+		remoteKeyPriv1.PubKey(), make(chan lnwire.Message, 20),
+		make(chan struct{}),
+	}
+	gossiper.InitSyncState(peer1)
+
+	anns := receivedAnns(peer1, time.Second)
+	require.Len(t, anns, 2)
+	require.ElementsMatch(t, []lnwire.MessageType{
+		lnwire.MsgNodeAnnouncement, lnwire.MsgChannelUpdate,
+	}, []lnwire.MessageType{anns[0].MsgType(), anns[1].MsgType()})
+
+	require.Eventually(t, func() bool {
+		msgs, err := gossiper.cfg.Outbox.Messages()
+		return err == nil && len(msgs) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	// The announcements were delivered, so the next peer to connect
+	// shouldn't receive them from the outbox.
+	peer2 := &mockPeer{
+		remoteKeyPriv2.PubKey(), make(chan lnwire.Message, 20),
+		make(chan struct{}),
+	}
+	gossiper.InitSyncState(peer2)
+
+	require.Empty(t, receivedAnns(peer2, 100*time.Millisecond))
+	require.Empty(t, receivedAnns(peer1, 0))
+}
@@ -0,0 +1,171 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/kvdb"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+var (
+	// outboxBucket is a key used to create a top level bucket in the
+	// gossiper database, used for storing self-originated announcements
+	// that couldn't be delivered to any peer. Upon restarts, these
+	// messages are still sent to the first peer that connects.
+	//
+	// maps:
+	//   msgType (2 bytes) -> msg                      (node announcements)
+	//   msgType (2 bytes) + shortChanID (8 bytes) -> msg (channel updates)
+	outboxBucket = []byte("gossip-outbox")
+)
+
+// GossipOutbox is a store responsible for holding the latest version of each
+// self-originated announcement that we couldn't deliver to any peer.
+type GossipOutbox interface {
+	// PutMessage adds a message to the outbox, replacing any previous
+	// version of the message.
+	PutMessage(lnwire.Message) error
+
+	// DeleteMessage deletes a message from the outbox, unless it has been
+	// replaced by a newer version in the meantime.
+	DeleteMessage(lnwire.Message) error
+
+	// Messages returns all the messages within the outbox.
+	Messages() ([]lnwire.Message, error)
+}
+
+// MessageOutbox is an implementation of the GossipOutbox interface backed by
+// a channeldb instance. It holds at most one node announcement and one
+// channel update per channel.
+type MessageOutbox struct {
+	db kvdb.Backend
+}
+
+// A compile-time assertion to ensure MessageOutbox implements the
+// GossipOutbox interface.
+var _ GossipOutbox = (*MessageOutbox)(nil)
+
+// NewMessageOutbox creates a new outbox backed by a channeldb instance.
+func NewMessageOutbox(db kvdb.Backend) (*MessageOutbox, error) {
+	err := kvdb.Batch(db, func(tx kvdb.RwTx) error {
+		_, err := tx.CreateTopLevelBucket(outboxBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create required buckets: %w",
+			err)
+	}
+
+	return &MessageOutbox{db}, nil
+}
+
+// outboxKey constructs the database key for the message to be stored. Newer
+// versions of a message map to the same key as older ones.
+func outboxKey(msg lnwire.Message) ([]byte, error) {
+	var k [2 + 8]byte
+	binary.BigEndian.PutUint16(k[:2], uint16(msg.MsgType()))
+
+	switch msg := msg.(type) {
+	case *lnwire.NodeAnnouncement:
+		return k[:2], nil
+
+	case *lnwire.ChannelUpdate:
+		binary.BigEndian.PutUint64(
+			k[2:], msg.ShortChannelID.ToUint64(),
+		)
+
+		return k[:], nil
+
+	default:
+		return nil, ErrUnsupportedMessage
+	}
+}
+
+// PutMessage adds a message to the outbox, replacing any previous version of
+// the message.
+func (o *MessageOutbox) PutMessage(msg lnwire.Message) error {
+	log.Tracef("Adding message of type %v to outbox", msg.MsgType())
+
+	msgKey, err := outboxKey(msg)
+	if err != nil {
+		return err
+	}
+
+	// Serialize the message with its wire encoding.
+	var b bytes.Buffer
+	if _, err := lnwire.WriteMessage(&b, msg, 0); err != nil {
+		return err
+	}
+
+	return kvdb.Batch(o.db, func(tx kvdb.RwTx) error {
+		outbox := tx.ReadWriteBucket(outboxBucket)
+		if outbox == nil {
+			return ErrCorruptedMessageStore
+		}
+
+		return outbox.Put(msgKey, b.Bytes())
+	})
+}
+
+// DeleteMessage deletes a message from the outbox, unless it has been
+// replaced by a newer version in the meantime.
+func (o *MessageOutbox) DeleteMessage(msg lnwire.Message) error {
+	log.Tracef("Deleting message of type %v from outbox", msg.MsgType())
+
+	msgKey, err := outboxKey(msg)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	if _, err := lnwire.WriteMessage(&b, msg, 0); err != nil {
+		return err
+	}
+
+	return kvdb.Batch(o.db, func(tx kvdb.RwTx) error {
+		outbox := tx.ReadWriteBucket(outboxBucket)
+		if outbox == nil {
+			return ErrCorruptedMessageStore
+		}
+
+		// Only delete the message if it is the exact one we were
+		// asked to delete, as it may have been replaced by a newer
+		// version that is still to be delivered.
+		if !bytes.Equal(outbox.Get(msgKey), b.Bytes()) {
+			return nil
+		}
+
+		return outbox.Delete(msgKey)
+	})
+}
+
+// Messages returns all the messages within the outbox.
+func (o *MessageOutbox) Messages() ([]lnwire.Message, error) {
+	var msgs []lnwire.Message
+	err := kvdb.View(o.db, func(tx kvdb.RTx) error {
+		outbox := tx.ReadBucket(outboxBucket)
+		if outbox == nil {
+			return ErrCorruptedMessageStore
+		}
+
+		return outbox.ForEach(func(_, v []byte) error {
+			msg, err := lnwire.ReadMessage(bytes.NewReader(v), 0)
+			if err != nil {
+				return err
+			}
+
+			msgs = append(msgs, msg)
+
+			return nil
+		})
+	}, func() {
+		msgs = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return msgs, nil
+}
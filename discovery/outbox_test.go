@@ -0,0 +1,81 @@
+package discovery
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMessageOutbox ensures that the outbox only keeps the latest version of
+// each message, only deletes the exact version it is asked to delete and
+// keeps its messages across restarts.
+func TestMessageOutbox(t *testing.T) {
+	t.Parallel()
+
+	dbDir := t.TempDir()
+	db, err := channeldb.Open(dbDir)
+	require.NoError(t, err)
+
+	outbox, err := NewMessageOutbox(db)
+	require.NoError(t, err)
+
+	nodeAnn, err := createNodeAnnouncement(selfKeyPriv, 1, []byte{})
+	require.NoError(t, err)
+
+	oldUpdate := randChannelUpdate()
+	newUpdate := *oldUpdate
+	newUpdate.Timestamp++
+	otherUpdate := randChannelUpdate()
+
+	// Messages other than node announcements and channel updates can't be
+	// stored in the outbox.
+	err = outbox.PutMessage(randAnnounceSignatures())
+	require.ErrorIs(t, err, ErrUnsupportedMessage)
+
+	// The newer version of the channel update should replace the older
+	// one.
+	require.NoError(t, outbox.PutMessage(nodeAnn))
+	require.NoError(t, outbox.PutMessage(oldUpdate))
+	require.NoError(t, outbox.PutMessage(&newUpdate))
+	require.NoError(t, outbox.PutMessage(otherUpdate))
+
+	msgs, err := outbox.Messages()
+	require.NoError(t, err)
+	require.ElementsMatch(
+		t, []lnwire.Message{nodeAnn, &newUpdate, otherUpdate}, msgs,
+	)
+
+	// Deleting the replaced version of the update should leave the newer
+	// one in place.
+	require.NoError(t, outbox.DeleteMessage(oldUpdate))
+	require.NoError(t, outbox.DeleteMessage(otherUpdate))
+
+	msgs, err = outbox.Messages()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []lnwire.Message{nodeAnn, &newUpdate}, msgs)
+
+	// The messages should still be there after a restart.
+	require.NoError(t, db.Close())
+
+	db, err = channeldb.Open(dbDir)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+
+	outbox, err = NewMessageOutbox(db)
+	require.NoError(t, err)
+
+	msgs, err = outbox.Messages()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []lnwire.Message{nodeAnn, &newUpdate}, msgs)
+
+	require.NoError(t, outbox.DeleteMessage(nodeAnn))
+	require.NoError(t, outbox.DeleteMessage(&newUpdate))
+
+	msgs, err = outbox.Messages()
+	require.NoError(t, err)
+	require.Empty(t, msgs)
+}
@@ -81,6 +81,83 @@ func (e ErrInvalidPayload) Error() string {
 		hopType, e.Violation, e.Type)
 }
 
+// BlindedViolation is an enum describing which of the constraints that a
+// blinded route places on the HTLCs forwarded along it was violated.
+type BlindedViolation byte
+
+const (
+	// BlindedExpiryTooLarge indicates that the expiry of the incoming HTLC
+	// exceeds the route's max_cltv_expiry.
+	BlindedExpiryTooLarge BlindedViolation = iota
+
+	// BlindedAmountTooLow indicates that the amount of the incoming HTLC
+	// is below the route's htlc_minimum_msat.
+	BlindedAmountTooLow
+
+	// BlindedUnknownFeatures indicates that the route requires features
+	// that we don't understand.
+	BlindedUnknownFeatures
+)
+
+// String returns a human-readable description of the violation.
+func (v BlindedViolation) String() string {
+	switch v {
+	case BlindedExpiryTooLarge:
+		return "expiry exceeds max cltv expiry"
+
+	case BlindedAmountTooLow:
+		return "amount below htlc minimum"
+
+	case BlindedUnknownFeatures:
+		return "unknown features"
+
+	default:
+		return "unknown blinded violation"
+	}
+}
+
+// FailureString returns the string representation of the violation, so that
+// it can be used as the failure detail of a failed HTLC.
+func (v BlindedViolation) FailureString() string {
+	return v.String()
+}
+
+// ErrBlindedConstraint is returned when an incoming HTLC violates one of the
+// constraints set in the encrypted data of a blinded route. It embeds the
+// invalid payload error that describes the violation in terms of the onion
+// records, while Reason identifies the constraint that was violated.
+type ErrBlindedConstraint struct {
+	ErrInvalidPayload
+
+	// Reason is the constraint of the blinded route that was violated.
+	Reason BlindedViolation
+}
+
+// Error returns a human-readable description of the blinded constraint error.
+func (e ErrBlindedConstraint) Error() string {
+	return fmt.Sprintf("blinded route constraint violated (%v): %v",
+		e.Reason, e.ErrInvalidPayload)
+}
+
+// Unwrap returns the underlying invalid payload error.
+func (e ErrBlindedConstraint) Unwrap() error {
+	return e.ErrInvalidPayload
+}
+
+// newBlindedConstraintErr creates an error for the violation of a blinded
+// route constraint that is reported on the record of the given type.
+func newBlindedConstraintErr(recordType tlv.Type, violation PayloadViolation,
+	reason BlindedViolation) ErrBlindedConstraint {
+
+	return ErrBlindedConstraint{
+		ErrInvalidPayload: ErrInvalidPayload{
+			Type:      recordType,
+			Violation: violation,
+		},
+		Reason: reason,
+	}
+}
+
 // Payload encapsulates all information delivered to a hop in an onion payload.
 // A Hop can represent either a TLV or legacy payload. The primary forwarding
 // instruction can be accessed via ForwardingInfo, and additional records can be
@@ -475,18 +552,20 @@ func ValidateBlindedRouteData(blindedData *record.BlindedRouteData,
 			// MUST fail if the expiry is greater than
 			// max_cltv_expiry.
 			if incomingTimelock > c.Val.MaxCltvExpiry {
-				err = ErrInvalidPayload{
-					Type:      record.LockTimeOnionType,
-					Violation: InsufficientViolation,
-				}
+				err = newBlindedConstraintErr(
+					record.LockTimeOnionType,
+					InsufficientViolation,
+					BlindedExpiryTooLarge,
+				)
 			}
 
 			// MUST fail if the amount is below htlc_minimum_msat.
 			if incomingAmount < c.Val.HtlcMinimumMsat {
-				err = ErrInvalidPayload{
-					Type:      record.AmtOnionType,
-					Violation: InsufficientViolation,
-				}
+				err = newBlindedConstraintErr(
+					record.AmtOnionType,
+					InsufficientViolation,
+					BlindedAmountTooLow,
+				)
 			}
 		},
 	)
@@ -507,10 +586,11 @@ func ValidateBlindedRouteData(blindedData *record.BlindedRouteData,
 	blindedData.Features.WhenSome(
 		func(f tlv.RecordT[tlv.TlvType14, lnwire.FeatureVector]) {
 			if f.Val.UnknownFeatures() {
-				err = ErrInvalidPayload{
-					Type:      14,
-					Violation: IncludedViolation,
-				}
+				err = newBlindedConstraintErr(
+					14,
+					IncludedViolation,
+					BlindedUnknownFeatures,
+				)
 			}
 		},
 	)
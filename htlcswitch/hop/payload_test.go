@@ -656,9 +656,12 @@ func TestValidateBlindedRouteData(t *testing.T) {
 				nil,
 			),
 			incomingTimelock: 200,
-			err: hop.ErrInvalidPayload{
-				Type:      record.LockTimeOnionType,
-				Violation: hop.InsufficientViolation,
+			err: hop.ErrBlindedConstraint{
+				ErrInvalidPayload: hop.ErrInvalidPayload{
+					Type:      record.LockTimeOnionType,
+					Violation: hop.InsufficientViolation,
+				},
+				Reason: hop.BlindedExpiryTooLarge,
 			},
 		},
 		{
@@ -675,9 +678,12 @@ func TestValidateBlindedRouteData(t *testing.T) {
 			),
 			incomingAmount:   100,
 			incomingTimelock: 10,
-			err: hop.ErrInvalidPayload{
-				Type:      record.LockTimeOnionType,
-				Violation: hop.InsufficientViolation,
+			err: hop.ErrBlindedConstraint{
+				ErrInvalidPayload: hop.ErrInvalidPayload{
+					Type:      record.LockTimeOnionType,
+					Violation: hop.InsufficientViolation,
+				},
+				Reason: hop.BlindedExpiryTooLarge,
 			},
 		},
 		{
@@ -692,9 +698,12 @@ func TestValidateBlindedRouteData(t *testing.T) {
 				nil,
 			),
 			incomingAmount: 10,
-			err: hop.ErrInvalidPayload{
-				Type:      record.AmtOnionType,
-				Violation: hop.InsufficientViolation,
+			err: hop.ErrBlindedConstraint{
+				ErrInvalidPayload: hop.ErrInvalidPayload{
+					Type:      record.AmtOnionType,
+					Violation: hop.InsufficientViolation,
+				},
+				Reason: hop.BlindedAmountTooLow,
 			},
 		},
 		{
@@ -731,9 +740,12 @@ func TestValidateBlindedRouteData(t *testing.T) {
 			),
 			incomingAmount:   40,
 			incomingTimelock: 80,
-			err: hop.ErrInvalidPayload{
-				Type:      14,
-				Violation: hop.IncludedViolation,
+			err: hop.ErrBlindedConstraint{
+				ErrInvalidPayload: hop.ErrInvalidPayload{
+					Type:      14,
+					Violation: hop.IncludedViolation,
+				},
+				Reason: hop.BlindedUnknownFeatures,
 			},
 		},
 		{
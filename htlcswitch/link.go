@@ -3335,10 +3335,13 @@ func (l *channelLink) processRemoteAdds(fwdPkg *channeldb.FwdPkg,
 			// for TLV payloads that also supports injecting invalid
 			// payloads. Deferring this non-trival effort till a
 			// later date
-			failure := lnwire.NewInvalidOnionPayload(failedType, 0)
-			l.sendHTLCError(
-				pd, NewLinkError(failure), obfuscator, false,
+			failure := NewLinkError(
+				lnwire.NewInvalidOnionPayload(failedType, 0),
 			)
+			if obfuscator.Type().IsBlinded() {
+				failure = blindedLinkError(pldErr, onionBlob[:])
+			}
+			l.sendHTLCError(pd, failure, obfuscator, false)
 
 			l.log.Errorf("unable to decode forwarding "+
 				"instructions: %v", pldErr)
@@ -3768,6 +3771,26 @@ func (l *channelLink) sendHTLCError(pd *lnwallet.PaymentDescriptor,
 	)
 }
 
+// blindedLinkError returns the link error for an incoming HTLC in a blinded
+// route that failed validation. The sender must only learn that the blinded
+// route was invalid, so the wire failure is always invalid_onion_blinding
+// (which sendIncomingHTLCFailureMsg switches out depending on our role in the
+// route). If the HTLC violated one of the route's constraints, the violation
+// is kept as the failure detail so that it's available for local reporting.
+func blindedLinkError(err error, onionBlob []byte) *LinkError {
+	failure := lnwire.NewInvalidBlinding(onionBlob)
+
+	// The hop iterator returns validation errors unwrapped, so a type
+	// assertion suffices.
+	//
+	//nolint:errorlint
+	if constraintErr, ok := err.(hop.ErrBlindedConstraint); ok {
+		return NewDetailedLinkError(failure, constraintErr.Reason)
+	}
+
+	return NewLinkError(failure)
+}
+
 // sendPeerHTLCFailure handles sending a HTLC failure message back to the
 // peer from which the HTLC was received. This function is primarily used to
 // handle the special requirements of route blinding, specifically:
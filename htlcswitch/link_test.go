@@ -38,6 +38,7 @@ import (
 	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
 	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
 	"github.com/lightningnetwork/lnd/ticker"
 	"github.com/stretchr/testify/require"
 )
@@ -7489,3 +7490,147 @@ func TestLinkFlushHooksCalled(t *testing.T) {
 	ctx.receiveRevAndAckAliceToBob()
 	assertHookCalled(true)
 }
+
+// TestBlindedLinkError tests that HTLCs that violate the constraints of a
+// blinded route are failed back with invalid_onion_blinding on the wire,
+// while the violated constraint is recorded as the local failure detail.
+func TestBlindedLinkError(t *testing.T) {
+	t.Parallel()
+
+	harness, err := newSingleLinkTestHarness(
+		t, 5*btcutil.SatoshiPerBitcoin, 0,
+	)
+	require.NoError(t, err)
+
+	link, ok := harness.aliceLink.(*channelLink)
+	require.True(t, ok)
+	peer, ok := link.cfg.Peer.(*mockPeer)
+	require.True(t, ok)
+
+	var (
+		scid        = lnwire.NewShortChanIDFromInt(1)
+		onionBlob   = []byte{1, 2, 3}
+		constraints = &record.PaymentConstraints{
+			MaxCltvExpiry:   100,
+			HtlcMinimumMsat: 20,
+		}
+		unknownFeatures = lnwire.NewFeatureVector(
+			lnwire.NewRawFeatureVector(lnwire.FeatureBit(9999)),
+			lnwire.Features,
+		)
+	)
+
+	// As the introduction node, we fail the HTLC with an invalid blinding
+	// failure that we encrypt as if we were the failing node.
+	expectedIntroReason, err := NewMockObfuscator().EncryptFirstHop(
+		lnwire.NewInvalidBlinding(nil),
+	)
+	require.NoError(t, err)
+
+	var expectedWireMsg bytes.Buffer
+	err = lnwire.EncodeFailureMessage(
+		&expectedWireMsg, lnwire.NewInvalidBlinding(onionBlob), 0,
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name             string
+		features         *lnwire.FeatureVector
+		incomingAmount   lnwire.MilliSatoshi
+		incomingTimelock uint32
+		reason           hop.BlindedViolation
+	}{
+		{
+			name:             "expiry too large",
+			incomingAmount:   40,
+			incomingTimelock: 200,
+			reason:           hop.BlindedExpiryTooLarge,
+		},
+		{
+			name:             "amount too low",
+			incomingAmount:   10,
+			incomingTimelock: 80,
+			reason:           hop.BlindedAmountTooLow,
+		},
+		{
+			name:             "unknown features",
+			features:         unknownFeatures,
+			incomingAmount:   40,
+			incomingTimelock: 80,
+			reason:           hop.BlindedUnknownFeatures,
+		},
+	}
+
+	for _, testCase := range tests {
+		data := record.NewBlindedRouteData(
+			scid, nil, record.PaymentRelayInfo{}, constraints,
+			testCase.features,
+		)
+		validationErr := hop.ValidateBlindedRouteData(
+			data, testCase.incomingAmount,
+			testCase.incomingTimelock,
+		)
+		require.Error(t, validationErr, testCase.name)
+
+		failure := blindedLinkError(validationErr, onionBlob)
+		require.Equal(
+			t, testCase.reason, failure.FailureDetail,
+			testCase.name,
+		)
+
+		var wireMsg bytes.Buffer
+		err := lnwire.EncodeFailureMessage(
+			&wireMsg, failure.WireMessage(), 0,
+		)
+		require.NoError(t, err, testCase.name)
+		require.Equal(
+			t, expectedWireMsg.Bytes(), wireMsg.Bytes(),
+			testCase.name,
+		)
+
+		// Fail the HTLC as the introduction node, which should send
+		// our peer an update_fail_htlc.
+		introEncrypter := &hop.IntroductionErrorEncrypter{
+			ErrorEncrypter: NewMockObfuscator(),
+		}
+		reason, err := introEncrypter.EncryptFirstHop(
+			failure.WireMessage(),
+		)
+		require.NoError(t, err, testCase.name)
+		require.NoError(t, link.sendIncomingHTLCFailureMsg(
+			0, introEncrypter, reason,
+		), testCase.name)
+
+		msg := <-peer.sentMsgs
+		failMsg, ok := msg.(*lnwire.UpdateFailHTLC)
+		require.True(t, ok, testCase.name)
+		require.Equal(
+			t, expectedIntroReason, failMsg.Reason, testCase.name,
+		)
+
+		// Fail the HTLC as a relaying node, which should send our peer
+		// an update_fail_malformed_htlc.
+		relayEncrypter := &hop.RelayingErrorEncrypter{
+			ErrorEncrypter: NewMockObfuscator(),
+		}
+		require.NoError(t, link.sendIncomingHTLCFailureMsg(
+			0, relayEncrypter, reason,
+		), testCase.name)
+
+		msg = <-peer.sentMsgs
+		malformedMsg, ok := msg.(*lnwire.UpdateFailMalformedHTLC)
+		require.True(t, ok, testCase.name)
+		require.Equal(
+			t, lnwire.CodeInvalidBlinding, malformedMsg.FailureCode,
+			testCase.name,
+		)
+	}
+
+	// Failures that aren't caused by a violated constraint, such as
+	// blinded data that can't be decoded, carry no failure detail.
+	failure := blindedLinkError(hop.ErrDecodeFailed, onionBlob)
+	require.Nil(t, failure.FailureDetail)
+	require.Equal(
+		t, lnwire.NewInvalidBlinding(onionBlob), failure.WireMessage(),
+	)
+}
@@ -2277,6 +2277,62 @@ func (s *Switch) Stop() error {
 	return nil
 }
 
+// DrainLinks stops forwarding new HTLCs over all active links and waits up to
+// the given timeout for the links to resolve the HTLCs that are still in
+// flight, so that they aren't failed abruptly once the links are torn down.
+// The number of links that were fully drained and the number of links that
+// still had HTLCs in flight when the timeout expired are returned.
+//
+// NOTE: Only outgoing adds are disabled, so a link with an incoming HTLC that
+// terminates at us, such as an accepted hold invoice HTLC, won't become clean
+// within the timeout and is counted as abandoned. The same goes for a link
+// whose htlcManager loop hasn't started yet, e.g. because it's still waiting
+// for the peer to reestablish the channel.
+func (s *Switch) DrainLinks(timeout time.Duration) (int, int) {
+	s.indexMtx.RLock()
+	links := make([]ChannelLink, 0, len(s.linkIndex))
+	for _, link := range s.linkIndex {
+		links = append(links, link)
+	}
+	s.indexMtx.RUnlock()
+
+	// Start the timeout before touching the links, as registering a flush
+	// hook blocks until the link's htlcManager loop picks it up, which
+	// may never happen for a link that is stuck syncing its channel
+	// state.
+	timeoutChan := s.cfg.Clock.TickAfter(timeout)
+
+	// Make sure none of the links accepts any new outgoing HTLCs, which
+	// prevents any new HTLCs from being forwarded through us. The HTLCs
+	// that are already in flight can still be settled or failed. The
+	// flush hooks are registered in their own goroutines, which exit once
+	// the hook is taken or the link is stopped.
+	flushed := make(chan struct{}, len(links))
+	for _, link := range links {
+		link.DisableAdds(Outgoing)
+
+		go link.OnFlushedOnce(func() {
+			flushed <- struct{}{}
+		})
+	}
+
+	var drained int
+	for drained < len(links) {
+		select {
+		case <-flushed:
+			drained++
+
+		case <-timeoutChan:
+			return drained, len(links) - drained
+
+		case <-s.quit:
+			return drained, len(links) - drained
+		}
+	}
+
+	return drained, 0
+}
+
 // CreateAndAddLink will create a link and then add it to the internal maps
 // when given a ChannelLinkConfig and LightningChannel.
 func (s *Switch) CreateAndAddLink(linkCfg ChannelLinkConfig,
@@ -8,6 +8,7 @@ import (
 	"io"
 	mrand "math/rand"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -5539,3 +5540,204 @@ func testSwitchAliasInterceptFail(t *testing.T, zeroConf bool) {
 
 	require.NoError(t, interceptSwitch.Stop())
 }
+
+// drainTestLink is a mock link that records the flush hook registered by the
+// switch, so the test can control when its in-flight HTLCs are resolved.
+type drainTestLink struct {
+	*mockChannelLink
+
+	mu          sync.Mutex
+	disabled    []LinkDirection
+	flushedHook func()
+}
+
+func (l *drainTestLink) DisableAdds(linkDirection LinkDirection) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.disabled = append(l.disabled, linkDirection)
+
+	return true
+}
+
+func (l *drainTestLink) OnFlushedOnce(hook func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.flushedHook = hook
+}
+
+// resolve mimics the link resolving its last in-flight HTLC.
+func (l *drainTestLink) resolve() {
+	l.mu.Lock()
+	hook := l.flushedHook
+	l.mu.Unlock()
+
+	if hook != nil {
+		hook()
+	}
+}
+
+// TestSwitchDrainLinks asserts that DrainLinks stops new HTLCs from being
+// forwarded over the active links and waits for their in-flight HTLCs to be
+// resolved, abandoning the links that don't resolve before the timeout.
+func TestSwitchDrainLinks(t *testing.T) {
+	t.Parallel()
+
+	alicePeer, err := newMockServer(
+		t, "alice", testStartingHeight, nil, testDefaultDelta,
+	)
+	require.NoError(t, err, "unable to create alice server")
+	bobPeer, err := newMockServer(
+		t, "bob", testStartingHeight, nil, testDefaultDelta,
+	)
+	require.NoError(t, err, "unable to create bob server")
+
+	s, err := initSwitchWithTempDB(t, testStartingHeight)
+	require.NoError(t, err, "unable to init switch")
+	require.NoError(t, s.Start(), "unable to start switch")
+	defer s.Stop()
+
+	chanID1, aliceScid := genID()
+	chanID2, bobScid := genID()
+
+	// Alice's link resolves its in-flight forward within the timeout,
+	// while Bob's link never does.
+	aliceLink := &drainTestLink{
+		mockChannelLink: newMockChannelLink(
+			s, chanID1, aliceScid, emptyScid, alicePeer, true,
+			false, false, false,
+		),
+	}
+	bobLink := &drainTestLink{
+		mockChannelLink: newMockChannelLink(
+			s, chanID2, bobScid, emptyScid, bobPeer, true, false,
+			false, false,
+		),
+	}
+	require.NoError(t, s.AddLink(aliceLink))
+	require.NoError(t, s.AddLink(bobLink))
+
+	type drainResult struct {
+		drained   int
+		abandoned int
+	}
+	resultChan := make(chan drainResult, 1)
+	go func() {
+		drained, abandoned := s.DrainLinks(time.Second)
+		resultChan <- drainResult{drained, abandoned}
+	}()
+
+	// Wait for the switch to register its flush hook on Alice's link
+	// before resolving her forward.
+	require.Eventually(t, func() bool {
+		aliceLink.mu.Lock()
+		defer aliceLink.mu.Unlock()
+
+		return aliceLink.flushedHook != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	aliceLink.resolve()
+
+	select {
+	case result := <-resultChan:
+		require.Equal(t, drainResult{1, 1}, result)
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("links weren't drained")
+	}
+
+	// Only outgoing adds should have been disabled on both links, as our
+	// peers may still add HTLCs that we'll fail back.
+	for _, link := range []*drainTestLink{aliceLink, bobLink} {
+		link.mu.Lock()
+		require.Equal(t, []LinkDirection{Outgoing}, link.disabled)
+		link.mu.Unlock()
+	}
+}
+
+// blockedDrainTestLink is a mock link whose htlcManager loop never starts, so
+// registering a flush hook blocks until the link is stopped.
+type blockedDrainTestLink struct {
+	*mockChannelLink
+
+	quit chan struct{}
+}
+
+func (l *blockedDrainTestLink) DisableAdds(LinkDirection) bool {
+	return true
+}
+
+func (l *blockedDrainTestLink) OnFlushedOnce(func()) {
+	<-l.quit
+}
+
+// TestSwitchDrainLinksBlockedLink asserts that DrainLinks returns at the
+// timeout and counts a link as abandoned if registering its flush hook blocks,
+// as is the case for a link that still waits for the peer to reestablish the
+// channel.
+func TestSwitchDrainLinksBlockedLink(t *testing.T) {
+	t.Parallel()
+
+	alicePeer, err := newMockServer(
+		t, "alice", testStartingHeight, nil, testDefaultDelta,
+	)
+	require.NoError(t, err, "unable to create alice server")
+	bobPeer, err := newMockServer(
+		t, "bob", testStartingHeight, nil, testDefaultDelta,
+	)
+	require.NoError(t, err, "unable to create bob server")
+
+	s, err := initSwitchWithTempDB(t, testStartingHeight)
+	require.NoError(t, err, "unable to init switch")
+	require.NoError(t, s.Start(), "unable to start switch")
+	defer s.Stop()
+
+	chanID1, aliceScid := genID()
+	chanID2, bobScid := genID()
+
+	// Alice's link is still syncing its channel state, while Bob's link
+	// has no HTLCs in flight.
+	aliceLink := &blockedDrainTestLink{
+		mockChannelLink: newMockChannelLink(
+			s, chanID1, aliceScid, emptyScid, alicePeer, true,
+			false, false, false,
+		),
+		quit: make(chan struct{}),
+	}
+	defer close(aliceLink.quit)
+
+	bobLink := &drainTestLink{
+		mockChannelLink: newMockChannelLink(
+			s, chanID2, bobScid, emptyScid, bobPeer, true, false,
+			false, false,
+		),
+	}
+	require.NoError(t, s.AddLink(aliceLink))
+	require.NoError(t, s.AddLink(bobLink))
+
+	type drainResult struct {
+		drained   int
+		abandoned int
+	}
+	resultChan := make(chan drainResult, 1)
+	go func() {
+		drained, abandoned := s.DrainLinks(time.Second)
+		resultChan <- drainResult{drained, abandoned}
+	}()
+
+	require.Eventually(t, func() bool {
+		bobLink.mu.Lock()
+		defer bobLink.mu.Unlock()
+
+		return bobLink.flushedHook != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	bobLink.resolve()
+
+	select {
+	case result := <-resultChan:
+		require.Equal(t, drainResult{1, 1}, result)
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("drain blocked on a link that isn't running")
+	}
+}
@@ -94,6 +94,26 @@ var (
 	// ErrNoPaymentsCreated is returned when bucket of payments hasn't been
 	// created.
 	ErrNoPaymentsCreated = errors.New("there are no existing payments")
+
+	// ErrIdempotencyKeyNotFound is returned when no invoice was created for
+	// an idempotency key, or when the key expired.
+	ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+
+	// ErrIdempotencyKeyMismatch is returned when an invoice creation
+	// request is retried with an idempotency key that was used to create
+	// an invoice with different parameters.
+	ErrIdempotencyKeyMismatch = errors.New("idempotency key was used to " +
+		"create an invoice with different parameters")
+
+	// ErrInvalidIdempotencyKey is returned when an idempotency key is
+	// empty or exceeds MaxIdempotencyKeySize.
+	ErrInvalidIdempotencyKey = fmt.Errorf("idempotency key must be "+
+		"between 1 and %d bytes", MaxIdempotencyKeySize)
+
+	// ErrIdempotencyKeysDisabled is returned when an invoice is added with
+	// an idempotency key, but the registry has no store for them.
+	ErrIdempotencyKeysDisabled = errors.New("idempotency keys are not " +
+		"supported")
 )
 
 // ErrDuplicateSetID is an error returned when attempting to adding an AMP HTLC
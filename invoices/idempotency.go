@@ -0,0 +1,102 @@
+package invoices
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+const (
+	// MaxIdempotencyKeySize is the maximum size in bytes of the key that a
+	// client chooses to identify an invoice creation request.
+	MaxIdempotencyKeySize = 64
+
+	// DefaultIdempotencyKeyTTL is the default duration for which a retried
+	// invoice creation request returns the invoice that was created for
+	// its idempotency key.
+	DefaultIdempotencyKeyTTL = 24 * time.Hour
+)
+
+// IdempotencyKey identifies an invoice creation request that a client can
+// safely retry, without creating a second invoice. The key is chosen by the
+// client and is scoped to the identity of the client, so that clients can't
+// collide with or probe each other's keys.
+type IdempotencyKey struct {
+	// Scope identifies the client that creates the invoice, such as the
+	// id of the macaroon it authenticated with.
+	Scope []byte
+
+	// Key is the key chosen by the client.
+	Key []byte
+}
+
+// Validate returns ErrInvalidIdempotencyKey if the key is empty or too large.
+func (k IdempotencyKey) Validate() error {
+	if len(k.Key) == 0 || len(k.Key) > MaxIdempotencyKeySize {
+		return ErrInvalidIdempotencyKey
+	}
+
+	return nil
+}
+
+// ID returns the identifier under which the key is stored. Both the scope and
+// the key are length prefixed, so that a key can never have the same
+// identifier as a key of another scope.
+func (k IdempotencyKey) ID() [32]byte {
+	var lenBuf [4]byte
+
+	h := sha256.New()
+
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(k.Scope)))
+	h.Write(lenBuf[:])
+	h.Write(k.Scope)
+
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(k.Key)))
+	h.Write(lenBuf[:])
+	h.Write(k.Key)
+
+	var id [32]byte
+	copy(id[:], h.Sum(nil))
+
+	return id
+}
+
+// IdempotentInvoiceParams are the parameters of a request to create an
+// invoice with an idempotency key. A retry of the request must use the same
+// parameters, so that it's never answered with an invoice that differs from
+// the one it asked for.
+type IdempotentInvoiceParams struct {
+	// Value is the amount of the invoice.
+	Value lnwire.MilliSatoshi
+
+	// Memo is the memo of the invoice.
+	Memo []byte
+
+	// Expiry is the expiry of the invoice's payment request.
+	Expiry time.Duration
+}
+
+// check returns ErrIdempotencyKeyMismatch if the given invoice wasn't created
+// with these parameters.
+func (p IdempotentInvoiceParams) check(invoice *Invoice) error {
+	switch {
+	case invoice.Terms.Value != p.Value:
+		return fmt.Errorf("%w: amount %v differs from %v",
+			ErrIdempotencyKeyMismatch, p.Value, invoice.Terms.Value)
+
+	case !bytes.Equal(invoice.Memo, p.Memo):
+		return fmt.Errorf("%w: memo differs",
+			ErrIdempotencyKeyMismatch)
+
+	case invoice.Terms.Expiry != p.Expiry:
+		return fmt.Errorf("%w: expiry %v differs from %v",
+			ErrIdempotencyKeyMismatch, p.Expiry,
+			invoice.Terms.Expiry)
+	}
+
+	return nil
+}
@@ -85,6 +85,18 @@ type InvoiceDB interface {
 	// DeleteCanceledInvoices removes all canceled invoices from the
 	// database.
 	DeleteCanceledInvoices(ctx context.Context) error
+
+	// ReplaceInvoice cancels the open invoice identified by the passed
+	// reference and adds the new invoice in its place. Both operations are
+	// carried out within a single database transaction, so either the old
+	// invoice is canceled and the new one is added, or nothing changes.
+	// Only invoices in the ContractOpen state can be replaced. The
+	// canceled invoice is returned.
+	//
+	// NOTE: A side effect of this function is that it sets AddIndex on
+	// newInvoice.
+	ReplaceInvoice(ctx context.Context, ref InvoiceRef, newInvoice *Invoice,
+		newPaymentHash lntypes.Hash) (*Invoice, error)
 }
 
 // Payload abstracts access to any additional fields provided in the final hop's
@@ -198,3 +210,19 @@ type InvoiceUpdater interface {
 	// Finalize finalizes the update before it is written to the database.
 	Finalize(updateType UpdateType) error
 }
+
+// IdempotencyKeyStore stores which invoice was created for the idempotency key
+// of an invoice creation request, so that a retried request can return the
+// same invoice.
+type IdempotencyKeyStore interface {
+	// FetchIdempotencyKey returns the payment hash of the invoice that was
+	// created for the given key. If no invoice was created for the key,
+	// or if the key expired, ErrIdempotencyKeyNotFound is returned.
+	FetchIdempotencyKey(key IdempotencyKey) (lntypes.Hash, error)
+
+	// PutIdempotencyKey stores the payment hash of the invoice created for
+	// the given key, until the key expires at the given time. An existing
+	// entry for the key is replaced.
+	PutIdempotencyKey(key IdempotencyKey, paymentHash lntypes.Hash,
+		expiry time.Time) error
+}
@@ -54,7 +54,19 @@ func (b invoiceExpiryHeight) Less(other queue.PriorityQueueItem) bool {
 // expired returns a boolean that indicates whether this entry has expired,
 // taking our expiry delta into account.
 func (b invoiceExpiryHeight) expired(currentHeight, delta uint32) bool {
-	return currentHeight+delta >= b.expiryHeight
+	return currentHeight >= CancelHeight(b.expiryHeight, delta)
+}
+
+// CancelHeight returns the block height at which a hold invoice is canceled
+// automatically, given the expiry height of its earliest htlc and the hold
+// expiry delta. If the delta exceeds the expiry height, zero is returned, as
+// the invoice is canceled right away.
+func CancelHeight(htlcExpiry, holdExpiryDelta uint32) uint32 {
+	if holdExpiryDelta >= htlcExpiry {
+		return 0
+	}
+
+	return htlcExpiry - holdExpiryDelta
 }
 
 // InvoiceExpiryWatcher handles automatic invoice cancellation of expired
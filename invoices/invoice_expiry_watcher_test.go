@@ -290,3 +290,68 @@ func TestExpiryHeightArrives(t *testing.T) {
 	test.announceBlock(t, htlc2-delta)
 	test.assertCanceled(t, test.hash)
 }
+
+// TestCancelHeight tests the computation of the height at which a hold invoice
+// is canceled automatically.
+func TestCancelHeight(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		htlcExpiry      uint32
+		holdExpiryDelta uint32
+		cancelHeight    uint32
+	}{
+		{
+			name:            "no delta",
+			htlcExpiry:      100,
+			holdExpiryDelta: 0,
+			cancelHeight:    100,
+		},
+		{
+			name:            "delta below expiry",
+			htlcExpiry:      100,
+			holdExpiryDelta: 30,
+			cancelHeight:    70,
+		},
+		{
+			name:            "delta equal to expiry",
+			htlcExpiry:      100,
+			holdExpiryDelta: 100,
+			cancelHeight:    0,
+		},
+		{
+			name:            "delta exceeds expiry",
+			htlcExpiry:      10,
+			holdExpiryDelta: 30,
+			cancelHeight:    0,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			height := CancelHeight(
+				test.htlcExpiry, test.holdExpiryDelta,
+			)
+			require.Equal(t, test.cancelHeight, height)
+
+			// The invoice must be expired exactly from the cancel
+			// height onwards.
+			entry := invoiceExpiryHeight{
+				expiryHeight: test.htlcExpiry,
+			}
+			require.True(t, entry.expired(
+				height, test.holdExpiryDelta,
+			))
+			if height > 0 {
+				require.False(t, entry.expired(
+					height-1, test.holdExpiryDelta,
+				))
+			}
+		})
+	}
+}
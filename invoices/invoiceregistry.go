@@ -74,6 +74,17 @@ type RegistryConfig struct {
 	// KeysendHoldTime indicates for how long we want to accept and hold
 	// spontaneous keysend payments.
 	KeysendHoldTime time.Duration
+
+	// IdempotencyKeys stores the idempotency keys of invoices that are
+	// added with AddIdempotentInvoice. If nil, adding invoices with an
+	// idempotency key isn't supported.
+	IdempotencyKeys IdempotencyKeyStore
+
+	// IdempotencyKeyTTL is the duration for which an invoice creation
+	// request that is retried with the same idempotency key returns the
+	// invoice that was created first. If zero, DefaultIdempotencyKeyTTL
+	// is used.
+	IdempotencyKeyTTL time.Duration
 }
 
 // htlcReleaseEvent describes an htlc auto-release event. It is used to release
@@ -146,6 +157,11 @@ type InvoiceRegistry struct {
 
 	expiryWatcher *InvoiceExpiryWatcher
 
+	// idempotencyMtx serializes the invoices that are added with an
+	// idempotency key, so that concurrent requests with the same key
+	// don't both create an invoice.
+	idempotencyMtx sync.Mutex
+
 	wg   sync.WaitGroup
 	quit chan struct{}
 }
@@ -574,6 +590,123 @@ func (i *InvoiceRegistry) AddInvoice(ctx context.Context, invoice *Invoice,
 	return addIndex, nil
 }
 
+// AddIdempotentInvoice adds the invoice returned by newInvoice like AddInvoice
+// does, and records it for the given idempotency key. If an invoice was added
+// for the same key before and the key hasn't expired yet, that invoice is
+// returned instead and newInvoice isn't called. This allows clients to safely
+// retry a request whose response got lost, without creating a second invoice.
+// A retry must use the same params as the original request, otherwise
+// ErrIdempotencyKeyMismatch is returned.
+func (i *InvoiceRegistry) AddIdempotentInvoice(ctx context.Context,
+	key IdempotencyKey, params IdempotentInvoiceParams,
+	newInvoice func() (*Invoice, lntypes.Hash, error)) (*Invoice,
+	lntypes.Hash, error) {
+
+	if i.cfg.IdempotencyKeys == nil {
+		return nil, lntypes.Hash{}, ErrIdempotencyKeysDisabled
+	}
+	if err := key.Validate(); err != nil {
+		return nil, lntypes.Hash{}, err
+	}
+
+	i.idempotencyMtx.Lock()
+	defer i.idempotencyMtx.Unlock()
+
+	paymentHash, err := i.cfg.IdempotencyKeys.FetchIdempotencyKey(key)
+	switch {
+	case err == nil:
+		invoice, err := i.LookupInvoice(ctx, paymentHash)
+		switch {
+		case err == nil:
+			if err := params.check(&invoice); err != nil {
+				return nil, lntypes.Hash{}, err
+			}
+
+			ref := InvoiceRefByHash(paymentHash)
+			log.Debugf("Invoice%v: returning existing invoice "+
+				"for idempotency key", ref)
+
+			return &invoice, paymentHash, nil
+
+		// If the invoice was deleted in the meantime, we'll create a
+		// new one for the key.
+		case !errors.Is(err, ErrInvoiceNotFound):
+			return nil, lntypes.Hash{}, err
+		}
+
+	case !errors.Is(err, ErrIdempotencyKeyNotFound):
+		return nil, lntypes.Hash{}, err
+	}
+
+	invoice, paymentHash, err := newInvoice()
+	if err != nil {
+		return nil, lntypes.Hash{}, err
+	}
+
+	if _, err := i.AddInvoice(ctx, invoice, paymentHash); err != nil {
+		return nil, lntypes.Hash{}, err
+	}
+
+	ttl := i.cfg.IdempotencyKeyTTL
+	if ttl == 0 {
+		ttl = DefaultIdempotencyKeyTTL
+	}
+
+	// We only record the key once the invoice was added, so that the key
+	// never refers to an invoice that wasn't created for it, such as an
+	// existing invoice of another client with the same payment hash. The
+	// invoice was created regardless, so we still return it if the key
+	// can't be recorded, only retries won't find it.
+	err = i.cfg.IdempotencyKeys.PutIdempotencyKey(
+		key, paymentHash, i.cfg.Clock.Now().Add(ttl),
+	)
+	if err != nil {
+		log.Errorf("Invoice%v: unable to record idempotency key: %v",
+			InvoiceRefByHash(paymentHash), err)
+	}
+
+	return invoice, paymentHash, nil
+}
+
+// ReplaceInvoice atomically cancels the open invoice identified by the passed
+// payment hash and adds the new invoice in its place. Invoices that have
+// already been accepted, settled or canceled are refused. Subscribers are
+// notified of both the cancellation and the new invoice.
+func (i *InvoiceRegistry) ReplaceInvoice(ctx context.Context,
+	oldPaymentHash lntypes.Hash, newInvoice *Invoice,
+	newPaymentHash lntypes.Hash) (uint64, error) {
+
+	i.Lock()
+
+	ref := InvoiceRefByHash(oldPaymentHash)
+	newRef := InvoiceRefByHash(newPaymentHash)
+	log.Debugf("Invoice%v: replacing with Invoice%v", ref, newRef)
+
+	canceledInvoice, err := i.idb.ReplaceInvoice(
+		ctx, ref, newInvoice, newPaymentHash,
+	)
+	if err != nil {
+		i.Unlock()
+		return 0, err
+	}
+
+	log.Debugf("Invoice%v: canceled, replaced by Invoice%v", ref, newRef)
+
+	i.notifyClients(oldPaymentHash, canceledInvoice, nil)
+	i.notifyClients(newPaymentHash, newInvoice, nil)
+	i.Unlock()
+
+	// InvoiceExpiryWatcher.AddInvoice must not be locked by InvoiceRegistry
+	// to avoid deadlock when a new invoice is added while an other is being
+	// canceled.
+	invoiceExpiryRef := makeInvoiceExpiry(newPaymentHash, newInvoice)
+	if invoiceExpiryRef != nil {
+		i.expiryWatcher.AddInvoices(invoiceExpiryRef)
+	}
+
+	return newInvoice.AddIndex, nil
+}
+
 // LookupInvoice looks up an invoice by its payment hash (R-Hash), if found
 // then we're able to pull the funds pending within an HTLC.
 //
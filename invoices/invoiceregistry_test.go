@@ -109,6 +109,14 @@ func TestInvoiceRegistry(t *testing.T) {
 			name: "SpontaneousAmpPayment",
 			test: testSpontaneousAmpPayment,
 		},
+		{
+			name: "HoldInvoiceManyShards",
+			test: testHoldInvoiceManyShards,
+		},
+		{
+			name: "AddIdempotentInvoice",
+			test: testAddIdempotentInvoice,
+		},
 	}
 
 	makeKeyValueDB := func(t *testing.T) (invpkg.InvoiceDB,
@@ -1119,6 +1127,108 @@ func testMppPayment(t *testing.T,
 	}
 }
 
+// testHoldInvoiceManyShards tests that a hold invoice paid with many small
+// shards only notifies subscribers once the full amount has been accepted, and
+// that a shard that times out before the set is complete no longer counts
+// towards the total.
+func testHoldInvoiceManyShards(t *testing.T,
+	makeDB func(t *testing.T) (invpkg.InvoiceDB, *clock.TestClock)) {
+
+	t.Parallel()
+	defer timeout()()
+
+	const numShards = 50
+
+	ctx := newTestContext(t, nil, makeDB)
+	ctxb := context.Background()
+
+	subscription, err := ctx.registry.SubscribeSingleInvoice(
+		ctxb, testInvoicePaymentHash,
+	)
+	require.NoError(t, err)
+	defer subscription.Cancel()
+
+	// Add the hold invoice.
+	testInvoice := newInvoice(t, true)
+	_, err = ctx.registry.AddInvoice(
+		ctxb, testInvoice, testInvoicePaymentHash,
+	)
+	require.NoError(t, err)
+
+	update := <-subscription.Updates
+	require.Equal(t, invpkg.ContractOpen, update.State)
+
+	mppPayload := &mockPayload{
+		mpp: record.NewMPP(testInvoiceAmount, [32]byte{}),
+	}
+	shardAmt := testInvoiceAmount / numShards
+	hodlChan := make(chan interface{}, numShards+1)
+
+	sendShard := func(htlcID uint64) {
+		t.Helper()
+
+		resolution, err := ctx.registry.NotifyExitHopHtlc(
+			testInvoicePaymentHash, shardAmt, testHtlcExpiry,
+			testCurrentHeight, getCircuitKey(htlcID), hodlChan,
+			mppPayload,
+		)
+		require.NoError(t, err)
+		require.Nil(t, resolution)
+	}
+
+	// Send the first shard, and all but one of the remaining shards a bit
+	// later so that only the first one times out.
+	sendShard(0)
+
+	ctx.clock.SetTime(testTime.Add(20 * time.Second))
+	for i := uint64(1); i < numShards; i++ {
+		sendShard(i)
+	}
+
+	ctx.clock.SetTime(testTime.Add(30 * time.Second))
+
+	htlcResolution, _ := (<-hodlChan).(invpkg.HtlcResolution)
+	failResolution, ok := htlcResolution.(*invpkg.HtlcFailResolution)
+	require.True(t, ok)
+	require.Equal(t, invpkg.ResultMppTimeout, failResolution.Outcome)
+	require.Equal(t, getCircuitKey(0), failResolution.CircuitKey())
+
+	// Without the timed out shard the set is incomplete, so the invoice
+	// must still be open.
+	inv, err := ctx.registry.LookupInvoice(ctxb, testInvoicePaymentHash)
+	require.NoError(t, err)
+	require.Equal(t, invpkg.ContractOpen, inv.State)
+
+	// Replace the timed out shard, which completes the set.
+	sendShard(numShards)
+
+	inv, err = ctx.registry.LookupInvoice(ctxb, testInvoicePaymentHash)
+	require.NoError(t, err)
+	require.Equal(t, invpkg.ContractAccepted, inv.State)
+
+	// Subscribers are notified exactly once, when the invoice is
+	// accepted, rather than for every single shard.
+	update = <-subscription.Updates
+	require.Equal(t, invpkg.ContractAccepted, update.State)
+	require.Len(t, update.Htlcs, numShards+1)
+
+	select {
+	case update := <-subscription.Updates:
+		t.Fatalf("unexpected invoice update: %v", update.State)
+
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// None of the accepted shards are resolved until the invoice is
+	// settled.
+	select {
+	case res := <-hodlChan:
+		t.Fatalf("unexpected htlc resolution: %T", res)
+
+	default:
+	}
+}
+
 // testMppPaymentWithOverpayment tests settling of an invoice with multiple
 // partial payments. It covers the case where the mpp overpays what is in the
 // invoice.
@@ -2095,3 +2205,168 @@ func testSpontaneousAmpPaymentImpl(
 		}
 	}
 }
+
+// testAddIdempotentInvoice tests that retrying to add an invoice with the same
+// idempotency key returns the invoice that was added first, until the key
+// expires, and that keys of different scopes don't collide.
+func testAddIdempotentInvoice(t *testing.T,
+	makeDB func(t *testing.T) (invpkg.InvoiceDB, *clock.TestClock)) {
+
+	t.Parallel()
+
+	const ttl = time.Hour
+
+	// The idempotency keys are stored in a channeldb that needs to use the
+	// test clock of the invoice db, which is only created by makeDB.
+	cfg := defaultRegistryConfig()
+	cfg.IdempotencyKeyTTL = ttl
+	ctx := newTestContext(t, &cfg, func(t *testing.T) (invpkg.InvoiceDB,
+		*clock.TestClock) {
+
+		idb, testClock := makeDB(t)
+
+		keyStore, err := channeldb.MakeTestDB(
+			t, channeldb.OptionClock(testClock),
+		)
+		require.NoError(t, err)
+		cfg.IdempotencyKeys = keyStore
+
+		return idb, testClock
+	})
+
+	// newInvoice returns a function that creates a new random invoice and
+	// counts how often it was called.
+	var numCreated int
+	newInvoice := func() (*invpkg.Invoice, lntypes.Hash, error) {
+		numCreated++
+
+		invoice, err := randInvoice(testInvoiceAmount)
+		if err != nil {
+			return nil, lntypes.Hash{}, err
+		}
+
+		return invoice, invoice.Terms.PaymentPreimage.Hash(), nil
+	}
+
+	// The parameters match the invoices created by randInvoice.
+	params := invpkg.IdempotentInvoiceParams{
+		Value:  testInvoiceAmount,
+		Memo:   []byte("memo"),
+		Expiry: 4000,
+	}
+
+	key := invpkg.IdempotencyKey{
+		Scope: []byte("macaroon-1"),
+		Key:   []byte("order-1"),
+	}
+
+	ctxb := context.Background()
+	invoice, hash, err := ctx.registry.AddIdempotentInvoice(
+		ctxb, key, params, newInvoice,
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, numCreated)
+
+	added, err := ctx.registry.LookupInvoice(ctxb, hash)
+	require.NoError(t, err)
+	require.Equal(t, invoice.PaymentRequest, added.PaymentRequest)
+
+	// Retrying with the same key returns the same invoice, without
+	// creating a new one.
+	replayed, replayedHash, err := ctx.registry.AddIdempotentInvoice(
+		ctxb, key, params, newInvoice,
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, numCreated)
+	require.Equal(t, hash, replayedHash)
+	require.Equal(t, added.AddIndex, replayed.AddIndex)
+	require.Equal(t, added.PaymentRequest, replayed.PaymentRequest)
+	require.Equal(
+		t, added.Terms.PaymentPreimage, replayed.Terms.PaymentPreimage,
+	)
+
+	// A retry that asks for a different invoice is refused.
+	mismatched := []invpkg.IdempotentInvoiceParams{
+		{Value: testInvoiceAmount + 1, Memo: params.Memo, Expiry: 4000},
+		{Value: testInvoiceAmount, Memo: []byte("other"), Expiry: 4000},
+		{Value: testInvoiceAmount, Memo: params.Memo, Expiry: 4001},
+	}
+	for _, mismatch := range mismatched {
+		_, _, err = ctx.registry.AddIdempotentInvoice(
+			ctxb, key, mismatch, newInvoice,
+		)
+		require.ErrorIs(t, err, invpkg.ErrIdempotencyKeyMismatch)
+	}
+	require.Equal(t, 1, numCreated)
+
+	// The same key used by another client must not return the invoice of
+	// the first client.
+	otherScope := key
+	otherScope.Scope = []byte("macaroon-2")
+	_, otherHash, err := ctx.registry.AddIdempotentInvoice(
+		ctxb, otherScope, params, newInvoice,
+	)
+	require.NoError(t, err)
+	require.Equal(t, 2, numCreated)
+	require.NotEqual(t, hash, otherHash)
+
+	// Once the key expired, it can be used to create a new invoice.
+	ctx.clock.SetTime(testNow.Add(ttl))
+	_, expiredHash, err := ctx.registry.AddIdempotentInvoice(
+		ctxb, key, params, newInvoice,
+	)
+	require.NoError(t, err)
+	require.Equal(t, 3, numCreated)
+	require.NotEqual(t, hash, expiredHash)
+
+	// Which is then returned for the key again.
+	_, replayedHash, err = ctx.registry.AddIdempotentInvoice(
+		ctxb, key, params, newInvoice,
+	)
+	require.NoError(t, err)
+	require.Equal(t, 3, numCreated)
+	require.Equal(t, expiredHash, replayedHash)
+
+	// Empty and oversized keys are rejected.
+	_, _, err = ctx.registry.AddIdempotentInvoice(
+		ctxb, invpkg.IdempotencyKey{}, params, newInvoice,
+	)
+	require.ErrorIs(t, err, invpkg.ErrInvalidIdempotencyKey)
+
+	_, _, err = ctx.registry.AddIdempotentInvoice(
+		ctxb, invpkg.IdempotencyKey{
+			Key: make([]byte, invpkg.MaxIdempotencyKeySize+1),
+		}, params, newInvoice,
+	)
+	require.ErrorIs(t, err, invpkg.ErrInvalidIdempotencyKey)
+	require.Equal(t, 3, numCreated)
+
+	// A request that fails to add its invoice, because an invoice with
+	// the same payment hash exists already, must not leave its key
+	// pointing at the existing invoice.
+	dupKey := invpkg.IdempotencyKey{
+		Scope: []byte("macaroon-2"),
+		Key:   []byte("order-2"),
+	}
+	duplicate := func() (*invpkg.Invoice, lntypes.Hash, error) {
+		invoice, err := randInvoice(testInvoiceAmount)
+		if err != nil {
+			return nil, lntypes.Hash{}, err
+		}
+
+		return invoice, hash, nil
+	}
+	_, _, err = ctx.registry.AddIdempotentInvoice(
+		ctxb, dupKey, params, duplicate,
+	)
+	require.ErrorIs(t, err, invpkg.ErrDuplicateInvoice)
+
+	// A retry therefore creates a new invoice instead of returning the
+	// invoice of the first client.
+	_, dupHash, err := ctx.registry.AddIdempotentInvoice(
+		ctxb, dupKey, params, newInvoice,
+	)
+	require.NoError(t, err)
+	require.Equal(t, 4, numCreated)
+	require.NotEqual(t, hash, dupHash)
+}
@@ -17,6 +17,11 @@ const (
 	// in the database.
 	MaxMemoSize = 1024
 
+	// MaxBolt11MemoSize is the maximum size of a memo that still fits
+	// within a single BOLT 11 description field, whose length is limited
+	// to 1023 5-bit words.
+	MaxBolt11MemoSize = 639
+
 	// MaxPaymentRequestSize is the max size of a payment request for
 	// this invoice.
 	// TODO(halseth): determine the max length payment request when field
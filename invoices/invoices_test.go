@@ -211,15 +211,10 @@ func TestInvoices(t *testing.T) {
 			name: "AddInvoiceInvalidFeatureDeps",
 			test: testAddInvoiceInvalidFeatureDeps,
 		},
-	}
-
-	makeKeyValueDB := func(t *testing.T) invpkg.InvoiceDB {
-		db, err := channeldb.MakeTestInvoiceDB(
-			t, channeldb.OptionClock(clock.NewTestClock(testNow)),
-		)
-		require.NoError(t, err, "unable to make test db")
-
-		return db
+		{
+			name: "ReplaceInvoice",
+			test: testReplaceInvoice,
+		},
 	}
 
 	// First create a shared Postgres instance so we don't spawn a new
@@ -231,49 +226,67 @@ func TestInvoices(t *testing.T) {
 		pgFixture.TearDown(t)
 	})
 
-	makeSQLDB := func(t *testing.T, sqlite bool) invpkg.InvoiceDB {
-		var db *sqldb.BaseDB
-		if sqlite {
-			sqliteConstructorMu.Lock()
-			db = sqldb.NewTestSqliteDB(t).BaseDB
-			sqliteConstructorMu.Unlock()
-		} else {
-			db = sqldb.NewTestPostgresDB(t, pgFixture).BaseDB
-		}
-
-		executor := sqldb.NewTransactionExecutor(
-			db, func(tx *sql.Tx) invpkg.SQLInvoiceQueries {
-				return db.WithTx(tx)
-			},
-		)
-
-		testClock := clock.NewTestClock(testNow)
-
-		return invpkg.NewSQLStore(executor, testClock)
-	}
-
 	for _, test := range testList {
 		test := test
 		t.Run(test.name+"_KV", func(t *testing.T) {
-			test.test(t, makeKeyValueDB)
+			test.test(t, makeKeyValueInvoiceDB)
 		})
 
 		t.Run(test.name+"_SQLite", func(t *testing.T) {
-			test.test(t,
-				func(t *testing.T) invpkg.InvoiceDB {
-					return makeSQLDB(t, true)
-				})
+			test.test(t, makeSQLiteInvoiceDB)
 		})
 
 		t.Run(test.name+"_Postgres", func(t *testing.T) {
 			test.test(t,
 				func(t *testing.T) invpkg.InvoiceDB {
-					return makeSQLDB(t, false)
+					return makePostgresInvoiceDB(
+						t, pgFixture,
+					)
 				})
 		})
 	}
 }
 
+// makeKeyValueInvoiceDB creates a new key value invoice store for testing.
+func makeKeyValueInvoiceDB(t *testing.T) invpkg.InvoiceDB {
+	db, err := channeldb.MakeTestInvoiceDB(
+		t, channeldb.OptionClock(clock.NewTestClock(testNow)),
+	)
+	require.NoError(t, err, "unable to make test db")
+
+	return db
+}
+
+// makeSQLiteInvoiceDB creates a new SQLite invoice store for testing.
+func makeSQLiteInvoiceDB(t *testing.T) invpkg.InvoiceDB {
+	sqliteConstructorMu.Lock()
+	db := sqldb.NewTestSqliteDB(t).BaseDB
+	sqliteConstructorMu.Unlock()
+
+	return newSQLInvoiceStore(db)
+}
+
+// makePostgresInvoiceDB creates a new Postgres invoice store for testing,
+// using the given fixture.
+func makePostgresInvoiceDB(t *testing.T,
+	pgFixture *sqldb.TestPgFixture) invpkg.InvoiceDB {
+
+	return newSQLInvoiceStore(sqldb.NewTestPostgresDB(t, pgFixture).BaseDB)
+}
+
+// newSQLInvoiceStore creates a SQL invoice store on top of the given database.
+func newSQLInvoiceStore(db *sqldb.BaseDB) invpkg.InvoiceDB {
+	executor := sqldb.NewTransactionExecutor(
+		db, func(tx *sql.Tx) invpkg.SQLInvoiceQueries {
+			return db.WithTx(tx)
+		},
+	)
+
+	testClock := clock.NewTestClock(testNow)
+
+	return invpkg.NewSQLStore(executor, testClock)
+}
+
 // TestInvoiceIsPending tests that pending invoices are those which are either
 // in ContractOpen or in ContractAccepted state.
 func TestInvoiceIsPending(t *testing.T) {
@@ -503,7 +516,7 @@ func testAddDuplicatePayAddr(t *testing.T,
 	// Second insert should fail with duplicate payment addr.
 	inv2Hash := invoice2.Terms.PaymentPreimage.Hash()
 	_, err = db.AddInvoice(ctxb, invoice2, inv2Hash)
-	require.Error(t, err, invpkg.ErrDuplicatePayAddr)
+	require.ErrorIs(t, err, invpkg.ErrDuplicatePayAddr)
 }
 
 // testAddDuplicateKeysendPayAddr asserts that we permit duplicate payment
@@ -615,7 +628,7 @@ func testInvRefEquivocation(t *testing.T,
 		inv2Hash, invoice1.Terms.PaymentAddr,
 	)
 	_, err = db.LookupInvoice(ctxb, ref)
-	require.Error(t, err, invpkg.ErrInvRefEquivocation)
+	require.ErrorIs(t, err, invpkg.ErrInvRefEquivocation)
 
 	// The same error should be returned when updating an equivocating
 	// reference.
@@ -623,7 +636,7 @@ func testInvRefEquivocation(t *testing.T,
 		return nil, nil
 	}
 	_, err = db.UpdateInvoice(ctxb, ref, nil, nop)
-	require.Error(t, err, invpkg.ErrInvRefEquivocation)
+	require.ErrorIs(t, err, invpkg.ErrInvRefEquivocation)
 }
 
 // testInvoiceCancelSingleHtlc tests that a single htlc can be canceled on the
@@ -2725,6 +2738,108 @@ func testDeleteCanceledInvoices(t *testing.T,
 	require.Equal(t, invoices, dbInvoices.Invoices)
 }
 
+// testReplaceInvoice asserts that an open invoice is atomically canceled and
+// replaced by a new invoice, and that settled invoices are refused.
+func testReplaceInvoice(t *testing.T,
+	makeDB func(t *testing.T) invpkg.InvoiceDB) {
+
+	t.Parallel()
+	db := makeDB(t)
+	ctxb := context.Background()
+
+	addInvoice := func() (*invpkg.Invoice, lntypes.Hash) {
+		invoice, err := randInvoice(lnwire.NewMSatFromSatoshis(1000))
+		require.NoError(t, err)
+
+		payHash := invoice.Terms.PaymentPreimage.Hash()
+		_, err = db.AddInvoice(ctxb, invoice, payHash)
+		require.NoError(t, err)
+
+		return invoice, payHash
+	}
+
+	// Add an open invoice and one that we'll settle.
+	_, openHash := addInvoice()
+	_, settledHash := addInvoice()
+
+	amt := lnwire.NewMSatFromSatoshis(1000)
+	_, err := db.UpdateInvoice(
+		ctxb, invpkg.InvoiceRefByHash(settledHash), nil,
+		getUpdateInvoice(0, amt),
+	)
+	require.NoError(t, err)
+
+	// Replacing the open invoice cancels it and adds the new invoice.
+	newInvoice, err := randInvoice(amt)
+	require.NoError(t, err)
+	newHash := newInvoice.Terms.PaymentPreimage.Hash()
+
+	canceled, err := db.ReplaceInvoice(
+		ctxb, invpkg.InvoiceRefByHash(openHash), newInvoice, newHash,
+	)
+	require.NoError(t, err)
+	require.Equal(t, invpkg.ContractCanceled, canceled.State)
+	require.NotZero(t, newInvoice.AddIndex)
+
+	dbInvoice, err := db.LookupInvoice(
+		ctxb, invpkg.InvoiceRefByHash(openHash),
+	)
+	require.NoError(t, err)
+	require.Equal(t, invpkg.ContractCanceled, dbInvoice.State)
+
+	dbInvoice, err = db.LookupInvoice(
+		ctxb, invpkg.InvoiceRefByHash(newHash),
+	)
+	require.NoError(t, err)
+	require.Equal(t, invpkg.ContractOpen, dbInvoice.State)
+	require.Equal(t, newInvoice.AddIndex, dbInvoice.AddIndex)
+
+	// A canceled invoice can't be replaced a second time.
+	otherInvoice, err := randInvoice(amt)
+	require.NoError(t, err)
+	otherHash := otherInvoice.Terms.PaymentPreimage.Hash()
+
+	_, err = db.ReplaceInvoice(
+		ctxb, invpkg.InvoiceRefByHash(openHash), otherInvoice,
+		otherHash,
+	)
+	require.ErrorIs(t, err, invpkg.ErrInvoiceAlreadyCanceled)
+
+	// Settled invoices are refused and remain settled.
+	_, err = db.ReplaceInvoice(
+		ctxb, invpkg.InvoiceRefByHash(settledHash), otherInvoice,
+		otherHash,
+	)
+	require.ErrorIs(t, err, invpkg.ErrInvoiceAlreadySettled)
+
+	dbInvoice, err = db.LookupInvoice(
+		ctxb, invpkg.InvoiceRefByHash(settledHash),
+	)
+	require.NoError(t, err)
+	require.Equal(t, invpkg.ContractSettled, dbInvoice.State)
+
+	// Neither of the refused replacements added the new invoice.
+	_, err = db.LookupInvoice(ctxb, invpkg.InvoiceRefByHash(otherHash))
+	require.ErrorIs(t, err, invpkg.ErrInvoiceNotFound)
+
+	// If adding the new invoice fails, the old invoice must not be
+	// canceled either.
+	_, pendingHash := addInvoice()
+	duplicate, err := randInvoice(amt)
+	require.NoError(t, err)
+
+	_, err = db.ReplaceInvoice(
+		ctxb, invpkg.InvoiceRefByHash(pendingHash), duplicate, newHash,
+	)
+	require.ErrorIs(t, err, invpkg.ErrDuplicateInvoice)
+
+	dbInvoice, err = db.LookupInvoice(
+		ctxb, invpkg.InvoiceRefByHash(pendingHash),
+	)
+	require.NoError(t, err)
+	require.Equal(t, invpkg.ContractOpen, dbInvoice.State)
+}
+
 // testAddInvoiceInvalidFeatureDeps asserts that inserting an invoice with
 // invalid transitive feature dependencies fails with the appropriate error.
 func testAddInvoiceInvalidFeatureDeps(t *testing.T,
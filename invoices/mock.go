@@ -83,3 +83,12 @@ func (m *MockInvoiceDB) DeleteCanceledInvoices(ctx context.Context) error {
 
 	return args.Error(0)
 }
+
+func (m *MockInvoiceDB) ReplaceInvoice(ctx context.Context, ref InvoiceRef,
+	newInvoice *Invoice, newPaymentHash lntypes.Hash) (*Invoice, error) {
+
+	args := m.Called(ctx, ref, newInvoice, newPaymentHash)
+	invoice, _ := args.Get(0).(*Invoice)
+
+	return invoice, args.Error(1)
+}
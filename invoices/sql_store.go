@@ -185,6 +185,45 @@ func (i *SQLStore) AddInvoice(ctx context.Context,
 		invoiceID   int64
 	)
 
+	err := i.db.ExecTx(ctx, &writeTxOpts, func(db SQLInvoiceQueries) error {
+		var err error
+		invoiceID, err = insertInvoice(ctx, db, newInvoice, paymentHash)
+
+		return err
+	}, func() {})
+	if err != nil {
+		mappedSQLErr := sqldb.MapSQLError(err)
+		var uniqueConstraintErr *sqldb.ErrSQLUniqueConstraintViolation
+		if errors.As(mappedSQLErr, &uniqueConstraintErr) {
+			// Add context to unique constraint errors.
+			return 0, ErrDuplicateInvoice
+		}
+
+		return 0, fmt.Errorf("unable to add invoice(%v): %w",
+			paymentHash, err)
+	}
+
+	newInvoice.AddIndex = uint64(invoiceID)
+
+	return newInvoice.AddIndex, nil
+}
+
+// insertInvoice inserts the passed invoice along with its features and the
+// invoice created event using the passed db transaction. The id of the new
+// invoice is returned.
+func insertInvoice(ctx context.Context, db SQLInvoiceQueries,
+	newInvoice *Invoice, paymentHash lntypes.Hash) (int64, error) {
+
+	// Check for duplicate payment hashes and addresses before inserting
+	// the invoice, so that we return the same errors as the KV store.
+	// This also makes sure that a rejected invoice doesn't use up an id
+	// of the Postgres sequence that the add index is taken from, as
+	// sequences aren't rolled back with the transaction.
+	err := checkDuplicateInvoice(ctx, db, newInvoice, paymentHash)
+	if err != nil {
+		return 0, err
+	}
+
 	// Precompute the payment request hash so we can use it in the query.
 	var paymentRequestHash []byte
 	if len(newInvoice.PaymentRequest) > 0 {
@@ -193,88 +232,147 @@ func (i *SQLStore) AddInvoice(ctx context.Context,
 		paymentRequestHash = h.Sum(nil)
 	}
 
-	err := i.db.ExecTx(ctx, &writeTxOpts, func(db SQLInvoiceQueries) error {
-		params := sqlc.InsertInvoiceParams{
-			Hash:       paymentHash[:],
-			Memo:       sqldb.SQLStr(string(newInvoice.Memo)),
-			AmountMsat: int64(newInvoice.Terms.Value),
-			// Note: BOLT12 invoices don't have a final cltv delta.
-			CltvDelta: sqldb.SQLInt32(
-				newInvoice.Terms.FinalCltvDelta,
-			),
-			Expiry: int32(newInvoice.Terms.Expiry),
-			// Note: keysend invoices don't have a payment request.
-			PaymentRequest: sqldb.SQLStr(string(
-				newInvoice.PaymentRequest),
-			),
-			PaymentRequestHash: paymentRequestHash,
-			State:              int16(newInvoice.State),
-			AmountPaidMsat:     int64(newInvoice.AmtPaid),
-			IsAmp:              newInvoice.IsAMP(),
-			IsHodl:             newInvoice.HodlInvoice,
-			IsKeysend:          newInvoice.IsKeysend(),
-			CreatedAt:          newInvoice.CreationDate.UTC(),
-		}
+	params := sqlc.InsertInvoiceParams{
+		Hash:       paymentHash[:],
+		Memo:       sqldb.SQLStr(string(newInvoice.Memo)),
+		AmountMsat: int64(newInvoice.Terms.Value),
+		// Note: BOLT12 invoices don't have a final cltv delta.
+		CltvDelta: sqldb.SQLInt32(newInvoice.Terms.FinalCltvDelta),
+		Expiry:    int32(newInvoice.Terms.Expiry),
+		// Note: keysend invoices don't have a payment request.
+		PaymentRequest: sqldb.SQLStr(
+			string(newInvoice.PaymentRequest),
+		),
+		PaymentRequestHash: paymentRequestHash,
+		State:              int16(newInvoice.State),
+		AmountPaidMsat:     int64(newInvoice.AmtPaid),
+		IsAmp:              newInvoice.IsAMP(),
+		IsHodl:             newInvoice.HodlInvoice,
+		IsKeysend:          newInvoice.IsKeysend(),
+		CreatedAt:          newInvoice.CreationDate.UTC(),
+	}
 
-		// Some invoices may not have a preimage, like in the case of
-		// HODL invoices.
-		if newInvoice.Terms.PaymentPreimage != nil {
-			preimage := *newInvoice.Terms.PaymentPreimage
-			if preimage == UnknownPreimage {
-				return errors.New("cannot use all-zeroes " +
-					"preimage")
-			}
-			params.Preimage = preimage[:]
+	// Some invoices may not have a preimage, like in the case of HODL
+	// invoices.
+	if newInvoice.Terms.PaymentPreimage != nil {
+		preimage := *newInvoice.Terms.PaymentPreimage
+		if preimage == UnknownPreimage {
+			return 0, errors.New("cannot use all-zeroes preimage")
 		}
+		params.Preimage = preimage[:]
+	}
+
+	// Some non MPP payments may have the default (invalid) value.
+	if newInvoice.Terms.PaymentAddr != BlankPayAddr {
+		params.PaymentAddr = newInvoice.Terms.PaymentAddr[:]
+	}
+
+	invoiceID, err := db.InsertInvoice(ctx, params)
+	if err != nil {
+		return 0, fmt.Errorf("unable to insert invoice: %w", err)
+	}
 
-		// Some non MPP payments may have the default (invalid) value.
-		if newInvoice.Terms.PaymentAddr != BlankPayAddr {
-			params.PaymentAddr = newInvoice.Terms.PaymentAddr[:]
+	// TODO(positiveblue): if invocies do not have custom features maybe
+	// just store the "invoice type" and populate the features based on
+	// that.
+	for feature := range newInvoice.Terms.Features.Features() {
+		params := sqlc.InsertInvoiceFeatureParams{
+			InvoiceID: invoiceID,
+			Feature:   int32(feature),
 		}
 
-		var err error
-		invoiceID, err = db.InsertInvoice(ctx, params)
+		err := db.InsertInvoiceFeature(ctx, params)
 		if err != nil {
-			return fmt.Errorf("unable to insert invoice: %w", err)
+			return 0, fmt.Errorf("unable to insert invoice "+
+				"feature(%v): %w", feature, err)
 		}
+	}
 
-		// TODO(positiveblue): if invocies do not have custom features
-		// maybe just store the "invoice type" and populate the features
-		// based on that.
-		for feature := range newInvoice.Terms.Features.Features() {
-			params := sqlc.InsertInvoiceFeatureParams{
-				InvoiceID: invoiceID,
-				Feature:   int32(feature),
-			}
+	// Finally add a new event for this invoice.
+	err = db.OnInvoiceCreated(ctx, sqlc.OnInvoiceCreatedParams{
+		AddedAt:   newInvoice.CreationDate.UTC(),
+		InvoiceID: invoiceID,
+	})
+	if err != nil {
+		return 0, err
+	}
 
-			err := db.InsertInvoiceFeature(ctx, params)
-			if err != nil {
-				return fmt.Errorf("unable to insert invoice "+
-					"feature(%v): %w", feature, err)
-			}
-		}
+	return invoiceID, nil
+}
 
-		// Finally add a new event for this invoice.
-		return db.OnInvoiceCreated(ctx, sqlc.OnInvoiceCreatedParams{
-			AddedAt:   newInvoice.CreationDate.UTC(),
-			InvoiceID: invoiceID,
-		})
-	}, func() {})
-	if err != nil {
-		mappedSQLErr := sqldb.MapSQLError(err)
-		var uniqueConstraintErr *sqldb.ErrSQLUniqueConstraintViolation
-		if errors.As(mappedSQLErr, &uniqueConstraintErr) {
-			// Add context to unique constraint errors.
-			return 0, ErrDuplicateInvoice
-		}
+// checkDuplicateInvoice returns ErrDuplicateInvoice if an invoice with the
+// given payment hash already exists, or ErrDuplicatePayAddr if an invoice with
+// the payment address of the new invoice already exists. The blank payment
+// address of legacy keysend invoices is allowed to be shared.
+func checkDuplicateInvoice(ctx context.Context, db SQLInvoiceQueries,
+	newInvoice *Invoice, paymentHash lntypes.Hash) error {
 
-		return 0, fmt.Errorf("unable to add invoice(%v): %w",
-			paymentHash, err)
+	rows, err := db.GetInvoice(ctx, sqlc.GetInvoiceParams{
+		Hash: paymentHash[:],
+	})
+	switch {
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return err
+
+	case len(rows) > 0:
+		return ErrDuplicateInvoice
 	}
 
-	newInvoice.AddIndex = uint64(invoiceID)
+	if newInvoice.Terms.PaymentAddr == BlankPayAddr {
+		return nil
+	}
 
-	return newInvoice.AddIndex, nil
+	rows, err = db.GetInvoice(ctx, sqlc.GetInvoiceParams{
+		PaymentAddr: newInvoice.Terms.PaymentAddr[:],
+	})
+	switch {
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return err
+
+	case len(rows) > 0:
+		return ErrDuplicatePayAddr
+	}
+
+	return nil
+}
+
+// fetchInvoiceByHashOrAddr looks up the invoice of a reference that has both
+// a payment hash and a payment address, but which matches no invoice that has
+// both. Like the KV store, ErrInvRefEquivocation is returned if they match
+// different invoices, and the invoice is looked up by its payment hash if the
+// payment address is unknown.
+func fetchInvoiceByHashOrAddr(ctx context.Context, db SQLInvoiceQueries,
+	params sqlc.GetInvoiceParams) ([]sqlc.Invoice, error) {
+
+	byHash := params
+	byHash.PaymentAddr = nil
+
+	byAddr := params
+	byAddr.Hash = nil
+
+	rows, err := db.GetInvoice(ctx, byAddr)
+	switch {
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+
+	// The payment address is unknown, so we fall back to the payment
+	// hash.
+	case len(rows) == 0:
+		return db.GetInvoice(ctx, byHash)
+	}
+
+	// The payment address belongs to another invoice than the payment
+	// hash, if that one exists.
+	rows, err = db.GetInvoice(ctx, byHash)
+	switch {
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+
+	case len(rows) > 0:
+		return nil, ErrInvRefEquivocation
+	}
+
+	return nil, nil
 }
 
 // fetchInvoice fetches the common invoice data and the AMP state for the
@@ -312,8 +410,23 @@ func (i *SQLStore) fetchInvoice(ctx context.Context,
 		params.SetID = ref.SetID()[:]
 	}
 
+	// A failed query (for example because the context expired) doesn't
+	// return any rows either, so we need to check the error first in order
+	// not to mistake it for a missing invoice.
 	rows, err := db.GetInvoice(ctx, params)
+	if err == nil && len(rows) == 0 && params.Hash != nil &&
+		params.PaymentAddr != nil {
+
+		rows, err = fetchInvoiceByHashOrAddr(ctx, db, params)
+		if errors.Is(err, ErrInvRefEquivocation) {
+			return nil, err
+		}
+	}
+
 	switch {
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("unable to fetch invoice: %w", err)
+
 	case len(rows) == 0:
 		return nil, ErrInvoiceNotFound
 
@@ -322,9 +435,6 @@ func (i *SQLStore) fetchInvoice(ctx context.Context,
 		// than	one invoice, we'll return an error.
 		return nil, fmt.Errorf("ambiguous invoice ref: %s",
 			ref.String())
-
-	case err != nil:
-		return nil, fmt.Errorf("unable to fetch invoice: %w", err)
 	}
 
 	var (
@@ -581,6 +691,25 @@ func fetchAmpState(ctx context.Context, db SQLInvoiceQueries, invoiceID int64,
 	return ampState, ampHtlcs, nil
 }
 
+// withCtxErr annotates the passed error with the error of the context if the
+// context expired or was canceled while the query was in flight. The database
+// drivers don't always surface the context error itself (postgres for example
+// reports that the statement was canceled due to a user request), so without
+// this callers wouldn't be able to tell a deadline apart from a database
+// failure.
+func withCtxErr(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	ctxErr := ctx.Err()
+	if ctxErr == nil || errors.Is(err, ctxErr) {
+		return err
+	}
+
+	return fmt.Errorf("%w: %v", ctxErr, err)
+}
+
 // LookupInvoice attempts to look up an invoice corresponding the passed in
 // reference. The reference may be a payment hash, a payment address, or a set
 // ID for an AMP sub invoice. If the invoice is found, we'll return the complete
@@ -601,7 +730,7 @@ func (i *SQLStore) LookupInvoice(ctx context.Context,
 		return err
 	}, func() {})
 	if txErr != nil {
-		return Invoice{}, txErr
+		return Invoice{}, withCtxErr(ctx, txErr)
 	}
 
 	return *invoice, nil
@@ -652,7 +781,7 @@ func (i *SQLStore) FetchPendingInvoices(ctx context.Context) (
 	})
 	if err != nil {
 		return nil, fmt.Errorf("unable to fetch pending invoices: %w",
-			err)
+			withCtxErr(ctx, err))
 	}
 
 	return invoices, nil
@@ -769,7 +898,7 @@ func (i *SQLStore) InvoicesSettledSince(ctx context.Context, idx uint64) (
 	})
 	if err != nil {
 		return nil, fmt.Errorf("unable to get invoices settled since "+
-			"index (excluding) %d: %w", idx, err)
+			"index (excluding) %d: %w", idx, withCtxErr(ctx, err))
 	}
 
 	return invoices, nil
@@ -831,7 +960,7 @@ func (i *SQLStore) InvoicesAddedSince(ctx context.Context, idx uint64) (
 
 	if err != nil {
 		return nil, fmt.Errorf("unable to get invoices added since "+
-			"index %d: %w", idx, err)
+			"index %d: %w", idx, withCtxErr(ctx, err))
 	}
 
 	return result, nil
@@ -929,7 +1058,7 @@ func (i *SQLStore) QueryInvoices(ctx context.Context,
 	})
 	if err != nil {
 		return InvoiceSlice{}, fmt.Errorf("unable to query "+
-			"invoices: %w", err)
+			"invoices: %w", withCtxErr(ctx, err))
 	}
 
 	if len(invoices) == 0 {
@@ -1405,6 +1534,74 @@ func (i *SQLStore) DeleteCanceledInvoices(ctx context.Context) error {
 	return nil
 }
 
+// ReplaceInvoice cancels the open invoice identified by the passed reference
+// and adds the new invoice in its place within a single database transaction.
+// Only invoices in the ContractOpen state can be replaced. The canceled
+// invoice is returned.
+//
+// NOTE: A side effect of this function is that it sets AddIndex on newInvoice.
+func (i *SQLStore) ReplaceInvoice(ctx context.Context, ref InvoiceRef,
+	newInvoice *Invoice, newPaymentHash lntypes.Hash) (*Invoice, error) {
+
+	// Make sure the new invoice is valid before trying to store it in our
+	// DB.
+	if err := ValidateInvoice(newInvoice, newPaymentHash); err != nil {
+		return nil, err
+	}
+
+	var (
+		canceledInvoice *Invoice
+		invoiceID       int64
+	)
+
+	txOpt := SQLInvoiceQueriesTxOptions{readOnly: false}
+	txErr := i.db.ExecTx(ctx, &txOpt, func(db SQLInvoiceQueries) error {
+		invoice, err := i.fetchInvoice(ctx, db, ref)
+		if err != nil {
+			return err
+		}
+
+		updateTime := i.clock.Now()
+		updater := &sqlInvoiceUpdater{
+			db:         db,
+			ctx:        ctx,
+			invoice:    invoice,
+			updateTime: updateTime,
+		}
+
+		canceledInvoice, err = UpdateInvoice(
+			ref.PayHash(), invoice, updateTime, CancelOpenInvoice,
+			updater,
+		)
+		if err != nil {
+			return err
+		}
+
+		invoiceID, err = insertInvoice(
+			ctx, db, newInvoice, newPaymentHash,
+		)
+
+		return err
+	}, func() {
+		canceledInvoice = nil
+		invoiceID = 0
+	})
+	if txErr != nil {
+		mappedSQLErr := sqldb.MapSQLError(txErr)
+		var uniqueConstraintErr *sqldb.ErrSQLUniqueConstraintViolation
+		if errors.As(mappedSQLErr, &uniqueConstraintErr) {
+			// Add context to unique constraint errors.
+			return nil, ErrDuplicateInvoice
+		}
+
+		return nil, txErr
+	}
+
+	newInvoice.AddIndex = uint64(invoiceID)
+
+	return canceledInvoice, nil
+}
+
 // fetchInvoiceData fetches additional data for the given invoice. If the
 // invoice is AMP and the setID is not nil, then it will also fetch the AMP
 // state and HTLCs for the given setID, otherwise for all AMP sub invoices of
@@ -1453,13 +1650,6 @@ func fetchInvoiceData(ctx context.Context, db SQLInvoiceQueries,
 
 	if len(htlcs) > 0 {
 		invoice.Htlcs = htlcs
-		var amountPaid lnwire.MilliSatoshi
-		for _, htlc := range htlcs {
-			if htlc.State == HtlcStateSettled {
-				amountPaid += htlc.Amt
-			}
-		}
-		invoice.AmtPaid = amountPaid
 	}
 
 	return hash, invoice, nil
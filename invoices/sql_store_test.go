@@ -0,0 +1,70 @@
+package invoices_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/clock"
+	invpkg "github.com/lightningnetwork/lnd/invoices"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/sqldb"
+	"github.com/lightningnetwork/lnd/sqldb/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+// errStatementCanceled mimics the error postgres returns when a statement is
+// canceled because its context expired, which doesn't wrap the context error.
+var errStatementCanceled = errors.New("canceling statement due to user " +
+	"request")
+
+// slowInvoiceQueries wraps SQLInvoiceQueries and makes invoice lookups block
+// until the context of the query is done.
+type slowInvoiceQueries struct {
+	invpkg.SQLInvoiceQueries
+}
+
+// GetInvoice blocks until the context is done and then fails the same way the
+// postgres driver does.
+func (s *slowInvoiceQueries) GetInvoice(ctx context.Context,
+	_ sqlc.GetInvoiceParams) ([]sqlc.Invoice, error) {
+
+	<-ctx.Done()
+
+	return nil, errStatementCanceled
+}
+
+// TestSQLStoreLookupInvoiceDeadline asserts that a lookup that outlives the
+// deadline of its context returns promptly with an error that can be
+// identified as a deadline, rather than a not found or generic db error.
+func TestSQLStoreLookupInvoiceDeadline(t *testing.T) {
+	t.Parallel()
+
+	sqliteConstructorMu.Lock()
+	db := sqldb.NewTestSqliteDB(t).BaseDB
+	sqliteConstructorMu.Unlock()
+
+	executor := sqldb.NewTransactionExecutor(
+		db, func(tx *sql.Tx) invpkg.SQLInvoiceQueries {
+			return &slowInvoiceQueries{
+				SQLInvoiceQueries: db.WithTx(tx),
+			}
+		},
+	)
+	store := invpkg.NewSQLStore(executor, clock.NewTestClock(testNow))
+
+	const timeout = 100 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := store.LookupInvoice(
+		ctx, invpkg.InvoiceRefByHash(lntypes.Hash{1}),
+	)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorContains(t, err, errStatementCanceled.Error())
+	require.NotErrorIs(t, err, invpkg.ErrInvoiceNotFound)
+	require.Less(t, time.Since(start), 10*timeout)
+}
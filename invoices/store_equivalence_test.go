@@ -0,0 +1,613 @@
+package invoices_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb/models"
+	invpkg "github.com/lightningnetwork/lnd/invoices"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
+	"github.com/lightningnetwork/lnd/sqldb"
+	"github.com/stretchr/testify/require"
+)
+
+// knownStoreErrors are the errors that the invoice stores are expected to
+// return in the same situations. They are compared by identity, any other
+// error is compared by its message.
+var knownStoreErrors = []error{
+	invpkg.ErrInvoiceNotFound,
+	invpkg.ErrDuplicateInvoice,
+	invpkg.ErrDuplicatePayAddr,
+	invpkg.ErrInvRefEquivocation,
+	invpkg.ErrInvoiceAlreadySettled,
+	invpkg.ErrInvoiceAlreadyCanceled,
+	invpkg.ErrInvoiceStillOpen,
+	invpkg.ErrNoInvoicesCreated,
+}
+
+// storeOp is the externally observable outcome of a single operation on an
+// invoice store.
+type storeOp struct {
+	// Name describes the operation.
+	Name string
+
+	// Result is the value returned by the operation, if any.
+	Result any
+
+	// Err is the error returned by the operation, reduced to the known
+	// store error it wraps, or to its message otherwise.
+	Err any
+}
+
+// storeRecorder executes operations on an invoice store and records their
+// outcome, so that the outcomes can be compared across backends.
+type storeRecorder struct {
+	db  invpkg.InvoiceDB
+	ops []storeOp
+}
+
+// record records the outcome of an operation.
+func (r *storeRecorder) record(name string, result any, err error) {
+	var recordedErr any
+	if err != nil {
+		recordedErr = err.Error()
+		for _, knownErr := range knownStoreErrors {
+			if errors.Is(err, knownErr) {
+				recordedErr = knownErr
+				break
+			}
+		}
+	}
+
+	r.ops = append(r.ops, storeOp{
+		Name:   name,
+		Result: result,
+		Err:    recordedErr,
+	})
+}
+
+// add adds the invoice and records its add index.
+func (r *storeRecorder) add(name string, invoice *invpkg.Invoice,
+	hash lntypes.Hash) {
+
+	addIndex, err := r.db.AddInvoice(context.Background(), invoice, hash)
+	r.record(name, addIndex, err)
+}
+
+// lookup looks up an invoice and records it.
+func (r *storeRecorder) lookup(name string, ref invpkg.InvoiceRef) {
+	invoice, err := r.db.LookupInvoice(context.Background(), ref)
+	r.record(name, invoice, err)
+}
+
+// update updates an invoice and records the invoice that is returned.
+func (r *storeRecorder) update(name string, ref invpkg.InvoiceRef,
+	callback invpkg.InvoiceUpdateCallback) {
+
+	invoice, err := r.db.UpdateInvoice(
+		context.Background(), ref, nil, callback,
+	)
+	r.record(name, invoice, err)
+}
+
+// query queries the invoices and records the returned slice.
+func (r *storeRecorder) query(name string, q invpkg.InvoiceQuery) {
+	slice, err := r.db.QueryInvoices(context.Background(), q)
+	r.record(name, slice, err)
+}
+
+// addedSince records the invoices added since the given add index.
+func (r *storeRecorder) addedSince(name string, addIndex uint64) {
+	invoices, err := r.db.InvoicesAddedSince(
+		context.Background(), addIndex,
+	)
+	r.record(name, invoices, err)
+}
+
+// settledSince records the invoices settled since the given settle index.
+func (r *storeRecorder) settledSince(name string, settleIndex uint64) {
+	invoices, err := r.db.InvoicesSettledSince(
+		context.Background(), settleIndex,
+	)
+	r.record(name, invoices, err)
+}
+
+// pending records the pending invoices.
+func (r *storeRecorder) pending(name string) {
+	invoices, err := r.db.FetchPendingInvoices(context.Background())
+	r.record(name, invoices, err)
+}
+
+// deleteCanceled deletes all canceled invoices.
+func (r *storeRecorder) deleteCanceled(name string) {
+	err := r.db.DeleteCanceledInvoices(context.Background())
+	r.record(name, nil, err)
+}
+
+// testInvoice deterministically creates the n-th invoice of a scenario, so
+// that every backend is handed the very same invoices. A nil preimage makes
+// it a hold invoice.
+func testInvoice(n byte, value lnwire.MilliSatoshi, creationDate time.Time,
+	hold bool) (*invpkg.Invoice, lntypes.Hash) {
+
+	var (
+		preimage lntypes.Preimage
+		payAddr  [32]byte
+	)
+	preimage[0], preimage[31] = n, 0xaa
+	payAddr[0], payAddr[31] = n, 0xbb
+
+	invoice := &invpkg.Invoice{
+		Memo:           []byte("memo"),
+		PaymentRequest: []byte{n},
+		CreationDate:   creationDate,
+		Terms: invpkg.ContractTerm{
+			Expiry:          4000,
+			PaymentPreimage: &preimage,
+			PaymentAddr:     payAddr,
+			Value:           value,
+			Features:        emptyFeatures,
+		},
+		HodlInvoice: hold,
+		Htlcs:       map[models.CircuitKey]*invpkg.InvoiceHTLC{},
+		AMPState:    map[invpkg.SetID]invpkg.InvoiceStateAMP{},
+	}
+	if hold {
+		invoice.Terms.PaymentPreimage = nil
+	}
+
+	return invoice, preimage.Hash()
+}
+
+// acceptHtlcs returns an invoice update callback that accepts HTLCs of the
+// given amounts, starting at the given HTLC ID, and moves the invoice to the
+// given state, if any.
+func acceptHtlcs(firstID uint64, newState *invpkg.ContractState,
+	amts ...lnwire.MilliSatoshi) invpkg.InvoiceUpdateCallback {
+
+	return func(invoice *invpkg.Invoice) (*invpkg.InvoiceUpdateDesc,
+		error) {
+
+		htlcs := make(map[models.CircuitKey]*invpkg.HtlcAcceptDesc)
+		for i, amt := range amts {
+			key := models.CircuitKey{HtlcID: firstID + uint64(i)}
+			htlcs[key] = &invpkg.HtlcAcceptDesc{
+				Amt:           amt,
+				CustomRecords: make(record.CustomSet),
+			}
+		}
+
+		update := &invpkg.InvoiceUpdateDesc{
+			UpdateType: invpkg.AddHTLCsUpdate,
+			AddHtlcs:   htlcs,
+		}
+		if newState != nil {
+			update.State = &invpkg.InvoiceStateUpdateDesc{
+				NewState: *newState,
+				Preimage: invoice.Terms.PaymentPreimage,
+			}
+		}
+
+		return update, nil
+	}
+}
+
+// cancelHtlc returns an invoice update callback that cancels the HTLC with
+// the given ID.
+func cancelHtlc(id uint64) invpkg.InvoiceUpdateCallback {
+	return func(invoice *invpkg.Invoice) (*invpkg.InvoiceUpdateDesc,
+		error) {
+
+		return &invpkg.InvoiceUpdateDesc{
+			UpdateType: invpkg.CancelHTLCsUpdate,
+			CancelHtlcs: map[models.CircuitKey]struct{}{
+				{HtlcID: id}: {},
+			},
+		}, nil
+	}
+}
+
+// cancelInvoice is an invoice update callback that cancels the invoice.
+func cancelInvoice(*invpkg.Invoice) (*invpkg.InvoiceUpdateDesc, error) {
+	return &invpkg.InvoiceUpdateDesc{
+		UpdateType: invpkg.CancelInvoiceUpdate,
+		State: &invpkg.InvoiceStateUpdateDesc{
+			NewState: invpkg.ContractCanceled,
+		},
+	}, nil
+}
+
+// settleHold returns an invoice update callback that settles a hold invoice
+// with the given preimage.
+func settleHold(preimage lntypes.Preimage) invpkg.InvoiceUpdateCallback {
+	return func(invoice *invpkg.Invoice) (*invpkg.InvoiceUpdateDesc,
+		error) {
+
+		return &invpkg.InvoiceUpdateDesc{
+			UpdateType: invpkg.SettleHodlInvoiceUpdate,
+			State: &invpkg.InvoiceStateUpdateDesc{
+				NewState: invpkg.ContractSettled,
+				Preimage: &preimage,
+			},
+		}, nil
+	}
+}
+
+var (
+	stateAccepted = invpkg.ContractAccepted
+	stateSettled  = invpkg.ContractSettled
+)
+
+// storeScenarios are the scenarios that every invoice store must behave the
+// same in.
+var storeScenarios = []struct {
+	name string
+	run  func(r *storeRecorder)
+}{
+	{
+		name: "AddAndLookup",
+		run: func(r *storeRecorder) {
+			// All invoices are created at the same time.
+			inv1, hash1 := testInvoice(1, 1000, testNow, false)
+			inv2, hash2 := testInvoice(2, 2000, testNow, false)
+			inv3, hash3 := testInvoice(3, 3000, testNow, false)
+
+			r.addedSince("added since 0 in empty db", 0)
+			r.add("add 1", inv1, hash1)
+			r.add("add 2", inv2, hash2)
+			r.add("add 3", inv3, hash3)
+
+			r.lookup("by hash", invpkg.InvoiceRefByHash(hash2))
+			r.lookup("by addr", invpkg.InvoiceRefByAddr(
+				inv3.Terms.PaymentAddr,
+			))
+			r.lookup("by hash and addr",
+				invpkg.InvoiceRefByHashAndAddr(
+					hash1, inv1.Terms.PaymentAddr,
+				),
+			)
+			r.lookup("equivocating ref",
+				invpkg.InvoiceRefByHashAndAddr(
+					hash1, inv2.Terms.PaymentAddr,
+				),
+			)
+			r.lookup("unknown hash", invpkg.InvoiceRefByHash(
+				lntypes.Hash{},
+			))
+			r.lookup("unknown addr", invpkg.InvoiceRefByHashAndAddr(
+				hash3, [32]byte{1},
+			))
+			r.lookup("unknown hash with addr",
+				invpkg.InvoiceRefByHashAndAddr(
+					lntypes.Hash{}, inv3.Terms.PaymentAddr,
+				),
+			)
+
+			r.addedSince("added since 0", 0)
+			r.addedSince("added since 2", 2)
+			r.addedSince("added since 3", 3)
+			r.addedSince("added since 100", 100)
+		},
+	},
+	{
+		name: "DuplicateAdd",
+		run: func(r *storeRecorder) {
+			inv1, hash1 := testInvoice(1, 1000, testNow, false)
+			r.add("add", inv1, hash1)
+
+			dupHash, _ := testInvoice(1, 1000, testNow, false)
+			r.add("duplicate hash", dupHash, hash1)
+
+			dupAddr, dupAddrHash := testInvoice(
+				2, 1000, testNow, false,
+			)
+			dupAddr.Terms.PaymentAddr = inv1.Terms.PaymentAddr
+			r.add("duplicate addr", dupAddr, dupAddrHash)
+
+			// Keysend invoices all share the blank payment
+			// address.
+			keysend1, keysendHash1 := testInvoice(
+				3, 1000, testNow, false,
+			)
+			keysend1.Terms.PaymentAddr = invpkg.BlankPayAddr
+			r.add("keysend 1", keysend1, keysendHash1)
+
+			keysend2, keysendHash2 := testInvoice(
+				4, 1000, testNow, false,
+			)
+			keysend2.Terms.PaymentAddr = invpkg.BlankPayAddr
+			r.add("keysend 2", keysend2, keysendHash2)
+
+			// Failed adds must not use up an add index.
+			inv5, hash5 := testInvoice(5, 1000, testNow, false)
+			r.add("add after duplicates", inv5, hash5)
+
+			r.addedSince("added since 0", 0)
+		},
+	},
+	{
+		name: "HoldAccept",
+		run: func(r *storeRecorder) {
+			inv, hash := testInvoice(1, 1000, testNow, true)
+			ref := invpkg.InvoiceRefByHash(hash)
+			r.add("add", inv, hash)
+
+			r.update("accept", ref, acceptHtlcs(
+				0, &stateAccepted, 1000,
+			))
+			r.lookup("accepted", ref)
+			r.pending("pending")
+
+			var preimage lntypes.Preimage
+			preimage[0], preimage[31] = 1, 0xaa
+			r.update("settle", ref, settleHold(preimage))
+			r.update("settle again", ref, settleHold(preimage))
+			r.pending("pending after settle")
+			r.settledSince("settled since 0", 0)
+		},
+	},
+	{
+		name: "MPPPartial",
+		run: func(r *storeRecorder) {
+			inv, hash := testInvoice(1, 1000, testNow, false)
+			ref := invpkg.InvoiceRefByHash(hash)
+			r.add("add", inv, hash)
+
+			r.update("accept partial", ref, acceptHtlcs(
+				0, nil, 400,
+			))
+			r.lookup("partially paid", ref)
+
+			r.update("cancel partial", ref, cancelHtlc(0))
+			r.lookup("partial canceled", ref)
+
+			r.update("accept remaining", ref, acceptHtlcs(
+				1, &stateSettled, 600, 400,
+			))
+			r.lookup("settled", ref)
+			r.settledSince("settled since 0", 0)
+		},
+	},
+	{
+		name: "SettleAndCancel",
+		run: func(r *storeRecorder) {
+			inv1, hash1 := testInvoice(1, 1000, testNow, false)
+			inv2, hash2 := testInvoice(2, 2000, testNow, false)
+			ref1 := invpkg.InvoiceRefByHash(hash1)
+			ref2 := invpkg.InvoiceRefByHash(hash2)
+			r.add("add 1", inv1, hash1)
+			r.add("add 2", inv2, hash2)
+
+			r.update("settle", ref1, acceptHtlcs(
+				0, &stateSettled, 1000,
+			))
+			r.update("settle again", ref1, acceptHtlcs(
+				1, &stateSettled, 1000,
+			))
+			r.update("cancel settled", ref1, cancelInvoice)
+
+			r.update("cancel", ref2, cancelInvoice)
+			r.update("cancel again", ref2, cancelInvoice)
+			r.update("settle canceled", ref2, acceptHtlcs(
+				2, &stateSettled, 2000,
+			))
+
+			r.update("update unknown", invpkg.InvoiceRefByHash(
+				lntypes.Hash{},
+			), cancelInvoice)
+
+			r.settledSince("settled since 0", 0)
+			r.settledSince("settled since 1", 1)
+			r.pending("pending")
+
+			r.deleteCanceled("delete canceled")
+			r.lookup("canceled after delete", ref2)
+			r.lookup("settled after delete", ref1)
+		},
+	},
+	{
+		name: "QueryInvoices",
+		run: func(r *storeRecorder) {
+			// Add ten invoices, created in pairs with equal
+			// creation dates, and settle every third one.
+			for i := byte(1); i <= 10; i++ {
+				creationDate := testNow.Add(
+					time.Duration((i-1)/2) * time.Hour,
+				)
+				inv, hash := testInvoice(
+					i, lnwire.MilliSatoshi(i)*1000,
+					creationDate, false,
+				)
+				r.add("add", inv, hash)
+
+				if i%3 != 0 {
+					continue
+				}
+
+				r.update("settle", invpkg.InvoiceRefByHash(
+					hash,
+				), acceptHtlcs(
+					uint64(i), &stateSettled,
+					inv.Terms.Value,
+				))
+			}
+
+			secondPair := testNow.Add(time.Hour).Unix()
+			queries := []struct {
+				name  string
+				query invpkg.InvoiceQuery
+			}{{
+				name: "all",
+				query: invpkg.InvoiceQuery{
+					NumMaxInvoices: math.MaxUint64,
+				},
+			}, {
+				name: "pending only",
+				query: invpkg.InvoiceQuery{
+					NumMaxInvoices: math.MaxUint64,
+					PendingOnly:    true,
+				},
+			}, {
+				name: "first page",
+				query: invpkg.InvoiceQuery{
+					NumMaxInvoices: 3,
+				},
+			}, {
+				name: "page from offset",
+				query: invpkg.InvoiceQuery{
+					IndexOffset:    3,
+					NumMaxInvoices: 3,
+				},
+			}, {
+				name: "last partial page",
+				query: invpkg.InvoiceQuery{
+					IndexOffset:    8,
+					NumMaxInvoices: 3,
+				},
+			}, {
+				name: "offset at end",
+				query: invpkg.InvoiceQuery{
+					IndexOffset:    10,
+					NumMaxInvoices: 3,
+				},
+			}, {
+				name: "offset beyond end",
+				query: invpkg.InvoiceQuery{
+					IndexOffset:    100,
+					NumMaxInvoices: 3,
+				},
+			}, {
+				name: "reversed from end",
+				query: invpkg.InvoiceQuery{
+					NumMaxInvoices: 3,
+					Reversed:       true,
+				},
+			}, {
+				name: "reversed from offset",
+				query: invpkg.InvoiceQuery{
+					IndexOffset:    5,
+					NumMaxInvoices: 3,
+					Reversed:       true,
+				},
+			}, {
+				name: "reversed from first",
+				query: invpkg.InvoiceQuery{
+					IndexOffset:    1,
+					NumMaxInvoices: 3,
+					Reversed:       true,
+				},
+			}, {
+				name: "reversed pending only",
+				query: invpkg.InvoiceQuery{
+					IndexOffset:    8,
+					NumMaxInvoices: 2,
+					PendingOnly:    true,
+					Reversed:       true,
+				},
+			}, {
+				name: "creation date start",
+				query: invpkg.InvoiceQuery{
+					NumMaxInvoices:    math.MaxUint64,
+					CreationDateStart: secondPair,
+				},
+			}, {
+				name: "creation date end",
+				query: invpkg.InvoiceQuery{
+					NumMaxInvoices:  math.MaxUint64,
+					CreationDateEnd: secondPair,
+				},
+			}, {
+				name: "creation date range",
+				query: invpkg.InvoiceQuery{
+					NumMaxInvoices:    math.MaxUint64,
+					CreationDateStart: secondPair,
+					CreationDateEnd:   secondPair,
+				},
+			}, {
+				name: "creation date range paginated",
+				query: invpkg.InvoiceQuery{
+					IndexOffset:       3,
+					NumMaxInvoices:    1,
+					CreationDateStart: secondPair,
+					CreationDateEnd:   secondPair,
+				},
+			}}
+			for _, q := range queries {
+				r.query(q.name, q.query)
+			}
+		},
+	},
+}
+
+// TestInvoiceStoreEquivalence runs the same scenarios against every invoice
+// store backend and asserts that all of them behave exactly like the key value
+// store, including the errors they return. Each backend runs in its own
+// subtest, so the SQLite backend can be tested on its own with
+// -run TestInvoiceStoreEquivalence/SQLite when docker isn't available.
+func TestInvoiceStoreEquivalence(t *testing.T) {
+	t.Parallel()
+
+	t.Run("SQLite", func(t *testing.T) {
+		t.Parallel()
+
+		testInvoiceStoreEquivalence(t, makeSQLiteInvoiceDB)
+	})
+
+	t.Run("Postgres", func(t *testing.T) {
+		t.Parallel()
+
+		// Create a shared Postgres instance so we don't spawn a new
+		// docker container for each scenario.
+		pgFixture := sqldb.NewTestPgFixture(
+			t, sqldb.DefaultPostgresFixtureLifetime,
+		)
+		t.Cleanup(func() {
+			pgFixture.TearDown(t)
+		})
+
+		testInvoiceStoreEquivalence(
+			t, func(t *testing.T) invpkg.InvoiceDB {
+				return makePostgresInvoiceDB(t, pgFixture)
+			},
+		)
+	})
+}
+
+// testInvoiceStoreEquivalence runs every scenario against the invoice store
+// created by makeDB and against the key value store, and asserts that both
+// stores record the same operations.
+func testInvoiceStoreEquivalence(t *testing.T,
+	makeDB func(t *testing.T) invpkg.InvoiceDB) {
+
+	for _, scenario := range storeScenarios {
+		scenario := scenario
+
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+
+			expected := &storeRecorder{
+				db: makeKeyValueInvoiceDB(t),
+			}
+			scenario.run(expected)
+
+			recorder := &storeRecorder{
+				db: makeDB(t),
+			}
+			scenario.run(recorder)
+
+			require.Len(t, recorder.ops, len(expected.ops))
+			for i, op := range recorder.ops {
+				require.Equalf(
+					t, expected.ops[i], op,
+					"differs from kv store in op %d: %v",
+					i, op.Name,
+				)
+			}
+		})
+	}
+}
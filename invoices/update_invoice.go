@@ -91,6 +91,36 @@ func settleHtlcsAmp(invoice *Invoice, circuitKey models.CircuitKey,
 	return updater.UpdateAmpState(setID, newAmpState, circuitKey)
 }
 
+// CancelOpenInvoice is an InvoiceUpdateCallback that moves an open invoice to
+// the canceled state. Unlike a regular cancellation it refuses to touch
+// invoices that have already progressed beyond the open state, which makes it
+// suitable for replacing an invoice that hasn't been paid yet.
+func CancelOpenInvoice(invoice *Invoice) (*InvoiceUpdateDesc, error) {
+	switch invoice.State {
+	case ContractOpen:
+
+	case ContractAccepted:
+		return nil, ErrInvoiceAlreadyAccepted
+
+	case ContractSettled:
+		return nil, ErrInvoiceAlreadySettled
+
+	case ContractCanceled:
+		return nil, ErrInvoiceAlreadyCanceled
+
+	default:
+		return nil, fmt.Errorf("unknown invoice state: %v",
+			invoice.State)
+	}
+
+	return &InvoiceUpdateDesc{
+		UpdateType: CancelInvoiceUpdate,
+		State: &InvoiceStateUpdateDesc{
+			NewState: ContractCanceled,
+		},
+	}, nil
+}
+
 // UpdateInvoice fetches the invoice, obtains the update descriptor from the
 // callback and applies the updates in a single db transaction.
 func UpdateInvoice(hash *lntypes.Hash, invoice *Invoice,
@@ -17,6 +17,14 @@ const (
 	// DefaultRPCGraphCacheDuration is the default interval that the RPC
 	// response to DescribeGraph should be cached for.
 	DefaultRPCGraphCacheDuration = time.Minute
+
+	// DefaultGraphCacheAuditInterval is the default interval at which the
+	// in-memory graph cache is audited against the graph store.
+	DefaultGraphCacheAuditInterval = 24 * time.Hour
+
+	// DefaultGraphCacheAuditSamples is the default number of nodes that
+	// are sampled during each graph cache audit.
+	DefaultGraphCacheAuditSamples = 1000
 )
 
 // Caches holds the configuration for various caches within lnd.
@@ -37,6 +45,14 @@ type Caches struct {
 	// RPCGraphCacheDuration is used to control the flush interval of the
 	// channel graph cache.
 	RPCGraphCacheDuration time.Duration `long:"rpc-graph-cache-duration" description:"The period of time expressed as a duration (1s, 1m, 1h, etc) that the RPC response to DescribeGraph should be cached for."`
+
+	// GraphCacheAuditInterval is the interval at which a random sample of
+	// the in-memory graph cache is compared against the graph store.
+	GraphCacheAuditInterval time.Duration `long:"graph-cache-audit-interval" description:"The period of time expressed as a duration (1s, 1m, 1h, etc) between audits of the in-memory graph cache against the graph store. Diverging cache entries are repaired. Setting the value to zero disables the audit."`
+
+	// GraphCacheAuditSamples is the number of nodes that are sampled
+	// during each graph cache audit.
+	GraphCacheAuditSamples int `long:"graph-cache-audit-samples" description:"The number of random nodes whose graph cache entries are compared against the graph store during each audit."`
 }
 
 // Validate checks the Caches configuration for values that are too small to be
@@ -50,6 +66,14 @@ func (c *Caches) Validate() error {
 		return fmt.Errorf("channel cache size %d is less than min: %d",
 			c.ChannelCacheSize, MinChannelCacheSize)
 	}
+	if c.GraphCacheAuditInterval < 0 {
+		return fmt.Errorf("graph cache audit interval must not be "+
+			"negative, got %v", c.GraphCacheAuditInterval)
+	}
+	if c.GraphCacheAuditInterval > 0 && c.GraphCacheAuditSamples <= 0 {
+		return fmt.Errorf("graph cache audit samples must be "+
+			"positive, got %d", c.GraphCacheAuditSamples)
+	}
 
 	return nil
 }
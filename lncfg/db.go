@@ -77,6 +77,10 @@ type DB struct {
 
 	BatchCommitInterval time.Duration `long:"batch-commit-interval" description:"The maximum duration the channel graph batch schedulers will wait before attempting to commit a batch of pending updates. This can be tradeoff database contenion for commit latency."`
 
+	SyncBatchCommitInterval time.Duration `long:"sync-batch-commit-interval" description:"The maximum duration the channel graph batch schedulers will wait before attempting to commit a batch of updates received during the initial graph sync. Setting this higher than batch-commit-interval results in fewer, larger transactions while syncing. If 0, batch-commit-interval is used."`
+
+	BatchCommitMaxSize int `long:"batch-commit-max-size" description:"The number of pending updates after which the channel graph batch schedulers commit a batch without waiting for the commit interval to elapse. If 0, batches aren't limited in size."`
+
 	Etcd *etcd.Config `group:"etcd" namespace:"etcd" description:"Etcd settings."`
 
 	Bolt *kvdb.BoltConfig `group:"bolt" namespace:"bolt" description:"Bolt settings."`
@@ -163,6 +167,15 @@ func (db *DB) Validate() error {
 			"backend '%v'", db.Backend)
 	}
 
+	if db.SyncBatchCommitInterval < 0 {
+		return fmt.Errorf("sync-batch-commit-interval must not be " +
+			"negative")
+	}
+
+	if db.BatchCommitMaxSize < 0 {
+		return fmt.Errorf("batch-commit-max-size must not be negative")
+	}
+
 	return nil
 }
 
@@ -0,0 +1,115 @@
+package lncfg
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/tor"
+)
+
+// DialTimeouts houses the timeouts used when dialing peers, which depend on
+// the address family of the peer's address. Tor dials legitimately take much
+// longer than clearnet dials, so using a single timeout for both either makes
+// Tor dials flaky or makes clearnet dials to unreachable peers slow to fail.
+type DialTimeouts struct {
+	// Default is the timeout used for any address family that doesn't
+	// have a specific timeout set.
+	Default time.Duration
+
+	// Tor is the timeout used when dialing onion addresses. If zero, the
+	// default timeout is used.
+	Tor time.Duration
+
+	// Clearnet is the timeout used when dialing clearnet addresses. If
+	// zero, the default timeout is used.
+	Clearnet time.Duration
+
+	// ClearnetViaTor is true if clearnet connections are proxied through
+	// Tor, in which case they are subject to the Tor timeout.
+	ClearnetViaTor bool
+}
+
+// tor returns the effective timeout for dialing onion addresses.
+func (d *DialTimeouts) tor() time.Duration {
+	if d.Tor != 0 {
+		return d.Tor
+	}
+
+	return d.Default
+}
+
+// clearnet returns the effective timeout for dialing clearnet addresses.
+func (d *DialTimeouts) clearnet() time.Duration {
+	if d.Clearnet != 0 {
+		return d.Clearnet
+	}
+
+	return d.Default
+}
+
+// Validate checks that the timeouts are sane.
+func (d *DialTimeouts) Validate() error {
+	if d.Tor < 0 {
+		return fmt.Errorf("tor connection timeout (%v) must not be "+
+			"negative", d.Tor)
+	}
+	if d.Clearnet < 0 {
+		return fmt.Errorf("clearnet connection timeout (%v) must not "+
+			"be negative", d.Clearnet)
+	}
+
+	// Dialing over Tor is never faster than dialing directly, so a Tor
+	// timeout below the clearnet one is most likely a mistake. We compare
+	// the effective timeouts, as a timeout that isn't set falls back to
+	// the default one.
+	if d.tor() < d.clearnet() {
+		return fmt.Errorf("tor connection timeout (%v) must not be "+
+			"lower than the clearnet connection timeout (%v)",
+			d.tor(), d.clearnet())
+	}
+
+	return nil
+}
+
+// ForAddr returns the timeout to use when dialing the given address. Any
+// address that isn't an onion address, such as an IP address or a resolved
+// DNS hostname, is dialed over clearnet.
+func (d *DialTimeouts) ForAddr(addr net.Addr) time.Duration {
+	timeout, _ := d.forAddr(addr)
+
+	return timeout
+}
+
+// MinRetryDelay returns the longest timeout that has explicitly been set for
+// the address families of the given addresses, or zero if none has been set.
+// Retrying a connection any sooner than that would mean giving up on a dial
+// before it could have completed.
+func (d *DialTimeouts) MinRetryDelay(addrs []net.Addr) time.Duration {
+	var delay time.Duration
+	for _, addr := range addrs {
+		timeout, explicit := d.forAddr(addr)
+		if explicit && timeout > delay {
+			delay = timeout
+		}
+	}
+
+	return delay
+}
+
+// forAddr returns the timeout to use when dialing the given address, and
+// whether it was set specifically for the address family.
+func (d *DialTimeouts) forAddr(addr net.Addr) (time.Duration, bool) {
+	// The address of a peer is wrapped together with its identity key, so
+	// we'll look at the underlying address.
+	if netAddr, ok := addr.(*lnwire.NetAddress); ok {
+		addr = netAddr.Address
+	}
+
+	if _, ok := addr.(*tor.OnionAddr); ok || d.ClearnetViaTor {
+		return d.tor(), d.Tor != 0
+	}
+
+	return d.clearnet(), d.Clearnet != 0
+}
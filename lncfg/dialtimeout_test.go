@@ -0,0 +1,248 @@
+package lncfg_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightningnetwork/lnd/lncfg"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/tor"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDialTimeoutsForAddr asserts that the dial timeout is selected based on
+// the address family of the address being dialed.
+func TestDialTimeoutsForAddr(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ipv4Addr = &net.TCPAddr{
+			IP:   net.ParseIP("10.0.0.1"),
+			Port: 9735,
+		}
+		ipv6Addr = &net.TCPAddr{
+			IP:   net.ParseIP("::1"),
+			Port: 9735,
+		}
+		onionAddr = &tor.OnionAddr{
+			OnionService: "3g2upl4pq6kufc4m.onion",
+			Port:         9735,
+		}
+	)
+
+	privKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	peerOnionAddr := &lnwire.NetAddress{
+		IdentityKey: privKey.PubKey(),
+		Address:     onionAddr,
+	}
+
+	timeouts := &lncfg.DialTimeouts{
+		Default:  time.Minute,
+		Tor:      30 * time.Second,
+		Clearnet: 3 * time.Second,
+	}
+
+	tests := []struct {
+		name     string
+		timeouts *lncfg.DialTimeouts
+		addr     net.Addr
+		expected time.Duration
+	}{
+		{
+			name:     "ipv4",
+			timeouts: timeouts,
+			addr:     ipv4Addr,
+			expected: 3 * time.Second,
+		},
+		{
+			name:     "ipv6",
+			timeouts: timeouts,
+			addr:     ipv6Addr,
+			expected: 3 * time.Second,
+		},
+		{
+			name:     "onion",
+			timeouts: timeouts,
+			addr:     onionAddr,
+			expected: 30 * time.Second,
+		},
+		{
+			name:     "onion peer address",
+			timeouts: timeouts,
+			addr:     peerOnionAddr,
+			expected: 30 * time.Second,
+		},
+		{
+			name: "clearnet via tor",
+			timeouts: &lncfg.DialTimeouts{
+				Default:        time.Minute,
+				Tor:            30 * time.Second,
+				Clearnet:       3 * time.Second,
+				ClearnetViaTor: true,
+			},
+			addr:     ipv4Addr,
+			expected: 30 * time.Second,
+		},
+		{
+			name: "default clearnet",
+			timeouts: &lncfg.DialTimeouts{
+				Default: time.Minute,
+				Tor:     30 * time.Second,
+			},
+			addr:     ipv4Addr,
+			expected: time.Minute,
+		},
+		{
+			name: "default tor",
+			timeouts: &lncfg.DialTimeouts{
+				Default:  time.Minute,
+				Clearnet: 3 * time.Second,
+			},
+			addr:     onionAddr,
+			expected: time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			timeout := test.timeouts.ForAddr(test.addr)
+			require.Equal(t, test.expected, timeout)
+		})
+	}
+}
+
+// TestDialTimeoutsMinRetryDelay asserts that only the timeouts that were set
+// explicitly for an address family are taken into account for the retry
+// delay.
+func TestDialTimeoutsMinRetryDelay(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ipAddr = &net.TCPAddr{
+			IP:   net.ParseIP("10.0.0.1"),
+			Port: 9735,
+		}
+		onionAddr = &tor.OnionAddr{
+			OnionService: "3g2upl4pq6kufc4m.onion",
+			Port:         9735,
+		}
+	)
+
+	timeouts := &lncfg.DialTimeouts{
+		Default: 2 * time.Minute,
+		Tor:     30 * time.Second,
+	}
+
+	require.Zero(t, timeouts.MinRetryDelay(nil))
+	require.Zero(t, timeouts.MinRetryDelay([]net.Addr{ipAddr}))
+	require.Equal(
+		t, 30*time.Second,
+		timeouts.MinRetryDelay([]net.Addr{ipAddr, onionAddr}),
+	)
+}
+
+// TestValidateDialTimeouts asserts that the effective Tor timeout may not be
+// lower than the effective clearnet timeout.
+func TestValidateDialTimeouts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		timeouts *lncfg.DialTimeouts
+		valid    bool
+	}{
+		{
+			name: "defaults only",
+			timeouts: &lncfg.DialTimeouts{
+				Default: time.Minute,
+			},
+			valid: true,
+		},
+		{
+			name: "tor above clearnet",
+			timeouts: &lncfg.DialTimeouts{
+				Default:  time.Minute,
+				Tor:      30 * time.Second,
+				Clearnet: 3 * time.Second,
+			},
+			valid: true,
+		},
+		{
+			name: "tor equal to clearnet",
+			timeouts: &lncfg.DialTimeouts{
+				Default:  time.Minute,
+				Tor:      3 * time.Second,
+				Clearnet: 3 * time.Second,
+			},
+			valid: true,
+		},
+		{
+			name: "tor below clearnet",
+			timeouts: &lncfg.DialTimeouts{
+				Default:  time.Minute,
+				Tor:      3 * time.Second,
+				Clearnet: 30 * time.Second,
+			},
+		},
+		{
+			name: "tor below default clearnet",
+			timeouts: &lncfg.DialTimeouts{
+				Default: 2 * time.Minute,
+				Tor:     30 * time.Second,
+			},
+		},
+		{
+			name: "tor above default clearnet",
+			timeouts: &lncfg.DialTimeouts{
+				Default: time.Minute,
+				Tor:     2 * time.Minute,
+			},
+			valid: true,
+		},
+		{
+			name: "clearnet above default tor",
+			timeouts: &lncfg.DialTimeouts{
+				Default:  time.Minute,
+				Clearnet: 2 * time.Minute,
+			},
+		},
+		{
+			name: "clearnet below default tor",
+			timeouts: &lncfg.DialTimeouts{
+				Default:  time.Minute,
+				Clearnet: 3 * time.Second,
+			},
+			valid: true,
+		},
+		{
+			name: "negative",
+			timeouts: &lncfg.DialTimeouts{
+				Default:  time.Minute,
+				Clearnet: -time.Second,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := test.timeouts.Validate()
+			if test.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
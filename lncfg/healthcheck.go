@@ -34,6 +34,8 @@ type HealthCheckConfig struct {
 	TorConnection *CheckConfig `group:"torconnection" namespace:"torconnection"`
 
 	RemoteSigner *CheckConfig `group:"remotesigner" namespace:"remotesigner"`
+
+	PeerLatency *CheckConfig `group:"peerlatency" namespace:"peerlatency"`
 }
 
 // Validate checks the values configured for our health checks.
@@ -60,6 +62,10 @@ func (h *HealthCheckConfig) Validate() error {
 		return err
 	}
 
+	if err := h.PeerLatency.validate("peer latency"); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -11,11 +11,18 @@ var (
 	// where both side send 483 payments at the same time to stress test
 	// lnd.
 	MaxMailboxDeliveryTimeout = 2 * time.Minute
+
+	// MaxShutdownDrainTimeout specifies the max allowed time to wait for
+	// in-flight HTLCs to be resolved on shutdown, so that a stuck HTLC
+	// can't delay the shutdown indefinitely.
+	MaxShutdownDrainTimeout = 5 * time.Minute
 )
 
 //nolint:lll
 type Htlcswitch struct {
 	MailboxDeliveryTimeout time.Duration `long:"mailboxdeliverytimeout" description:"The timeout value when delivering HTLCs to a channel link. Setting this value too small will result in local payment failures if large number of payments are sent over a short period."`
+
+	ShutdownDrainTimeout time.Duration `long:"shutdowndraintimeout" description:"The maximum time to wait on shutdown for HTLCs that are forwarded through us to be resolved, after we stopped forwarding new ones. Links with incoming HTLCs that settle at us, such as held HTLCs, don't drain and are abandoned at the timeout. Set to 0 to skip draining."`
 }
 
 // Validate checks the values configured for htlcswitch.
@@ -30,5 +37,15 @@ func (h *Htlcswitch) Validate() error {
 			MaxMailboxDeliveryTimeout)
 	}
 
+	if h.ShutdownDrainTimeout < 0 {
+		return fmt.Errorf("shutdowndraintimeout must not be negative")
+	}
+
+	if h.ShutdownDrainTimeout > MaxShutdownDrainTimeout {
+		return fmt.Errorf("shutdowndraintimeout: %v exceeds "+
+			"maximum: %v", h.ShutdownDrainTimeout,
+			MaxShutdownDrainTimeout)
+	}
+
 	return nil
 }
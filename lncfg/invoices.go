@@ -1,14 +1,27 @@
 package lncfg
 
+import "time"
+
 // DefaultHoldInvoiceExpiryDelta defines the number of blocks before the expiry
 // height of a hold invoice's htlc that lnd will automatically cancel the
 // invoice to prevent the channel from force closing. This value *must* be
 // greater than DefaultIncomingBroadcastDelta to prevent force closes.
 const DefaultHoldInvoiceExpiryDelta = DefaultIncomingBroadcastDelta + 2
 
+// DefaultMaxInvoiceMemoSize is the default maximum size in bytes of the memo
+// of a new invoice. This is the largest description that still fits within a
+// single BOLT 11 tagged field, whose length is limited to 1023 5-bit words.
+const DefaultMaxInvoiceMemoSize = 639
+
 // Invoices holds the configuration options for invoices.
 //
 //nolint:lll
 type Invoices struct {
 	HoldExpiryDelta uint32 `long:"holdexpirydelta" description:"The number of blocks before a hold invoice's htlc expires that the invoice should be canceled to prevent a force close. Force closes will not be prevented if this value is not greater than DefaultIncomingBroadcastDelta."`
+
+	MaxMemoSize uint32 `long:"maxmemosize" description:"The maximum size in bytes of the memo of a new invoice. Memos that are larger are rejected, unless truncatememo is set."`
+
+	TruncateMemo bool `long:"truncatememo" description:"If set, memos of new invoices that exceed maxmemosize are truncated to fit instead of being rejected. Memos are always cut on a UTF-8 character boundary."`
+
+	IdempotencyKeyTTL time.Duration `long:"idempotencykeyttl" description:"The duration for which an AddInvoice call that is retried with the same idempotency_key returns the invoice that was created first, instead of creating a new one."`
 }
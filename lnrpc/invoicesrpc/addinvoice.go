@@ -10,6 +10,7 @@ import (
 	mathRand "math/rand"
 	"sort"
 	"time"
+	"unicode/utf8"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
@@ -45,12 +46,50 @@ const (
 	maxHopHints = 20
 )
 
+var (
+	// ErrMemoInvalidUTF8 is returned when the memo of a new invoice isn't
+	// a valid UTF-8 string.
+	ErrMemoInvalidUTF8 = errors.New("memo is not valid UTF-8")
+
+	// ErrMemoAndDescriptionHash is returned when both a memo and a
+	// description hash are set for a new invoice. Only one of them can be
+	// used as the description of the payment request.
+	ErrMemoAndDescriptionHash = errors.New("memo and description hash " +
+		"both set")
+)
+
+// ErrMemoTooLarge is returned when the memo of a new invoice exceeds the
+// maximum memo size and truncation isn't enabled.
+type ErrMemoTooLarge struct {
+	// Size is the size of the memo in bytes.
+	Size int
+
+	// MaxSize is the maximum allowed size of the memo in bytes.
+	MaxSize int
+}
+
+// Error returns a human-readable description of the error.
+func (e ErrMemoTooLarge) Error() string {
+	return fmt.Sprintf("memo too large: %v bytes (maxsize=%v)", e.Size,
+		e.MaxSize)
+}
+
 // AddInvoiceConfig contains dependencies for invoice creation.
 type AddInvoiceConfig struct {
 	// AddInvoice is called to add the invoice to the registry.
 	AddInvoice func(ctx context.Context, invoice *invoices.Invoice,
 		paymentHash lntypes.Hash) (uint64, error)
 
+	// AddIdempotentInvoice is called to add an invoice that is created with
+	// an idempotency key to the registry. The invoice is only created
+	// using newInvoice if no invoice was created for the key yet,
+	// otherwise the existing invoice is returned if it matches params.
+	AddIdempotentInvoice func(ctx context.Context,
+		key invoices.IdempotencyKey,
+		params invoices.IdempotentInvoiceParams,
+		newInvoice func() (*invoices.Invoice, lntypes.Hash, error)) (
+		*invoices.Invoice, lntypes.Hash, error)
+
 	// IsChannelActive is used to generate valid hop hints.
 	IsChannelActive func(chanID lnwire.ChannelID) bool
 
@@ -84,6 +123,14 @@ type AddInvoiceConfig struct {
 	// GetAlias allows the peer's alias SCID to be retrieved for private
 	// option_scid_alias channels.
 	GetAlias func(lnwire.ChannelID) (lnwire.ShortChannelID, error)
+
+	// MaxMemoSize is the maximum size in bytes of the memo of a new
+	// invoice. If zero, invoices.MaxMemoSize is used.
+	MaxMemoSize int
+
+	// TruncateMemo signals that memos exceeding MaxMemoSize should be
+	// truncated to fit instead of being rejected.
+	TruncateMemo bool
 }
 
 // AddInvoiceData contains the required data to create a new invoice.
@@ -137,6 +184,27 @@ type AddInvoiceData struct {
 	// RouteHints are optional route hints that can each be individually
 	// used to assist in reaching the invoice's destination.
 	RouteHints [][]zpay32.HopHint
+
+	// IdempotencyKey is an optional key that identifies the request to
+	// create the invoice. If set, retrying the request with the same key
+	// returns the invoice that was created first, instead of creating a
+	// new one.
+	IdempotencyKey *invoices.IdempotencyKey
+}
+
+// expiry returns the expiry of the invoice's payment request, which is the
+// default expiry of the invoice type if no expiry is set.
+func (d *AddInvoiceData) expiry() time.Duration {
+	switch {
+	case d.Expiry > 0:
+		return time.Duration(d.Expiry) * time.Second
+
+	case !d.Amp:
+		return DefaultInvoiceExpiry
+
+	default:
+		return DefaultAMPInvoiceExpiry
+	}
 }
 
 // paymentHashAndPreimage returns the payment hash and preimage for this invoice
@@ -232,23 +300,116 @@ func (d *AddInvoiceData) mppPaymentHashAndPreimage() (*lntypes.Preimage,
 	return paymentPreimage, paymentHash, nil
 }
 
+// validateMemo checks that the passed memo is valid UTF-8 and fits within the
+// configured maximum memo size, which defaults to the largest memo that fits
+// in a BOLT 11 payment request. If the memo is too large and truncation is
+// enabled, the memo is cut on a rune boundary and the truncated memo is
+// returned.
+func validateMemo(cfg *AddInvoiceConfig, memo string) (string, error) {
+	if !utf8.ValidString(memo) {
+		return "", ErrMemoInvalidUTF8
+	}
+
+	maxSize := cfg.MaxMemoSize
+	if maxSize == 0 {
+		maxSize = invoices.MaxBolt11MemoSize
+	}
+
+	if len(memo) <= maxSize {
+		return memo, nil
+	}
+
+	if !cfg.TruncateMemo {
+		return "", ErrMemoTooLarge{
+			Size:    len(memo),
+			MaxSize: maxSize,
+		}
+	}
+
+	// Cut the memo at the start of the rune that straddles the limit (if
+	// any), so that we don't end up with a partial multi-byte rune.
+	end := maxSize
+	for end > 0 && !utf8.RuneStart(memo[end]) {
+		end--
+	}
+
+	return memo[:end], nil
+}
+
 // AddInvoice attempts to add a new invoice to the invoice database. Any
 // duplicated invoices are rejected, therefore all invoices *must* have a
-// unique payment preimage.
+// unique payment preimage. If the invoice has an idempotency key and an
+// invoice was already created for the key, that invoice is returned instead.
 func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 	invoice *AddInvoiceData) (*lntypes.Hash, *invoices.Invoice, error) {
 
+	if invoice.IdempotencyKey != nil {
+		if cfg.AddIdempotentInvoice == nil {
+			return nil, nil, invoices.ErrIdempotencyKeysDisabled
+		}
+
+		create := func() (*invoices.Invoice, lntypes.Hash, error) {
+			paymentHash, newInvoice, err := createInvoice(
+				cfg, invoice,
+			)
+			if err != nil {
+				return nil, lntypes.Hash{}, err
+			}
+
+			return newInvoice, *paymentHash, nil
+		}
+
+		// A retry of the request must ask for the same invoice as
+		// the original request.
+		memo, err := validateMemo(cfg, invoice.Memo)
+		if err != nil {
+			return nil, nil, err
+		}
+		params := invoices.IdempotentInvoiceParams{
+			Value:  invoice.Value,
+			Memo:   []byte(memo),
+			Expiry: invoice.expiry(),
+		}
+
+		newInvoice, paymentHash, err := cfg.AddIdempotentInvoice(
+			ctx, *invoice.IdempotencyKey, params, create,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &paymentHash, newInvoice, nil
+	}
+
+	paymentHash, newInvoice, err := createInvoice(cfg, invoice)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// With all sanity checks passed, write the invoice to the database.
+	_, err = cfg.AddInvoice(ctx, newInvoice, *paymentHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return paymentHash, newInvoice, nil
+}
+
+// createInvoice validates the data of a new invoice and creates the invoice,
+// including its signed payment request, without adding it to the database.
+func createInvoice(cfg *AddInvoiceConfig, invoice *AddInvoiceData) (
+	*lntypes.Hash, *invoices.Invoice, error) {
+
 	paymentPreimage, paymentHash, err := invoice.paymentHashAndPreimage()
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// The size of the memo, receipt and description hash attached must not
-	// exceed the maximum values for either of the fields.
-	if len(invoice.Memo) > invoices.MaxMemoSize {
-		return nil, nil, fmt.Errorf("memo too large: %v bytes "+
-			"(maxsize=%v)", len(invoice.Memo),
-			invoices.MaxMemoSize)
+	// The memo and description hash attached must be valid and must not
+	// exceed the maximum sizes for either of the fields.
+	memo, err := validateMemo(cfg, invoice.Memo)
+	if err != nil {
+		return nil, nil, err
 	}
 	if len(invoice.DescriptionHash) > 0 &&
 		len(invoice.DescriptionHash) != 32 {
@@ -256,6 +417,9 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 		return nil, nil, fmt.Errorf("description hash is %v bytes, "+
 			"must be 32", len(invoice.DescriptionHash))
 	}
+	if len(memo) > 0 && len(invoice.DescriptionHash) > 0 {
+		return nil, nil, ErrMemoAndDescriptionHash
+	}
 
 	// We set the max invoice amount to 100k BTC, which itself is several
 	// multiples off the current block reward.
@@ -350,7 +514,7 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 	} else {
 		// Use the memo field as the description. If this is not set
 		// this will just be an empty string.
-		options = append(options, zpay32.Description(invoice.Memo))
+		options = append(options, zpay32.Description(memo))
 	}
 
 	// We'll use our current default CLTV value unless one was specified as
@@ -459,7 +623,7 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 
 	newInvoice := &invoices.Invoice{
 		CreationDate:   creationDate,
-		Memo:           []byte(invoice.Memo),
+		Memo:           []byte(memo),
 		PaymentRequest: []byte(payReqString),
 		Terms: invoices.ContractTerm{
 			FinalCltvDelta:  int32(payReq.MinFinalCLTVExpiry()),
@@ -472,18 +636,12 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 		HodlInvoice: invoice.HodlInvoice,
 	}
 
-	log.Tracef("[addinvoice] adding new invoice %v",
+	log.Tracef("[addinvoice] created new invoice %v",
 		newLogClosure(func() string {
 			return spew.Sdump(newInvoice)
 		}),
 	)
 
-	// With all sanity checks passed, write the invoice to the database.
-	_, err = cfg.AddInvoice(ctx, newInvoice, paymentHash)
-	if err != nil {
-		return nil, nil, err
-	}
-
 	return &paymentHash, newInvoice, nil
 }
 
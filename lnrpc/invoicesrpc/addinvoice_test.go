@@ -1,14 +1,17 @@
 package invoicesrpc
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/channeldb/models"
+	"github.com/lightningnetwork/lnd/invoices"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/zpay32"
 	"github.com/stretchr/testify/mock"
@@ -896,3 +899,114 @@ func TestPopulateHopHints(t *testing.T) {
 		})
 	}
 }
+
+// TestValidateMemo tests that invoice memos are checked for valid UTF-8 and
+// are either rejected or truncated on a rune boundary when they exceed the
+// configured maximum size.
+func TestValidateMemo(t *testing.T) {
+	t.Parallel()
+
+	// "€" is encoded as the three bytes e2 82 ac.
+	const euros = "€€€"
+
+	tests := []struct {
+		name     string
+		memo     string
+		maxSize  int
+		truncate bool
+		expected string
+		err      error
+	}{
+		{
+			name:     "fits",
+			memo:     euros,
+			maxSize:  9,
+			expected: euros,
+		},
+		{
+			name:    "too large",
+			memo:    euros,
+			maxSize: 8,
+			err: ErrMemoTooLarge{
+				Size:    9,
+				MaxSize: 8,
+			},
+		},
+		{
+			name:     "truncate on rune boundary",
+			memo:     euros,
+			maxSize:  6,
+			truncate: true,
+			expected: "€€",
+		},
+		{
+			name:     "truncate rune straddling limit",
+			memo:     euros,
+			maxSize:  8,
+			truncate: true,
+			expected: "€€",
+		},
+		{
+			name:     "truncate within first rune",
+			memo:     euros,
+			maxSize:  2,
+			truncate: true,
+			expected: "",
+		},
+		{
+			name:     "truncate mixed runes",
+			memo:     "a€b",
+			maxSize:  3,
+			truncate: true,
+			expected: "a",
+		},
+		{
+			name: "default max size",
+			memo: strings.Repeat(
+				"a", invoices.MaxBolt11MemoSize+1,
+			),
+			truncate: true,
+			expected: strings.Repeat(
+				"a", invoices.MaxBolt11MemoSize,
+			),
+		},
+		{
+			name:     "invalid utf-8",
+			memo:     "a\xffb",
+			maxSize:  10,
+			truncate: true,
+			err:      ErrMemoInvalidUTF8,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := &AddInvoiceConfig{
+				MaxMemoSize:  test.maxSize,
+				TruncateMemo: test.truncate,
+			}
+
+			memo, err := validateMemo(cfg, test.memo)
+			require.Equal(t, test.err, err)
+			require.Equal(t, test.expected, memo)
+		})
+	}
+}
+
+// TestAddInvoiceMemoAndDescriptionHash tests that an invoice can't be created
+// with both a memo and a description hash.
+func TestAddInvoiceMemoAndDescriptionHash(t *testing.T) {
+	t.Parallel()
+
+	_, _, err := AddInvoice(
+		context.Background(), &AddInvoiceConfig{}, &AddInvoiceData{
+			Memo:            "memo",
+			DescriptionHash: make([]byte, 32),
+		},
+	)
+	require.ErrorIs(t, err, ErrMemoAndDescriptionHash)
+}
@@ -61,4 +61,12 @@ type Config struct {
 	// GetAlias returns the peer's alias SCID if it exists given the
 	// 32-byte ChannelID.
 	GetAlias func(lnwire.ChannelID) (lnwire.ShortChannelID, error)
+
+	// MaxMemoSize is the maximum size in bytes of the memo of a new
+	// invoice.
+	MaxMemoSize int
+
+	// TruncateMemo signals that memos exceeding MaxMemoSize should be
+	// truncated to fit instead of being rejected.
+	TruncateMemo bool
 }
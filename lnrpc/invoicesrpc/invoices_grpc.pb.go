@@ -39,6 +39,14 @@ type InvoicesClient interface {
 	// LookupInvoiceV2 attempts to look up at invoice. An invoice can be refrenced
 	// using either its payment hash, payment address, or set ID.
 	LookupInvoiceV2(ctx context.Context, in *LookupInvoiceMsg, opts ...grpc.CallOption) (*lnrpc.Invoice, error)
+	// ReplaceInvoices cancels each of the given open invoices and creates a
+	// replacement with the same amount, description and remaining expiry, but
+	// with a fresh payment hash and signed with the node key currently in use.
+	// This can be used to invalidate outstanding invoices after the node key was
+	// rotated. Each invoice is replaced atomically, so a failure to replace one of
+	// them leaves it untouched and doesn't affect the others. Invoices that are
+	// already accepted, settled or canceled are refused.
+	ReplaceInvoices(ctx context.Context, in *ReplaceInvoicesRequest, opts ...grpc.CallOption) (*ReplaceInvoicesResponse, error)
 }
 
 type invoicesClient struct {
@@ -117,6 +125,15 @@ func (c *invoicesClient) LookupInvoiceV2(ctx context.Context, in *LookupInvoiceM
 	return out, nil
 }
 
+func (c *invoicesClient) ReplaceInvoices(ctx context.Context, in *ReplaceInvoicesRequest, opts ...grpc.CallOption) (*ReplaceInvoicesResponse, error) {
+	out := new(ReplaceInvoicesResponse)
+	err := c.cc.Invoke(ctx, "/invoicesrpc.Invoices/ReplaceInvoices", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // InvoicesServer is the server API for Invoices service.
 // All implementations must embed UnimplementedInvoicesServer
 // for forward compatibility
@@ -141,6 +158,14 @@ type InvoicesServer interface {
 	// LookupInvoiceV2 attempts to look up at invoice. An invoice can be refrenced
 	// using either its payment hash, payment address, or set ID.
 	LookupInvoiceV2(context.Context, *LookupInvoiceMsg) (*lnrpc.Invoice, error)
+	// ReplaceInvoices cancels each of the given open invoices and creates a
+	// replacement with the same amount, description and remaining expiry, but
+	// with a fresh payment hash and signed with the node key currently in use.
+	// This can be used to invalidate outstanding invoices after the node key was
+	// rotated. Each invoice is replaced atomically, so a failure to replace one of
+	// them leaves it untouched and doesn't affect the others. Invoices that are
+	// already accepted, settled or canceled are refused.
+	ReplaceInvoices(context.Context, *ReplaceInvoicesRequest) (*ReplaceInvoicesResponse, error)
 	mustEmbedUnimplementedInvoicesServer()
 }
 
@@ -163,6 +188,9 @@ func (UnimplementedInvoicesServer) SettleInvoice(context.Context, *SettleInvoice
 func (UnimplementedInvoicesServer) LookupInvoiceV2(context.Context, *LookupInvoiceMsg) (*lnrpc.Invoice, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method LookupInvoiceV2 not implemented")
 }
+func (UnimplementedInvoicesServer) ReplaceInvoices(context.Context, *ReplaceInvoicesRequest) (*ReplaceInvoicesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplaceInvoices not implemented")
+}
 func (UnimplementedInvoicesServer) mustEmbedUnimplementedInvoicesServer() {}
 
 // UnsafeInvoicesServer may be embedded to opt out of forward compatibility for this service.
@@ -269,6 +297,24 @@ func _Invoices_LookupInvoiceV2_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Invoices_ReplaceInvoices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplaceInvoicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InvoicesServer).ReplaceInvoices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/invoicesrpc.Invoices/ReplaceInvoices",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InvoicesServer).ReplaceInvoices(ctx, req.(*ReplaceInvoicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Invoices_ServiceDesc is the grpc.ServiceDesc for Invoices service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -292,6 +338,10 @@ var Invoices_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "LookupInvoiceV2",
 			Handler:    _Invoices_LookupInvoiceV2_Handler,
 		},
+		{
+			MethodName: "ReplaceInvoices",
+			Handler:    _Invoices_ReplaceInvoices_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/lightningnetwork/lnd/invoices"
@@ -62,6 +63,10 @@ var (
 			Entity: "invoices",
 			Action: "write",
 		}},
+		"/invoicesrpc.Invoices/ReplaceInvoices": {{
+			Entity: "invoices",
+			Action: "write",
+		}},
 	}
 
 	// DefaultInvoicesMacFilename is the default name of the invoices
@@ -320,18 +325,7 @@ func (s *Server) CancelInvoice(ctx context.Context,
 func (s *Server) AddHoldInvoice(ctx context.Context,
 	invoice *AddHoldInvoiceRequest) (*AddHoldInvoiceResp, error) {
 
-	addInvoiceCfg := &AddInvoiceConfig{
-		AddInvoice:            s.cfg.InvoiceRegistry.AddInvoice,
-		IsChannelActive:       s.cfg.IsChannelActive,
-		ChainParams:           s.cfg.ChainParams,
-		NodeSigner:            s.cfg.NodeSigner,
-		DefaultCLTVExpiry:     s.cfg.DefaultCLTVExpiry,
-		ChanDB:                s.cfg.ChanStateDB,
-		Graph:                 s.cfg.GraphDB,
-		GenInvoiceFeatures:    s.cfg.GenInvoiceFeatures,
-		GenAmpInvoiceFeatures: s.cfg.GenAmpInvoiceFeatures,
-		GetAlias:              s.cfg.GetAlias,
-	}
+	addInvoiceCfg := s.addInvoiceConfig()
 
 	hash, err := lntypes.MakeHash(invoice.Hash)
 	if err != nil {
@@ -374,6 +368,86 @@ func (s *Server) AddHoldInvoice(ctx context.Context,
 	}, nil
 }
 
+// ReplaceInvoices cancels each of the given open invoices and creates a
+// replacement with a fresh payment hash, signed with the node key currently in
+// use. Each invoice is replaced atomically, and the outcome for each of them is
+// returned in the order in which they were requested.
+func (s *Server) ReplaceInvoices(ctx context.Context,
+	req *ReplaceInvoicesRequest) (*ReplaceInvoicesResponse, error) {
+
+	hashes := make([]lntypes.Hash, 0, len(req.PaymentHashes))
+	for _, rawHash := range req.PaymentHashes {
+		hash, err := lntypes.MakeHash(rawHash)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"unable to parse pay hash: %v", err)
+		}
+
+		hashes = append(hashes, hash)
+	}
+
+	replaceCfg := &ReplaceInvoiceConfig{
+		AddInvoiceConfig: s.addInvoiceConfig(),
+		LookupInvoice:    s.cfg.InvoiceRegistry.LookupInvoice,
+		ReplaceInvoice:   s.cfg.InvoiceRegistry.ReplaceInvoice,
+		Now:              time.Now,
+	}
+	results := ReplaceInvoices(ctx, replaceCfg, hashes)
+
+	resp := &ReplaceInvoicesResponse{
+		Replacements: make([]*InvoiceReplacement, 0, len(results)),
+	}
+	for _, result := range results {
+		replacement := &InvoiceReplacement{
+			OldPaymentHash: result.OldHash[:],
+		}
+
+		if result.Err != nil {
+			log.Warnf("Unable to replace invoice %v: %v",
+				result.OldHash, result.Err)
+
+			replacement.Error = result.Err.Error()
+			resp.Replacements = append(
+				resp.Replacements, replacement,
+			)
+
+			continue
+		}
+
+		log.Infof("Replaced invoice %v with %v", result.OldHash,
+			result.NewHash)
+
+		replacement.NewPaymentHash = result.NewHash[:]
+		replacement.PaymentRequest = string(
+			result.Invoice.PaymentRequest,
+		)
+		replacement.AddIndex = result.Invoice.AddIndex
+		replacement.PaymentAddr = result.Invoice.Terms.PaymentAddr[:]
+
+		resp.Replacements = append(resp.Replacements, replacement)
+	}
+
+	return resp, nil
+}
+
+// addInvoiceConfig returns the config used to create new invoices.
+func (s *Server) addInvoiceConfig() *AddInvoiceConfig {
+	return &AddInvoiceConfig{
+		AddInvoice:            s.cfg.InvoiceRegistry.AddInvoice,
+		IsChannelActive:       s.cfg.IsChannelActive,
+		ChainParams:           s.cfg.ChainParams,
+		NodeSigner:            s.cfg.NodeSigner,
+		DefaultCLTVExpiry:     s.cfg.DefaultCLTVExpiry,
+		ChanDB:                s.cfg.ChanStateDB,
+		Graph:                 s.cfg.GraphDB,
+		GenInvoiceFeatures:    s.cfg.GenInvoiceFeatures,
+		GenAmpInvoiceFeatures: s.cfg.GenAmpInvoiceFeatures,
+		GetAlias:              s.cfg.GetAlias,
+		MaxMemoSize:           s.cfg.MaxMemoSize,
+		TruncateMemo:          s.cfg.TruncateMemo,
+	}
+}
+
 // LookupInvoiceV2 attempts to look up at invoice. An invoice can be referenced
 // using either its payment hash, payment address, or set ID.
 func (s *Server) LookupInvoiceV2(ctx context.Context,
@@ -437,6 +511,8 @@ func (s *Server) LookupInvoiceV2(ctx context.Context,
 	switch {
 	case errors.Is(err, invoices.ErrInvoiceNotFound):
 		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return nil, status.Error(codes.DeadlineExceeded, err.Error())
 	case err != nil:
 		return nil, err
 	}
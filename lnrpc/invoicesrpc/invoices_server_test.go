@@ -0,0 +1,93 @@
+//go:build !js && !(windows && (arm || 386)) && !(linux && (ppc64 || mips || mipsle || mips64)) && !(netbsd || openbsd)
+
+package invoicesrpc
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/clock"
+	"github.com/lightningnetwork/lnd/invoices"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/sqldb"
+	"github.com/lightningnetwork/lnd/sqldb/sqlc"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// pgSleepQueries wraps SQLInvoiceQueries and makes invoice lookups run a
+// long pg_sleep query first, so that the lookup outlives any reasonable
+// deadline.
+type pgSleepQueries struct {
+	invoices.SQLInvoiceQueries
+
+	tx *sql.Tx
+}
+
+// GetInvoice sleeps within the database before looking up the invoice.
+func (q *pgSleepQueries) GetInvoice(ctx context.Context,
+	arg sqlc.GetInvoiceParams) ([]sqlc.Invoice, error) {
+
+	_, err := q.tx.ExecContext(ctx, "SELECT pg_sleep(30)")
+	if err != nil {
+		return nil, err
+	}
+
+	return q.SQLInvoiceQueries.GetInvoice(ctx, arg)
+}
+
+// TestLookupInvoiceV2Deadline asserts that an invoice lookup against a
+// postgres backend whose query outlives the deadline of the RPC returns at the
+// deadline with a DeadlineExceeded status.
+func TestLookupInvoiceV2Deadline(t *testing.T) {
+	t.Parallel()
+
+	pgFixture := sqldb.NewTestPgFixture(
+		t, sqldb.DefaultPostgresFixtureLifetime,
+	)
+	t.Cleanup(func() {
+		pgFixture.TearDown(t)
+	})
+
+	db := sqldb.NewTestPostgresDB(t, pgFixture).BaseDB
+	executor := sqldb.NewTransactionExecutor(
+		db, func(tx *sql.Tx) invoices.SQLInvoiceQueries {
+			return &pgSleepQueries{
+				SQLInvoiceQueries: db.WithTx(tx),
+				tx:                tx,
+			}
+		},
+	)
+	store := invoices.NewSQLStore(executor, clock.NewDefaultClock())
+
+	server := &Server{
+		cfg: &Config{
+			InvoiceRegistry: invoices.NewRegistry(
+				store, nil, &invoices.RegistryConfig{},
+			),
+		},
+	}
+
+	const timeout = 500 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	hash := lntypes.Hash{1}
+	_, err := server.LookupInvoiceV2(ctx, &LookupInvoiceMsg{
+		InvoiceRef: &LookupInvoiceMsg_PaymentHash{
+			PaymentHash: hash[:],
+		},
+	})
+	elapsed := time.Since(start)
+
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err), err)
+
+	// The lookup must return once the deadline is reached, rather than
+	// once the sleep in the database is over.
+	require.GreaterOrEqual(t, elapsed, timeout)
+	require.Less(t, elapsed, 10*timeout)
+}
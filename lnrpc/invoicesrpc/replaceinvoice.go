@@ -0,0 +1,191 @@
+package invoicesrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lightningnetwork/lnd/invoices"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+var (
+	// ErrReplaceKeysendInvoice is returned when attempting to replace an
+	// invoice that doesn't have a payment request, such as a keysend
+	// invoice.
+	ErrReplaceKeysendInvoice = errors.New("cannot replace invoice " +
+		"without payment request")
+
+	// ErrReplaceHodlInvoice is returned when attempting to replace a hodl
+	// invoice. We don't know the preimage of those, so we can't create a
+	// replacement with a fresh payment hash.
+	ErrReplaceHodlInvoice = errors.New("cannot replace hodl invoice")
+
+	// ErrReplaceExpiredInvoice is returned when attempting to replace an
+	// invoice that has already expired.
+	ErrReplaceExpiredInvoice = errors.New("cannot replace expired invoice")
+)
+
+// ReplaceInvoiceConfig contains the dependencies for replacing invoices.
+type ReplaceInvoiceConfig struct {
+	// AddInvoiceConfig is used to create the replacement invoices, which
+	// are signed with the node key currently held by the NodeSigner.
+	*AddInvoiceConfig
+
+	// LookupInvoice looks up the invoice with the given payment hash.
+	LookupInvoice func(ctx context.Context,
+		paymentHash lntypes.Hash) (invoices.Invoice, error)
+
+	// ReplaceInvoice atomically cancels the invoice with the old payment
+	// hash and adds the new invoice in its place.
+	ReplaceInvoice func(ctx context.Context, oldPaymentHash lntypes.Hash,
+		newInvoice *invoices.Invoice,
+		newPaymentHash lntypes.Hash) (uint64, error)
+
+	// Now returns the current time. It is used to compute the expiry that
+	// is left on the replaced invoices.
+	Now func() time.Time
+}
+
+// ReplacedInvoice is the outcome of replacing a single invoice.
+type ReplacedInvoice struct {
+	// OldHash is the payment hash of the invoice that was replaced.
+	OldHash lntypes.Hash
+
+	// NewHash is the payment hash of the replacement invoice. It is only
+	// set if the replacement succeeded.
+	NewHash lntypes.Hash
+
+	// Invoice is the replacement invoice. It is only set if the
+	// replacement succeeded.
+	Invoice *invoices.Invoice
+
+	// Err is set if the invoice couldn't be replaced.
+	Err error
+}
+
+// ReplaceInvoices cancels each of the open invoices identified by the passed
+// payment hashes and creates a replacement with the same amount, description
+// and remaining expiry, but with a fresh payment hash and signed with the
+// node key currently in use. Each invoice is replaced atomically, so a failure
+// to replace one of them leaves it untouched and doesn't affect the others.
+// The outcome for each invoice is returned in the order of the passed hashes.
+func ReplaceInvoices(ctx context.Context, cfg *ReplaceInvoiceConfig,
+	hashes []lntypes.Hash) []ReplacedInvoice {
+
+	results := make([]ReplacedInvoice, 0, len(hashes))
+	for _, hash := range hashes {
+		result := ReplacedInvoice{
+			OldHash: hash,
+		}
+
+		newHash, newInvoice, err := replaceInvoice(ctx, cfg, hash)
+		if err != nil {
+			result.Err = err
+		} else {
+			result.NewHash = *newHash
+			result.Invoice = newInvoice
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// replaceInvoice replaces the single invoice identified by the passed payment
+// hash.
+func replaceInvoice(ctx context.Context, cfg *ReplaceInvoiceConfig,
+	oldHash lntypes.Hash) (*lntypes.Hash, *invoices.Invoice, error) {
+
+	oldInvoice, err := cfg.LookupInvoice(ctx, oldHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := replacementInvoiceData(
+		&oldInvoice, cfg.AddInvoiceConfig, cfg.Now(),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// We re-use the regular invoice creation logic, but instead of adding
+	// the new invoice we atomically swap it in for the old one.
+	addCfg := *cfg.AddInvoiceConfig
+	addCfg.AddInvoice = func(ctx context.Context,
+		newInvoice *invoices.Invoice,
+		newHash lntypes.Hash) (uint64, error) {
+
+		return cfg.ReplaceInvoice(ctx, oldHash, newInvoice, newHash)
+	}
+
+	return AddInvoice(ctx, &addCfg, data)
+}
+
+// replacementInvoiceData derives the parameters of a replacement for the
+// passed invoice.
+func replacementInvoiceData(invoice *invoices.Invoice, cfg *AddInvoiceConfig,
+	now time.Time) (*AddInvoiceData, error) {
+
+	switch {
+	case invoice.State == invoices.ContractSettled:
+		return nil, invoices.ErrInvoiceAlreadySettled
+
+	case invoice.State == invoices.ContractCanceled:
+		return nil, invoices.ErrInvoiceAlreadyCanceled
+
+	case invoice.State == invoices.ContractAccepted:
+		return nil, invoices.ErrInvoiceAlreadyAccepted
+
+	case invoice.HodlInvoice:
+		return nil, ErrReplaceHodlInvoice
+
+	case len(invoice.PaymentRequest) == 0:
+		return nil, ErrReplaceKeysendInvoice
+	}
+
+	payReq, err := zpay32.Decode(
+		string(invoice.PaymentRequest), cfg.ChainParams,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode payment request: %w",
+			err)
+	}
+
+	// The replacement should expire at the same time as the original
+	// invoice.
+	remaining := invoice.CreationDate.Add(invoice.Terms.Expiry).Sub(now)
+	if remaining < time.Second {
+		return nil, ErrReplaceExpiredInvoice
+	}
+
+	data := &AddInvoiceData{
+		Memo:       string(invoice.Memo),
+		Value:      invoice.Terms.Value,
+		Expiry:     int64(remaining / time.Second),
+		CltvExpiry: uint64(invoice.Terms.FinalCltvDelta),
+		Amp:        invoice.IsAMP(),
+
+		// If the original invoice carried route hints, we'll select a
+		// fresh set for the replacement.
+		Private: len(payReq.RouteHints) > 0,
+	}
+
+	// Invoices that were created before a memo and description hash were
+	// mutually exclusive may have both. The replacement can only carry
+	// one of them, so we keep the description hash that the payer commits
+	// to.
+	if payReq.DescriptionHash != nil {
+		data.Memo = ""
+		data.DescriptionHash = payReq.DescriptionHash[:]
+	}
+
+	if payReq.FallbackAddr != nil {
+		data.FallbackAddr = payReq.FallbackAddr.String()
+	}
+
+	return data, nil
+}
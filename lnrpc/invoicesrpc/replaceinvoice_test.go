@@ -0,0 +1,207 @@
+package invoicesrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightningnetwork/lnd/invoices"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/netann"
+	"github.com/lightningnetwork/lnd/zpay32"
+	"github.com/stretchr/testify/require"
+)
+
+// replaceTestStore is a minimal in-memory invoice store used to exercise
+// ReplaceInvoices.
+type replaceTestStore struct {
+	invoices map[lntypes.Hash]*invoices.Invoice
+
+	// failReplace is the payment hash for which the replacement fails.
+	failReplace lntypes.Hash
+}
+
+func (s *replaceTestStore) lookup(_ context.Context,
+	hash lntypes.Hash) (invoices.Invoice, error) {
+
+	invoice, ok := s.invoices[hash]
+	if !ok {
+		return invoices.Invoice{}, invoices.ErrInvoiceNotFound
+	}
+
+	return *invoice, nil
+}
+
+func (s *replaceTestStore) replace(_ context.Context, oldHash lntypes.Hash,
+	newInvoice *invoices.Invoice, newHash lntypes.Hash) (uint64, error) {
+
+	// Mimic the atomicity of the real stores by only touching the old
+	// invoice once we know the replacement will succeed.
+	if oldHash == s.failReplace {
+		return 0, errors.New("replacement failed")
+	}
+
+	s.invoices[oldHash].State = invoices.ContractCanceled
+	s.invoices[newHash] = newInvoice
+
+	return uint64(len(s.invoices)), nil
+}
+
+// TestReplaceInvoices asserts that open invoices are replaced by equivalent
+// invoices with fresh payment hashes signed by the current node key, while
+// invoices that can't be replaced are left untouched.
+func TestReplaceInvoices(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx    = context.Background()
+		now    = time.Unix(1_700_000_000, 0)
+		params = &chaincfg.RegressionNetParams
+	)
+
+	// The old invoices were signed with a key we no longer use.
+	oldKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	newKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	features := lnwire.NewFeatureVector(lnwire.NewRawFeatureVector(
+		lnwire.TLVOnionPayloadRequired, lnwire.PaymentAddrRequired,
+	), lnwire.Features)
+
+	newAddCfg := func(key *btcec.PrivateKey) *AddInvoiceConfig {
+		signer := keychain.NewPrivKeyMessageSigner(
+			key, keychain.KeyLocator{},
+		)
+
+		return &AddInvoiceConfig{
+			ChainParams:       params,
+			NodeSigner:        netann.NewNodeSigner(signer),
+			DefaultCLTVExpiry: 80,
+			GenInvoiceFeatures: func() *lnwire.FeatureVector {
+				return features
+			},
+		}
+	}
+
+	store := &replaceTestStore{
+		invoices: make(map[lntypes.Hash]*invoices.Invoice),
+	}
+
+	// Create the invoices using the old key.
+	oldCfg := newAddCfg(oldKey)
+	oldCfg.AddInvoice = func(_ context.Context, invoice *invoices.Invoice,
+		hash lntypes.Hash) (uint64, error) {
+
+		store.invoices[hash] = invoice
+		return uint64(len(store.invoices)), nil
+	}
+
+	addInvoice := func(memo string, value lnwire.MilliSatoshi,
+		hodl bool) lntypes.Hash {
+
+		data := &AddInvoiceData{
+			Memo:   memo,
+			Value:  value,
+			Expiry: 3600,
+		}
+		if hodl {
+			hash := lntypes.Hash{byte(len(store.invoices) + 1)}
+			data.Hash = &hash
+		}
+
+		hash, invoice, err := AddInvoice(ctx, oldCfg, data)
+		require.NoError(t, err)
+
+		invoice.CreationDate = now.Add(-10 * time.Minute)
+		invoice.HodlInvoice = hodl
+
+		return *hash
+	}
+
+	openHash := addInvoice("open", 1000, false)
+	settledHash := addInvoice("settled", 2000, false)
+	hodlHash := addInvoice("hodl", 3000, true)
+	failingHash := addInvoice("failing", 4000, false)
+	unknownHash := lntypes.Hash{0xff}
+
+	store.invoices[settledHash].State = invoices.ContractSettled
+	store.failReplace = failingHash
+
+	cfg := &ReplaceInvoiceConfig{
+		AddInvoiceConfig: newAddCfg(newKey),
+		LookupInvoice:    store.lookup,
+		ReplaceInvoice:   store.replace,
+		Now: func() time.Time {
+			return now
+		},
+	}
+
+	results := ReplaceInvoices(ctx, cfg, []lntypes.Hash{
+		openHash, settledHash, hodlHash, failingHash, unknownHash,
+	})
+	require.Len(t, results, 5)
+
+	// The open invoice is replaced by an equivalent one that's signed with
+	// the new key.
+	replaced := results[0]
+	require.NoError(t, replaced.Err)
+	require.Equal(t, openHash, replaced.OldHash)
+	require.NotEqual(t, openHash, replaced.NewHash)
+	require.Equal(t, replaced.Invoice, store.invoices[replaced.NewHash])
+	require.Equal(
+		t, invoices.ContractCanceled, store.invoices[openHash].State,
+	)
+	require.Equal(t, []byte("open"), replaced.Invoice.Memo)
+	require.EqualValues(t, 1000, replaced.Invoice.Terms.Value)
+
+	// The replacement expires at the same time as the original invoice.
+	require.Equal(t, 50*time.Minute, replaced.Invoice.Terms.Expiry)
+
+	payReq, err := zpay32.Decode(
+		string(replaced.Invoice.PaymentRequest), params,
+	)
+	require.NoError(t, err)
+	require.True(t, payReq.Destination.IsEqual(newKey.PubKey()))
+	require.Equal(t, replaced.NewHash[:], payReq.PaymentHash[:])
+
+	// The settled invoice is refused and left untouched.
+	require.ErrorIs(t, results[1].Err, invoices.ErrInvoiceAlreadySettled)
+	require.Equal(
+		t, invoices.ContractSettled, store.invoices[settledHash].State,
+	)
+
+	// We can't replace hodl invoices, as we don't know their preimage.
+	require.ErrorIs(t, results[2].Err, ErrReplaceHodlInvoice)
+	require.Equal(
+		t, invoices.ContractOpen, store.invoices[hodlHash].State,
+	)
+
+	// A failed replacement doesn't affect the other invoices and leaves
+	// the old invoice open.
+	require.ErrorContains(t, results[3].Err, "replacement failed")
+	require.Equal(
+		t, invoices.ContractOpen, store.invoices[failingHash].State,
+	)
+
+	require.ErrorIs(t, results[4].Err, invoices.ErrInvoiceNotFound)
+
+	// Only the open invoice resulted in a new invoice being added.
+	require.Len(t, store.invoices, 5)
+
+	// Finally, an invoice can't be replaced once it has expired.
+	cfg.Now = func() time.Time {
+		return now.Add(time.Hour)
+	}
+	results = ReplaceInvoices(ctx, cfg, []lntypes.Hash{hodlHash})
+	require.ErrorIs(t, results[0].Err, ErrReplaceHodlInvoice)
+
+	store.invoices[hodlHash].HodlInvoice = false
+	results = ReplaceInvoices(ctx, cfg, []lntypes.Hash{hodlHash})
+	require.ErrorIs(t, results[0].Err, ErrReplaceExpiredInvoice)
+}
@@ -97,6 +97,18 @@ type LightningClient interface {
 	// the client in which any events relevant to the state of peers are sent
 	// over. Events include peers going online and offline.
 	SubscribePeerEvents(ctx context.Context, in *PeerEventSubscription, opts ...grpc.CallOption) (Lightning_SubscribePeerEventsClient, error)
+	// SetPeerAddrOverride sets the addresses we connect to a peer at, taking
+	// precedence over the addresses the peer announced. This is useful if the
+	// peer's announcement is stale, for example after a coordinated migration.
+	// The override replaces any existing one, is never announced to the network
+	// and can optionally expire. It takes effect the next time we connect to the
+	// peer.
+	SetPeerAddrOverride(ctx context.Context, in *SetPeerAddrOverrideRequest, opts ...grpc.CallOption) (*SetPeerAddrOverrideResponse, error)
+	// ListPeerAddrOverrides returns all address overrides that haven't expired.
+	ListPeerAddrOverrides(ctx context.Context, in *ListPeerAddrOverridesRequest, opts ...grpc.CallOption) (*ListPeerAddrOverridesResponse, error)
+	// DeletePeerAddrOverride removes the address override of a peer, so that we
+	// connect to it at its announced addresses again.
+	DeletePeerAddrOverride(ctx context.Context, in *DeletePeerAddrOverrideRequest, opts ...grpc.CallOption) (*DeletePeerAddrOverrideResponse, error)
 	// lncli: `getinfo`
 	// GetInfo returns general information concerning the lightning node including
 	// it's identity pubkey, alias, the chains it is connected to, and information
@@ -107,6 +119,12 @@ type LightningClient interface {
 	// and its subsystems. This includes the full configuration and the latest log
 	// entries from the log file.
 	GetDebugInfo(ctx context.Context, in *GetDebugInfoRequest, opts ...grpc.CallOption) (*GetDebugInfoResponse, error)
+	// lncli: `auditgraphcache`
+	// AuditGraphCache runs a consistency audit of the in-memory graph cache
+	// against the persistent graph store right away, instead of waiting for the
+	// next periodic audit. Nodes whose cache entries diverge from the store are
+	// re-primed from the store.
+	AuditGraphCache(ctx context.Context, in *AuditGraphCacheRequest, opts ...grpc.CallOption) (*AuditGraphCacheResponse, error)
 	// * lncli: `getrecoveryinfo`
 	// GetRecoveryInfo returns information concerning the recovery mode including
 	// whether it's in a recovery mode, whether the recovery is finished, and the
@@ -607,6 +625,33 @@ func (x *lightningSubscribePeerEventsClient) Recv() (*PeerEvent, error) {
 	return m, nil
 }
 
+func (c *lightningClient) SetPeerAddrOverride(ctx context.Context, in *SetPeerAddrOverrideRequest, opts ...grpc.CallOption) (*SetPeerAddrOverrideResponse, error) {
+	out := new(SetPeerAddrOverrideResponse)
+	err := c.cc.Invoke(ctx, "/lnrpc.Lightning/SetPeerAddrOverride", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lightningClient) ListPeerAddrOverrides(ctx context.Context, in *ListPeerAddrOverridesRequest, opts ...grpc.CallOption) (*ListPeerAddrOverridesResponse, error) {
+	out := new(ListPeerAddrOverridesResponse)
+	err := c.cc.Invoke(ctx, "/lnrpc.Lightning/ListPeerAddrOverrides", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lightningClient) DeletePeerAddrOverride(ctx context.Context, in *DeletePeerAddrOverrideRequest, opts ...grpc.CallOption) (*DeletePeerAddrOverrideResponse, error) {
+	out := new(DeletePeerAddrOverrideResponse)
+	err := c.cc.Invoke(ctx, "/lnrpc.Lightning/DeletePeerAddrOverride", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *lightningClient) GetInfo(ctx context.Context, in *GetInfoRequest, opts ...grpc.CallOption) (*GetInfoResponse, error) {
 	out := new(GetInfoResponse)
 	err := c.cc.Invoke(ctx, "/lnrpc.Lightning/GetInfo", in, out, opts...)
@@ -625,6 +670,15 @@ func (c *lightningClient) GetDebugInfo(ctx context.Context, in *GetDebugInfoRequ
 	return out, nil
 }
 
+func (c *lightningClient) AuditGraphCache(ctx context.Context, in *AuditGraphCacheRequest, opts ...grpc.CallOption) (*AuditGraphCacheResponse, error) {
+	out := new(AuditGraphCacheResponse)
+	err := c.cc.Invoke(ctx, "/lnrpc.Lightning/AuditGraphCache", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *lightningClient) GetRecoveryInfo(ctx context.Context, in *GetRecoveryInfoRequest, opts ...grpc.CallOption) (*GetRecoveryInfoResponse, error) {
 	out := new(GetRecoveryInfoResponse)
 	err := c.cc.Invoke(ctx, "/lnrpc.Lightning/GetRecoveryInfo", in, out, opts...)
@@ -1418,6 +1472,18 @@ type LightningServer interface {
 	// the client in which any events relevant to the state of peers are sent
 	// over. Events include peers going online and offline.
 	SubscribePeerEvents(*PeerEventSubscription, Lightning_SubscribePeerEventsServer) error
+	// SetPeerAddrOverride sets the addresses we connect to a peer at, taking
+	// precedence over the addresses the peer announced. This is useful if the
+	// peer's announcement is stale, for example after a coordinated migration.
+	// The override replaces any existing one, is never announced to the network
+	// and can optionally expire. It takes effect the next time we connect to the
+	// peer.
+	SetPeerAddrOverride(context.Context, *SetPeerAddrOverrideRequest) (*SetPeerAddrOverrideResponse, error)
+	// ListPeerAddrOverrides returns all address overrides that haven't expired.
+	ListPeerAddrOverrides(context.Context, *ListPeerAddrOverridesRequest) (*ListPeerAddrOverridesResponse, error)
+	// DeletePeerAddrOverride removes the address override of a peer, so that we
+	// connect to it at its announced addresses again.
+	DeletePeerAddrOverride(context.Context, *DeletePeerAddrOverrideRequest) (*DeletePeerAddrOverrideResponse, error)
 	// lncli: `getinfo`
 	// GetInfo returns general information concerning the lightning node including
 	// it's identity pubkey, alias, the chains it is connected to, and information
@@ -1428,6 +1494,12 @@ type LightningServer interface {
 	// and its subsystems. This includes the full configuration and the latest log
 	// entries from the log file.
 	GetDebugInfo(context.Context, *GetDebugInfoRequest) (*GetDebugInfoResponse, error)
+	// lncli: `auditgraphcache`
+	// AuditGraphCache runs a consistency audit of the in-memory graph cache
+	// against the persistent graph store right away, instead of waiting for the
+	// next periodic audit. Nodes whose cache entries diverge from the store are
+	// re-primed from the store.
+	AuditGraphCache(context.Context, *AuditGraphCacheRequest) (*AuditGraphCacheResponse, error)
 	// * lncli: `getrecoveryinfo`
 	// GetRecoveryInfo returns information concerning the recovery mode including
 	// whether it's in a recovery mode, whether the recovery is finished, and the
@@ -1789,12 +1861,24 @@ func (UnimplementedLightningServer) ListPeers(context.Context, *ListPeersRequest
 func (UnimplementedLightningServer) SubscribePeerEvents(*PeerEventSubscription, Lightning_SubscribePeerEventsServer) error {
 	return status.Errorf(codes.Unimplemented, "method SubscribePeerEvents not implemented")
 }
+func (UnimplementedLightningServer) SetPeerAddrOverride(context.Context, *SetPeerAddrOverrideRequest) (*SetPeerAddrOverrideResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPeerAddrOverride not implemented")
+}
+func (UnimplementedLightningServer) ListPeerAddrOverrides(context.Context, *ListPeerAddrOverridesRequest) (*ListPeerAddrOverridesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPeerAddrOverrides not implemented")
+}
+func (UnimplementedLightningServer) DeletePeerAddrOverride(context.Context, *DeletePeerAddrOverrideRequest) (*DeletePeerAddrOverrideResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePeerAddrOverride not implemented")
+}
 func (UnimplementedLightningServer) GetInfo(context.Context, *GetInfoRequest) (*GetInfoResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetInfo not implemented")
 }
 func (UnimplementedLightningServer) GetDebugInfo(context.Context, *GetDebugInfoRequest) (*GetDebugInfoResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetDebugInfo not implemented")
 }
+func (UnimplementedLightningServer) AuditGraphCache(context.Context, *AuditGraphCacheRequest) (*AuditGraphCacheResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AuditGraphCache not implemented")
+}
 func (UnimplementedLightningServer) GetRecoveryInfo(context.Context, *GetRecoveryInfoRequest) (*GetRecoveryInfoResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetRecoveryInfo not implemented")
 }
@@ -2237,6 +2321,60 @@ func (x *lightningSubscribePeerEventsServer) Send(m *PeerEvent) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Lightning_SetPeerAddrOverride_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPeerAddrOverrideRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LightningServer).SetPeerAddrOverride(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/lnrpc.Lightning/SetPeerAddrOverride",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LightningServer).SetPeerAddrOverride(ctx, req.(*SetPeerAddrOverrideRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Lightning_ListPeerAddrOverrides_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPeerAddrOverridesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LightningServer).ListPeerAddrOverrides(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/lnrpc.Lightning/ListPeerAddrOverrides",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LightningServer).ListPeerAddrOverrides(ctx, req.(*ListPeerAddrOverridesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Lightning_DeletePeerAddrOverride_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletePeerAddrOverrideRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LightningServer).DeletePeerAddrOverride(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/lnrpc.Lightning/DeletePeerAddrOverride",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LightningServer).DeletePeerAddrOverride(ctx, req.(*DeletePeerAddrOverrideRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Lightning_GetInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetInfoRequest)
 	if err := dec(in); err != nil {
@@ -2273,6 +2411,24 @@ func _Lightning_GetDebugInfo_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Lightning_AuditGraphCache_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuditGraphCacheRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LightningServer).AuditGraphCache(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/lnrpc.Lightning/AuditGraphCache",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LightningServer).AuditGraphCache(ctx, req.(*AuditGraphCacheRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Lightning_GetRecoveryInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetRecoveryInfoRequest)
 	if err := dec(in); err != nil {
@@ -3303,6 +3459,18 @@ var Lightning_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListPeers",
 			Handler:    _Lightning_ListPeers_Handler,
 		},
+		{
+			MethodName: "SetPeerAddrOverride",
+			Handler:    _Lightning_SetPeerAddrOverride_Handler,
+		},
+		{
+			MethodName: "ListPeerAddrOverrides",
+			Handler:    _Lightning_ListPeerAddrOverrides_Handler,
+		},
+		{
+			MethodName: "DeletePeerAddrOverride",
+			Handler:    _Lightning_DeletePeerAddrOverride_Handler,
+		},
 		{
 			MethodName: "GetInfo",
 			Handler:    _Lightning_GetInfo_Handler,
@@ -3311,6 +3479,10 @@ var Lightning_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetDebugInfo",
 			Handler:    _Lightning_GetDebugInfo_Handler,
 		},
+		{
+			MethodName: "AuditGraphCache",
+			Handler:    _Lightning_AuditGraphCache_Handler,
+		},
 		{
 			MethodName: "GetRecoveryInfo",
 			Handler:    _Lightning_GetRecoveryInfo_Handler,
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/lightningnetwork/lnd/htlcswitch"
+	"github.com/lightningnetwork/lnd/htlcswitch/hop"
 	"github.com/lightningnetwork/lnd/invoices"
 	"github.com/lightningnetwork/lnd/lnrpc"
 )
@@ -154,6 +155,11 @@ func rpcFailReason(linkErr *htlcswitch.LinkError) (lnrpc.Failure_FailureCode,
 		fd, err := rpcOutgoingFailure(failureDetail)
 		return wireCode, fd, err
 
+	// The violated constraint of a blinded route isn't exposed over rpc,
+	// the wire code already identifies the failure as a blinding failure.
+	case hop.BlindedViolation:
+		return wireCode, FailureDetail_NO_DETAIL, nil
+
 	default:
 		return 0, 0, fmt.Errorf("unknown failure "+
 			"detail type: %T", linkErr.FailureDetail)
@@ -3,7 +3,9 @@ package chancloser
 import (
 	"bytes"
 	"fmt"
+	"time"
 
+	"github.com/btcsuite/btcd/blockchain"
 	"github.com/btcsuite/btcd/btcec/v2/schnorr/musig2"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
@@ -11,10 +13,12 @@ import (
 	"github.com/btcsuite/btcd/wire"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/clock"
 	"github.com/lightningnetwork/lnd/fn"
 	"github.com/lightningnetwork/lnd/htlcswitch"
 	"github.com/lightningnetwork/lnd/input"
 	"github.com/lightningnetwork/lnd/labels"
+	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
 	"github.com/lightningnetwork/lnd/lnwire"
@@ -138,6 +142,9 @@ type ChanCloseCfg struct {
 	// FeeEstimator is used to estimate the absolute starting co-op close
 	// fee.
 	FeeEstimator CoopFeeEstimator
+
+	// Clock is used to time the fee negotiation for the close telemetry.
+	Clock clock.Clock
 }
 
 // ChanCloser is a state machine that handles the cooperative channel closure
@@ -213,6 +220,14 @@ type ChanCloser struct {
 	// we use to handle a specific race condition caused by the independent
 	// message processing queues.
 	cachedClosingSigned fn.Option[lnwire.ClosingSigned]
+
+	// negotiationStart is the time at which we entered the fee
+	// negotiation.
+	negotiationStart time.Time
+
+	// telemetry records the rounds and proposals of the fee negotiation.
+	// The final fee rate and duration are filled in once it completes.
+	telemetry channeldb.CoopCloseTelemetry
 }
 
 // calcCoopCloseFee computes an "ideal" absolute co-op close fee given the
@@ -436,6 +451,18 @@ func (c *ChanCloser) Channel() *lnwallet.LightningChannel {
 	return c.cfg.Channel.(*lnwallet.LightningChannel)
 }
 
+// Telemetry returns the telemetry of the fee negotiation.
+//
+// NOTE: The telemetry is only available if the state machine is in the
+// closeFinished state.
+func (c *ChanCloser) Telemetry() (*channeldb.CoopCloseTelemetry, error) {
+	if c.state != closeFinished {
+		return nil, ErrChanCloseNotFinished
+	}
+
+	return &c.telemetry, nil
+}
+
 // NegotiationHeight returns the negotiation height.
 func (c *ChanCloser) NegotiationHeight() uint32 {
 	return c.negotiationHeight
@@ -659,6 +686,7 @@ func (c *ChanCloser) BeginNegotiation() (fn.Option[lnwire.ClosingSigned],
 		// constructing and sending our initial signature for what we
 		// think the closing transaction should look like.
 		c.state = closeFeeNegotiation
+		c.negotiationStart = c.cfg.Clock.Now()
 
 		if !c.cfg.Channel.IsInitiator() {
 			// By default this means we do nothing, but we do want
@@ -725,6 +753,11 @@ func (c *ChanCloser) ReceiveClosingSigned( //nolint:funlen
 		// to our ideal fee.
 		remoteProposedFee := msg.FeeSatoshis
 
+		c.telemetry.Rounds++
+		c.telemetry.RemoteProposals = append(
+			c.telemetry.RemoteProposals, remoteProposedFee,
+		)
+
 		_, feeMatchesOffer := c.priorFeeOffers[remoteProposedFee]
 		switch {
 		// For taproot channels, since nonces are involved, we can't do
@@ -866,6 +899,24 @@ func (c *ChanCloser) ReceiveClosingSigned( //nolint:funlen
 			return noClosing, err
 		}
 
+		// With the negotiation over, we'll complete and persist its
+		// telemetry. It's only informational, so we don't fail the
+		// close if we can't store it.
+		closeWeight := blockchain.GetTransactionWeight(
+			btcutil.NewTx(closeTx),
+		)
+		c.telemetry.FinalFeeRate = chainfee.NewSatPerKWeight(
+			remoteProposedFee, lntypes.WeightUnit(closeWeight),
+		)
+		c.telemetry.Duration = c.cfg.Clock.Now().Sub(
+			c.negotiationStart,
+		)
+		err = c.cfg.Channel.MarkCoopCloseTelemetry(&c.telemetry)
+		if err != nil {
+			chancloserLog.Warnf("ChannelPoint(%v): unable to "+
+				"store close telemetry: %v", c.chanPoint, err)
+		}
+
 		// With the closing transaction crafted, we'll now broadcast it
 		// to the network.
 		chancloserLog.Infof("Broadcasting cooperative close tx: %v",
@@ -959,6 +1010,7 @@ func (c *ChanCloser) proposeCloseSigned(fee btcutil.Amount) (
 	}
 
 	c.lastFeeProposal = fee
+	c.telemetry.LocalProposals = append(c.telemetry.LocalProposals, fee)
 
 	chancloserLog.Infof("ChannelPoint(%v): proposing fee of %v sat to "+
 		"close chan", c.chanPoint, int64(fee))
@@ -6,7 +6,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/btcsuite/btcd/blockchain"
 	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
 	"github.com/btcsuite/btcd/btcec/v2/schnorr/musig2"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
@@ -14,8 +16,10 @@ import (
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/clock"
 	"github.com/lightningnetwork/lnd/input"
 	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnutils"
 	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
@@ -144,6 +148,13 @@ type mockChannel struct {
 	chanType  channeldb.ChannelType
 	localKey  keychain.KeyDescriptor
 	remoteKey keychain.KeyDescriptor
+
+	// signer, if set, is used to sign the close proposals of non-taproot
+	// channels.
+	signer *btcec.PrivateKey
+
+	// telemetry is the last close telemetry that was persisted.
+	telemetry *channeldb.CoopCloseTelemetry
 }
 
 func (m *mockChannel) ChannelPoint() wire.OutPoint {
@@ -158,6 +169,14 @@ func (m *mockChannel) MarkShutdownSent(*channeldb.ShutdownInfo) error {
 	return nil
 }
 
+func (m *mockChannel) MarkCoopCloseTelemetry(
+	telemetry *channeldb.CoopCloseTelemetry) error {
+
+	m.telemetry = telemetry
+
+	return nil
+}
+
 func (m *mockChannel) IsInitiator() bool {
 	return m.initiator
 }
@@ -188,6 +207,11 @@ func (m *mockChannel) CreateCloseProposal(fee btcutil.Amount,
 		), nil, 0, nil
 	}
 
+	if m.signer != nil {
+		var sigHash [32]byte
+		return ecdsa.Sign(m.signer, sigHash[:]), nil, 0, nil
+	}
+
 	return nil, nil, 0, nil
 }
 
@@ -338,6 +362,7 @@ func TestMaxFeeClamp(t *testing.T) {
 					Channel:      &channel,
 					MaxFee:       test.inputMaxFee,
 					FeeEstimator: &SimpleCoopFeeEstimator{},
+					Clock:        clock.NewDefaultClock(),
 				}, nil, test.idealFee, 0, nil, false,
 			)
 
@@ -377,6 +402,7 @@ func TestMaxFeeBailOut(t *testing.T) {
 					targetFee: absoluteFee,
 				},
 				MaxFee: idealFee * 2,
+				Clock:  clock.NewDefaultClock(),
 			}
 			chanCloser := NewChanCloser(
 				closeCfg, nil, idealFee, 0, nil, false,
@@ -503,6 +529,7 @@ func TestTaprootFastClose(t *testing.T) {
 			DisableChannel: func(wire.OutPoint) error {
 				return nil
 			},
+			Clock: clock.NewDefaultClock(),
 		}, nil, idealFee, 0, nil, true,
 	)
 	aliceCloser.initFeeBaseline()
@@ -520,6 +547,7 @@ func TestTaprootFastClose(t *testing.T) {
 			DisableChannel: func(wire.OutPoint) error {
 				return nil
 			},
+			Clock: clock.NewDefaultClock(),
 		}, nil, idealFee, 0, nil, false,
 	)
 	bobCloser.initFeeBaseline()
@@ -608,3 +636,110 @@ func TestTaprootFastClose(t *testing.T) {
 	require.NotNil(t, tx)
 	require.True(t, oClosingSigned.IsNone())
 }
+
+// TestCoopCloseTelemetry tests that the rounds, proposals, final fee rate and
+// duration of a fee negotiation are recorded and persisted by both parties.
+func TestCoopCloseTelemetry(t *testing.T) {
+	t.Parallel()
+
+	aliceKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	bobKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	aliceChan := &mockChannel{initiator: true, signer: aliceKey}
+	bobChan := &mockChannel{signer: bobKey}
+
+	startTime := time.Unix(1_700_000_000, 0)
+	testClock := clock.NewTestClock(startTime)
+
+	newCloser := func(channel *mockChannel, idealFee btcutil.Amount,
+		initiator bool) *ChanCloser {
+
+		return NewChanCloser(
+			ChanCloseCfg{
+				Channel: channel,
+				BroadcastTx: func(*wire.MsgTx, string) error {
+					return nil
+				},
+				DisableChannel: func(wire.OutPoint) error {
+					return nil
+				},
+				FeeEstimator: &mockCoopFeeEstimator{
+					targetFee: idealFee,
+				},
+				Clock: testClock,
+			}, nil, chainfee.FeePerKwFloor, 0, nil, initiator,
+		)
+	}
+
+	// Alice and Bob start far apart, so that it takes them three rounds
+	// to settle on a fee:
+	//
+	//   Alice: 1000 -> 1100 -> 1210 (accepted)
+	//   Bob:   1800 -> 1620 -> 1210
+	aliceCloser := newCloser(aliceChan, 1000, true)
+	bobCloser := newCloser(bobChan, 1800, false)
+
+	shutdown, err := aliceCloser.ShutdownChan()
+	require.NoError(t, err)
+
+	oShutdown, err := bobCloser.ReceiveShutdown(*shutdown)
+	require.NoError(t, err)
+	_, err = bobCloser.BeginNegotiation()
+	require.NoError(t, err)
+
+	_, err = aliceCloser.ReceiveShutdown(oShutdown.UnwrapOrFail(t))
+	require.NoError(t, err)
+	oClosingSigned, err := aliceCloser.BeginNegotiation()
+	require.NoError(t, err)
+
+	// The negotiation is still ongoing, so there's no telemetry yet.
+	_, err = aliceCloser.Telemetry()
+	require.ErrorIs(t, err, ErrChanCloseNotFinished)
+
+	// We'll now pass the offers back and forth until both parties stop
+	// responding. The negotiation takes a few seconds of wall-clock time.
+	testClock.SetTime(startTime.Add(3 * time.Second))
+
+	sender, receiver := aliceCloser, bobCloser
+	for oClosingSigned.IsSome() {
+		oClosingSigned, err = receiver.ReceiveClosingSigned(
+			oClosingSigned.UnwrapOrFail(t),
+		)
+		require.NoError(t, err)
+
+		sender, receiver = receiver, sender
+	}
+
+	closeTx, err := aliceCloser.ClosingTx()
+	require.NoError(t, err)
+
+	finalFeeRate := chainfee.NewSatPerKWeight(
+		1210, lntypes.WeightUnit(
+			blockchain.GetTransactionWeight(btcutil.NewTx(closeTx)),
+		),
+	)
+
+	aliceTelemetry, err := aliceCloser.Telemetry()
+	require.NoError(t, err)
+	require.Equal(t, &channeldb.CoopCloseTelemetry{
+		Rounds:          3,
+		LocalProposals:  []btcutil.Amount{1000, 1100, 1210},
+		RemoteProposals: []btcutil.Amount{1800, 1620, 1210},
+		FinalFeeRate:    finalFeeRate,
+		Duration:        3 * time.Second,
+	}, aliceTelemetry)
+	require.Equal(t, aliceTelemetry, aliceChan.telemetry)
+
+	bobTelemetry, err := bobCloser.Telemetry()
+	require.NoError(t, err)
+	require.Equal(t, &channeldb.CoopCloseTelemetry{
+		Rounds:          3,
+		LocalProposals:  []btcutil.Amount{1800, 1620, 1210},
+		RemoteProposals: []btcutil.Amount{1000, 1100, 1210},
+		FinalFeeRate:    finalFeeRate,
+		Duration:        3 * time.Second,
+	}, bobTelemetry)
+	require.Equal(t, bobTelemetry, bobChan.telemetry)
+}
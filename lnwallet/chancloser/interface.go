@@ -40,6 +40,10 @@ type Channel interface { //nolint:interfacebloat
 	// been sent by us and so should be re-sent on re-establish.
 	MarkShutdownSent(info *channeldb.ShutdownInfo) error
 
+	// MarkCoopCloseTelemetry persists the telemetry of the completed fee
+	// negotiation, so it can be added to the close summary of the channel.
+	MarkCoopCloseTelemetry(telemetry *channeldb.CoopCloseTelemetry) error
+
 	// IsInitiator returns true we are the initiator of the channel.
 	IsInitiator() bool
 
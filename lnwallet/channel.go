@@ -8890,6 +8890,17 @@ func (lc *LightningChannel) MarkShutdownSent(
 	return lc.channelState.MarkShutdownSent(info)
 }
 
+// MarkCoopCloseTelemetry persists the telemetry of the completed cooperative
+// close fee negotiation of the channel.
+func (lc *LightningChannel) MarkCoopCloseTelemetry(
+	telemetry *channeldb.CoopCloseTelemetry) error {
+
+	lc.Lock()
+	defer lc.Unlock()
+
+	return lc.channelState.MarkCoopCloseTelemetry(telemetry)
+}
+
 // MarkDataLoss marks sets the channel status to LocalDataLoss and stores the
 // passed commitPoint for use to retrieve funds in case the remote force closes
 // the channel.
@@ -79,10 +79,23 @@ func (e *ExtraOpaqueData) PackRecords(recordProducers ...tlv.RecordProducer) err
 // ExtractRecords attempts to decode any types in the internal raw bytes as if
 // it were a tlv stream. The set of raw parsed types is returned, and any
 // passed records (if found in the stream) will be parsed into the proper
-// tlv.Record.
+// tlv.Record. A stream that contains the same type more than once, known or
+// not, is rejected with tlv.ErrStreamNotCanonical.
 func (e *ExtraOpaqueData) ExtractRecords(recordProducers ...tlv.RecordProducer) (
 	tlv.TypeMap, error) {
 
+	typeMap, _, err := decodeRecords(*e, recordProducers)
+
+	return typeMap, err
+}
+
+// decodeRecords decodes data as a tlv stream, parsing any of the passed
+// records that are found in the stream. If decoding fails, the number of bytes
+// of data that were consumed before the failure is returned along with the
+// error.
+func decodeRecords(data []byte, recordProducers []tlv.RecordProducer) (
+	tlv.TypeMap, int, error) {
+
 	// First, assemble all the records passed in in series.
 	records := make([]tlv.Record, 0, len(recordProducers))
 	for _, producer := range recordProducers {
@@ -93,20 +106,124 @@ func (e *ExtraOpaqueData) ExtractRecords(recordProducers ...tlv.RecordProducer)
 	// decode from the stream, to ensure they're canonical.
 	tlv.SortRecords(records)
 
-	extraBytesReader := bytes.NewReader(*e)
-
 	tlvStream, err := tlv.NewStream(records...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// Since ExtraOpaqueData is provided by a potentially malicious peer,
 	// pass it into the P2P decoding variant.
-	return tlvStream.DecodeWithParsedTypesP2P(extraBytesReader)
+	reader := bytes.NewReader(data)
+	typeMap, err := tlvStream.DecodeWithParsedTypesP2P(reader)
+	if err != nil {
+		// The stream reads the records straight from our reader, so
+		// the number of bytes it consumed tells us how far it got
+		// before failing.
+		return nil, len(data) - reader.Len(), err
+	}
+
+	return typeMap, 0, nil
+}
+
+// DecodeError is returned when the TLV records of a message can't be decoded.
+// Next to the underlying error, it identifies the record that was being
+// decoded and where it starts, which helps to pinpoint malformed data.
+type DecodeError struct {
+	// MsgType is the type of the message that the records belong to. It
+	// is zero if the records aren't part of a wire message, such as the
+	// encrypted data of a blinded route.
+	MsgType MessageType
+
+	// TlvType is the type of the record that failed to decode. It is zero
+	// if the type of the record itself couldn't be read.
+	TlvType tlv.Type
+
+	// Offset is the byte offset within the TLV stream at which the record
+	// that failed to decode starts.
+	Offset int
+
+	// Err is the underlying decoding error.
+	Err error
+}
+
+// Error returns a human-readable description of the decode error.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("unable to decode tlv record type=%d at offset=%d "+
+		"of msg_type=%v: %v", e.TlvType, e.Offset, e.MsgType, e.Err)
+}
+
+// Unwrap returns the underlying decoding error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// ExtractRecordsDetailed is identical to ExtractRecords, but if the records
+// can't be decoded a *DecodeError is returned that reports the type and offset
+// of the record that failed to decode within a message of the given type.
+func (e *ExtraOpaqueData) ExtractRecordsDetailed(msgType MessageType,
+	recordProducers ...tlv.RecordProducer) (tlv.TypeMap, error) {
+
+	typeMap, consumed, err := decodeRecords(*e, recordProducers)
+	if err != nil {
+		tlvType, offset := locateRecord(*e, consumed)
+
+		return nil, &DecodeError{
+			MsgType: msgType,
+			TlvType: tlvType,
+			Offset:  offset,
+			Err:     err,
+		}
+	}
+
+	return typeMap, nil
+}
+
+// locateRecord walks the record headers of the TLV stream in data to find the
+// record that was being decoded once the first consumed bytes of the stream
+// were read. It returns the type of that record and the offset at which it
+// starts.
+func locateRecord(data []byte, consumed int) (tlv.Type, int) {
+	var (
+		r   = bytes.NewReader(data)
+		buf [8]byte
+	)
+	for {
+		offset := len(data) - r.Len()
+
+		t, err := tlv.ReadVarInt(r, &buf)
+		if err != nil {
+			return 0, offset
+		}
+
+		length, err := tlv.ReadVarInt(r, &buf)
+		if err != nil {
+			return tlv.Type(t), offset
+		}
+
+		// If the value of this record is truncated, or the decoder
+		// stopped somewhere within it, this is the record we're
+		// looking for.
+		if length > uint64(r.Len()) {
+			return tlv.Type(t), offset
+		}
+
+		end := len(data) - r.Len() + int(length)
+		if consumed <= end {
+			return tlv.Type(t), offset
+		}
+
+		if _, err := r.Seek(int64(length), io.SeekCurrent); err != nil {
+			return tlv.Type(t), offset
+		}
+	}
 }
 
 // EncodeMessageExtraData encodes the given recordProducers into the given
-// extraData.
+// extraData, sorted by type. Duplicate types are rejected with
+// tlv.ErrStreamNotCanonical. Any records already stored in extraData,
+// including unknown ones, are replaced. Messages that are covered by a
+// signature must therefore write the extra data they received as-is rather
+// than re-encode it with this function.
 func EncodeMessageExtraData(extraData *ExtraOpaqueData,
 	recordProducers ...tlv.RecordProducer) error {
 
@@ -0,0 +1,152 @@
+package lnwire
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+const (
+	// CompressedExtraDataType is the experimental TLV type of the record
+	// that carries gzip compressed extra data. Compression isn't part of
+	// the spec, so the type is odd and outside of the range used by the
+	// spec to make sure that nodes that don't know about it simply ignore
+	// the record.
+	CompressedExtraDataType tlv.Type = 65537
+)
+
+// ErrExtraDataTooLarge is returned when compressed extra data decompresses to
+// more than the maximum size of a message body.
+var ErrExtraDataTooLarge = errors.New("decompressed extra data exceeds " +
+	"max message size")
+
+// Compress replaces the extra data with a single CompressedExtraDataType
+// record that holds the gzip compressed extra data, if the extra data is
+// larger than the passed threshold. The extra data is left untouched if
+// compression wouldn't make it any smaller. A boolean is returned that
+// indicates whether the extra data was compressed.
+//
+// NOTE: Compression is opt-in and not part of the spec. Nodes that don't
+// support it will ignore the compressed records.
+func (e *ExtraOpaqueData) Compress(threshold int) (bool, error) {
+	if len(*e) <= threshold || e.IsCompressed() {
+		return false, nil
+	}
+
+	var b bytes.Buffer
+	gzipWriter := gzip.NewWriter(&b)
+	if _, err := gzipWriter.Write(*e); err != nil {
+		return false, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return false, err
+	}
+
+	compressedBytes := b.Bytes()
+	tlvStream, err := tlv.NewStream(tlv.MakePrimitiveRecord(
+		CompressedExtraDataType, &compressedBytes,
+	))
+	if err != nil {
+		return false, err
+	}
+
+	var compressed bytes.Buffer
+	if err := tlvStream.Encode(&compressed); err != nil {
+		return false, err
+	}
+
+	if compressed.Len() >= len(*e) {
+		return false, nil
+	}
+
+	*e = ExtraOpaqueData(compressed.Bytes())
+
+	return true, nil
+}
+
+// IsCompressed returns true if the extra data consists of nothing but a single
+// CompressedExtraDataType record.
+func (e ExtraOpaqueData) IsCompressed() bool {
+	_, ok := e.compressedPayload()
+	return ok
+}
+
+// Decompress returns the original extra data if the extra data is compressed.
+// Otherwise, the extra data is returned as is. An error is returned if the
+// compressed data is invalid or decompresses to more than the maximum size of
+// a message body.
+func (e ExtraOpaqueData) Decompress() (ExtraOpaqueData, error) {
+	payload, ok := e.compressedPayload()
+	if !ok {
+		return e, nil
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decompress extra data: %w",
+			err)
+	}
+
+	// Limit the amount of data we'll read, so that a small payload can't
+	// make us allocate an unbounded amount of memory.
+	decompressed, err := io.ReadAll(
+		io.LimitReader(gzipReader, MaxMsgBody+1),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decompress extra data: %w",
+			err)
+	}
+	if len(decompressed) > MaxMsgBody {
+		return nil, ErrExtraDataTooLarge
+	}
+
+	return decompressed, nil
+}
+
+// ExtractCompressedRecords is identical to ExtractRecords, but if the extra
+// data is compressed, the records are extracted from the decompressed bytes.
+// The raw extra data is left as-is, so signatures over it still verify.
+//
+// NOTE: Decompression is opt-in just like compression. ExtractRecords ignores
+// the odd CompressedExtraDataType record like any other unknown record, which
+// is what nodes that don't support compression do as well. This method must
+// therefore never be used for extra data that the rest of the network
+// interprets too, such as signed gossip, as we'd act on records that other
+// nodes don't see.
+func (e *ExtraOpaqueData) ExtractCompressedRecords(
+	recordProducers ...tlv.RecordProducer) (tlv.TypeMap, error) {
+
+	extraBytes, err := e.Decompress()
+	if err != nil {
+		return nil, err
+	}
+
+	typeMap, _, err := decodeRecords(extraBytes, recordProducers)
+
+	return typeMap, err
+}
+
+// compressedPayload returns the value of the CompressedExtraDataType record if
+// the extra data consists of nothing but that record.
+func (e ExtraOpaqueData) compressedPayload() ([]byte, bool) {
+	var (
+		r   = bytes.NewReader(e)
+		buf [8]byte
+	)
+
+	t, err := tlv.ReadVarInt(r, &buf)
+	if err != nil || tlv.Type(t) != CompressedExtraDataType {
+		return nil, false
+	}
+
+	length, err := tlv.ReadVarInt(r, &buf)
+	if err != nil || length != uint64(r.Len()) {
+		return nil, false
+	}
+
+	return e[len(e)-r.Len():], true
+}
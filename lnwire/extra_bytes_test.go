@@ -151,3 +151,426 @@ func TestExtraOpaqueDataPackUnpackRecords(t *testing.T) {
 		t.Fatalf("type2 not found in typeMap")
 	}
 }
+
+// TestExtractRecordsDetailed tests that a TLV stream that fails to decode
+// results in a DecodeError that reports the type and offset of the record that
+// couldn't be decoded.
+func TestExtractRecordsDetailed(t *testing.T) {
+	t.Parallel()
+
+	var (
+		type1 tlv.Type = 1
+		type2 tlv.Type = 2
+
+		channelType uint8  = 2
+		hop         uint32 = 99
+
+		msgType MessageType = MsgChannelUpdate
+	)
+
+	// The records are encoded as [01 01 02] [02 04 00 00 00 63], so the
+	// second record starts at offset 3.
+	var extraBytes ExtraOpaqueData
+	err := extraBytes.PackRecords(
+		&recordProducer{tlv.MakePrimitiveRecord(type1, &channelType)},
+		&recordProducer{tlv.MakePrimitiveRecord(type2, &hop)},
+	)
+	require.NoError(t, err)
+	require.Len(t, extraBytes, 9)
+
+	newRecords := func() []tlv.RecordProducer {
+		var (
+			channelType uint8
+			hop         uint32
+		)
+
+		return []tlv.RecordProducer{
+			&recordProducer{tlv.MakePrimitiveRecord(
+				type1, &channelType,
+			)},
+			&recordProducer{tlv.MakePrimitiveRecord(type2, &hop)},
+		}
+	}
+
+	// The complete stream decodes without error.
+	_, err = extraBytes.ExtractRecordsDetailed(
+		msgType, newRecords()...,
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		truncated int
+		tlvType   tlv.Type
+		offset    int
+	}{
+		{
+			name:      "missing first length",
+			truncated: 1,
+			tlvType:   type1,
+			offset:    0,
+		},
+		{
+			name:      "missing first value",
+			truncated: 2,
+			tlvType:   type1,
+			offset:    0,
+		},
+		{
+			name:      "missing second length",
+			truncated: 4,
+			tlvType:   type2,
+			offset:    3,
+		},
+		{
+			name:      "partial second value",
+			truncated: 7,
+			tlvType:   type2,
+			offset:    3,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			truncated := extraBytes[:test.truncated]
+			_, err := truncated.ExtractRecordsDetailed(
+				msgType, newRecords()...,
+			)
+
+			var decodeErr *DecodeError
+			require.ErrorAs(t, err, &decodeErr)
+			require.Equal(t, msgType, decodeErr.MsgType)
+			require.Equal(t, test.tlvType, decodeErr.TlvType)
+			require.Equal(t, test.offset, decodeErr.Offset)
+		})
+	}
+
+	// Errors that aren't caused by truncation should be located as well.
+	// Here the records are out of order, which is detected once the type
+	// of the second record at offset 6 is read.
+	unsorted := append(
+		ExtraOpaqueData{}, append(extraBytes[3:], extraBytes[:3]...)...,
+	)
+	_, err = unsorted.ExtractRecordsDetailed(
+		msgType, newRecords()...,
+	)
+
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	require.ErrorIs(t, err, tlv.ErrStreamNotCanonical)
+	require.Equal(t, type1, decodeErr.TlvType)
+	require.Equal(t, 6, decodeErr.Offset)
+}
+
+// TestExtractRecordsDuplicateTypes asserts that a TLV stream that contains the
+// same record type twice is rejected, rather than the second record silently
+// overwriting the first one. Otherwise two nodes could disagree about the
+// content of a signed message while both accept its signature.
+func TestExtractRecordsDuplicateTypes(t *testing.T) {
+	t.Parallel()
+
+	var (
+		channelType = ChannelType(*NewRawFeatureVector(
+			StaticRemoteKeyRequired,
+		))
+		fee        = Fee{BaseFee: 1, FeeRate: 2}
+		hop uint32 = 99
+
+		primitiveType   tlv.Type = 2
+		unknownOddType  tlv.Type = 101
+		unknownEvenType tlv.Type = 100
+	)
+
+	tests := []struct {
+		name    string
+		tlvType tlv.Type
+		record  tlv.RecordProducer
+
+		// newRecord returns the record to decode the stream into. If
+		// nil, the record is unknown to the decoder.
+		newRecord func() tlv.RecordProducer
+	}{
+		{
+			name:    "channel type",
+			tlvType: ChannelTypeRecordType,
+			record:  &channelType,
+			newRecord: func() tlv.RecordProducer {
+				return &ChannelType{}
+			},
+		},
+		{
+			name:    "inbound fee",
+			tlvType: FeeRecordType,
+			record:  &fee,
+			newRecord: func() tlv.RecordProducer {
+				return &Fee{}
+			},
+		},
+		{
+			name:    "primitive",
+			tlvType: primitiveType,
+			record: &recordProducer{
+				tlv.MakePrimitiveRecord(primitiveType, &hop),
+			},
+			newRecord: func() tlv.RecordProducer {
+				var hop uint32
+				return &recordProducer{tlv.MakePrimitiveRecord(
+					primitiveType, &hop,
+				)}
+			},
+		},
+		{
+			name:    "unknown odd",
+			tlvType: unknownOddType,
+			record: &recordProducer{
+				tlv.MakePrimitiveRecord(unknownOddType, &hop),
+			},
+		},
+		{
+			name:    "unknown even",
+			tlvType: unknownEvenType,
+			record: &recordProducer{
+				tlv.MakePrimitiveRecord(unknownEvenType, &hop),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var single ExtraOpaqueData
+			require.NoError(t, single.PackRecords(test.record))
+
+			var producers []tlv.RecordProducer
+			if test.newRecord != nil {
+				producers = append(producers, test.newRecord())
+			}
+
+			_, err := single.ExtractRecords(producers...)
+			require.NoError(t, err)
+
+			// Repeating the record must be rejected, no matter
+			// whether the record is known to us.
+			duplicated := append(
+				append(ExtraOpaqueData{}, single...),
+				single...,
+			)
+			_, err = duplicated.ExtractRecords(producers...)
+			require.ErrorIs(t, err, tlv.ErrStreamNotCanonical)
+
+			// The detailed error points at the second record.
+			_, err = duplicated.ExtractRecordsDetailed(
+				MsgChannelUpdate, producers...,
+			)
+
+			var decodeErr *DecodeError
+			require.ErrorAs(t, err, &decodeErr)
+			require.ErrorIs(t, err, tlv.ErrStreamNotCanonical)
+			require.Equal(t, test.tlvType, decodeErr.TlvType)
+			require.Equal(t, len(single), decodeErr.Offset)
+		})
+	}
+}
+
+// TestSignedExtraDataRoundTrip tests that the extra data of a signed message
+// that mixes known and unknown records is re-encoded byte for byte, no matter
+// whether the unknown records sort before or after the known ones, so that
+// the signature stays valid when the message is relayed.
+func TestSignedExtraDataRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	var (
+		fee        = Fee{BaseFee: -1, FeeRate: 100}
+		val uint32 = 7
+	)
+	unknown := func(typ tlv.Type) tlv.RecordProducer {
+		return &recordProducer{tlv.MakePrimitiveRecord(typ, &val)}
+	}
+
+	tests := []struct {
+		name    string
+		records []tlv.RecordProducer
+	}{
+		{
+			name:    "unknown before known",
+			records: []tlv.RecordProducer{unknown(1), &fee},
+		},
+		{
+			name: "unknown after known",
+			records: []tlv.RecordProducer{
+				&fee, unknown(FeeRecordType + 2),
+			},
+		},
+		{
+			name: "known between unknown",
+			records: []tlv.RecordProducer{
+				unknown(FeeRecordType + 2), &fee, unknown(1),
+				unknown(FeeRecordType - 2),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			upd := &ChannelUpdate{
+				ShortChannelID:  NewShortChanIDFromInt(1),
+				MessageFlags:    ChanUpdateRequiredMaxHtlc,
+				HtlcMaximumMsat: 1000,
+			}
+			err := upd.ExtraOpaqueData.PackRecords(test.records...)
+			require.NoError(t, err)
+
+			dataToSign, err := upd.DataToSign()
+			require.NoError(t, err)
+
+			var b bytes.Buffer
+			_, err = WriteMessage(&b, upd, 0)
+			require.NoError(t, err)
+			received := b.Bytes()
+
+			msg, err := ReadMessage(bytes.NewReader(received), 0)
+			require.NoError(t, err)
+
+			decoded, ok := msg.(*ChannelUpdate)
+			require.True(t, ok)
+
+			// Extracting the known record must not change the
+			// extra data.
+			var decodedFee Fee
+			typeMap, err := decoded.ExtraOpaqueData.ExtractRecords(
+				&decodedFee,
+			)
+			require.NoError(t, err)
+			require.Len(t, typeMap, len(test.records))
+			require.Equal(t, fee, decodedFee)
+
+			// Both the signed data and the relayed message must be
+			// identical to what we received.
+			decodedDataToSign, err := decoded.DataToSign()
+			require.NoError(t, err)
+			require.Equal(t, dataToSign, decodedDataToSign)
+
+			b.Reset()
+			_, err = WriteMessage(&b, decoded, 0)
+			require.NoError(t, err)
+			require.Equal(t, received, b.Bytes())
+		})
+	}
+}
+
+// TestEncodeMessageExtraDataCanonical tests that EncodeMessageExtraData sorts
+// the records by type and rejects duplicate types.
+func TestEncodeMessageExtraDataCanonical(t *testing.T) {
+	t.Parallel()
+
+	var (
+		fee        = Fee{BaseFee: 1, FeeRate: 2}
+		val uint32 = 3
+		low        = &recordProducer{tlv.MakePrimitiveRecord(1, &val)}
+	)
+
+	var sorted, reversed ExtraOpaqueData
+	require.NoError(t, EncodeMessageExtraData(&sorted, low, &fee))
+	require.NoError(t, EncodeMessageExtraData(&reversed, &fee, low))
+	require.Equal(t, sorted, reversed)
+
+	var duplicated ExtraOpaqueData
+	err := EncodeMessageExtraData(&duplicated, low, &fee, low)
+	require.ErrorIs(t, err, tlv.ErrStreamNotCanonical)
+}
+
+// TestExtraOpaqueDataCompression tests that large extra data can be compressed
+// and that records are only extracted from compressed extra data on request.
+func TestExtraOpaqueDataCompression(t *testing.T) {
+	t.Parallel()
+
+	var (
+		recordType tlv.Type = 1
+		value               = bytes.Repeat([]byte("extra data "), 1000)
+	)
+
+	var extraBytes ExtraOpaqueData
+	err := extraBytes.PackRecords(&recordProducer{
+		tlv.MakePrimitiveRecord(recordType, &value),
+	})
+	require.NoError(t, err)
+
+	original := append(ExtraOpaqueData{}, extraBytes...)
+
+	// Extra data that doesn't exceed the threshold is left untouched.
+	compressed, err := extraBytes.Compress(len(extraBytes))
+	require.NoError(t, err)
+	require.False(t, compressed)
+	require.Equal(t, original, extraBytes)
+
+	// Once the threshold is exceeded, the extra data is compressed into a
+	// much smaller record.
+	compressed, err = extraBytes.Compress(1000)
+	require.NoError(t, err)
+	require.True(t, compressed)
+	require.True(t, extraBytes.IsCompressed())
+	require.Less(t, len(extraBytes), len(original)/10)
+
+	// Compressing twice has no effect.
+	compressedBytes := append(ExtraOpaqueData{}, extraBytes...)
+	compressed, err = extraBytes.Compress(1000)
+	require.NoError(t, err)
+	require.False(t, compressed)
+	require.Equal(t, compressedBytes, extraBytes)
+
+	// Without opting in to decompression, the compressed record is
+	// ignored like any other unknown odd record.
+	var decodedValue []byte
+	typeMap, err := extraBytes.ExtractRecords(&recordProducer{
+		tlv.MakePrimitiveRecord(recordType, &decodedValue),
+	})
+	require.NoError(t, err)
+	require.NotContains(t, typeMap, recordType)
+	require.Contains(t, typeMap, CompressedExtraDataType)
+	require.Empty(t, decodedValue)
+
+	// Once opted in, the records are extracted from the decompressed
+	// data.
+	typeMap, err = extraBytes.ExtractCompressedRecords(&recordProducer{
+		tlv.MakePrimitiveRecord(recordType, &decodedValue),
+	})
+	require.NoError(t, err)
+	require.Contains(t, typeMap, recordType)
+	require.Equal(t, value, decodedValue)
+
+	decompressed, err := extraBytes.Decompress()
+	require.NoError(t, err)
+	require.Equal(t, original, decompressed)
+
+	// Data that doesn't compress well is left untouched.
+	random := make(ExtraOpaqueData, 2000)
+	_, err = rand.Read(random)
+	require.NoError(t, err)
+	randomCopy := append(ExtraOpaqueData{}, random...)
+
+	compressed, err = random.Compress(1000)
+	require.NoError(t, err)
+	require.False(t, compressed)
+	require.Equal(t, randomCopy, random)
+
+	// Compressed data that expands beyond the max message size is
+	// rejected.
+	tooLarge := make(ExtraOpaqueData, MaxMsgBody+1)
+	compressed, err = tooLarge.Compress(1000)
+	require.NoError(t, err)
+	require.True(t, compressed)
+
+	_, err = tooLarge.Decompress()
+	require.ErrorIs(t, err, ErrExtraDataTooLarge)
+}
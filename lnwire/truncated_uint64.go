@@ -0,0 +1,53 @@
+package lnwire
+
+import (
+	"io"
+
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+// TruncatedUint64 is a uint64 that is encoded in a TLV record with its leading
+// zero bytes trimmed, as done by the tu64 type of the spec. It is meant to be
+// used as a tlv.RecordT, which provides the TLV type of the record.
+type TruncatedUint64 uint64
+
+// Record returns a TLV record that can be used to encode/decode the truncated
+// uint64 from a given TLV stream.
+func (t *TruncatedUint64) Record() tlv.Record {
+	// We set a type here as zero as it isn't needed when used as a
+	// RecordT.
+	return tlv.MakeDynamicRecord(
+		0, t, func() uint64 {
+			return tlv.SizeTUint64(uint64(*t))
+		}, truncatedUint64Encoder, truncatedUint64Decoder,
+	)
+}
+
+// truncatedUint64Encoder is a custom TLV encoder for the TruncatedUint64
+// record.
+func truncatedUint64Encoder(w io.Writer, val interface{}, buf *[8]byte) error {
+	if v, ok := val.(*TruncatedUint64); ok {
+		return tlv.ETUint64T(w, uint64(*v), buf)
+	}
+
+	return tlv.NewTypeForEncodingErr(val, "lnwire.TruncatedUint64")
+}
+
+// truncatedUint64Decoder is a custom TLV decoder for the TruncatedUint64
+// record. Encodings that aren't minimal are rejected.
+func truncatedUint64Decoder(r io.Reader, val interface{}, buf *[8]byte,
+	l uint64) error {
+
+	if v, ok := val.(*TruncatedUint64); ok {
+		var u uint64
+		if err := tlv.DTUint64(r, &u, buf, l); err != nil {
+			return err
+		}
+
+		*v = TruncatedUint64(u)
+
+		return nil
+	}
+
+	return tlv.NewTypeForDecodingErr(val, "lnwire.TruncatedUint64", l, 8)
+}
@@ -0,0 +1,81 @@
+package lnwire
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/tlv"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTruncatedUint64EncodeDecode tests that truncated uint64 values are
+// encoded with their leading zero bytes trimmed, and that they survive a round
+// trip within a TLV stream.
+func TestTruncatedUint64EncodeDecode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		value   TruncatedUint64
+		encoded []byte
+	}{
+		{
+			name:    "zero",
+			value:   0,
+			encoded: []byte{},
+		},
+		{
+			name:    "partially trimmed",
+			value:   0x0102,
+			encoded: []byte{0x01, 0x02},
+		},
+		{
+			name:  "nothing to trim",
+			value: math.MaxUint64,
+			encoded: []byte{
+				0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+			},
+		},
+	}
+
+	for _, testCase := range tests {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			record := tlv.NewRecordT[tlv.TlvType1](testCase.value)
+
+			var extraData ExtraOpaqueData
+			require.NoError(t, extraData.PackRecords(&record))
+
+			// The record consists of the type, the length and the
+			// trimmed value.
+			expected := append(
+				[]byte{0x01, byte(len(testCase.encoded))},
+				testCase.encoded...,
+			)
+			require.Equal(t, expected, []byte(extraData))
+
+			var decoded tlv.RecordT[tlv.TlvType1, TruncatedUint64]
+			tlvs, err := extraData.ExtractRecords(&decoded)
+			require.NoError(t, err)
+
+			require.Contains(t, tlvs, decoded.TlvType())
+			require.Equal(t, testCase.value, decoded.Val)
+		})
+	}
+}
+
+// TestTruncatedUint64NotMinimal asserts that an encoding with leading zero
+// bytes is rejected.
+func TestTruncatedUint64NotMinimal(t *testing.T) {
+	t.Parallel()
+
+	var value TruncatedUint64
+	record := value.Record()
+
+	err := record.Decode(bytes.NewReader([]byte{0x00, 0x01}), 2)
+	require.ErrorIs(t, err, tlv.ErrTUintNotMinimal)
+}
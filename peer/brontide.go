@@ -25,6 +25,7 @@ import (
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/channeldb/models"
 	"github.com/lightningnetwork/lnd/channelnotifier"
+	"github.com/lightningnetwork/lnd/clock"
 	"github.com/lightningnetwork/lnd/contractcourt"
 	"github.com/lightningnetwork/lnd/discovery"
 	"github.com/lightningnetwork/lnd/feature"
@@ -2420,6 +2421,11 @@ func (p *Brontide) PingTime() int64 {
 	return p.pingManager.GetPingTimeMicroSeconds()
 }
 
+// PingStats returns a snapshot of the ping statistics gathered for the peer.
+func (p *Brontide) PingStats() PingStats {
+	return p.pingManager.Stats()
+}
+
 // queueMsg adds the lnwire.Message to the back of the high priority send queue.
 // If the errChan is non-nil, an error is sent back if the msg failed to queue
 // or failed to write, and nil otherwise.
@@ -3031,6 +3037,7 @@ func (p *Brontide) createChanCloser(channel *lnwallet.LightningChannel,
 			},
 			ChainParams: &p.cfg.Wallet.Cfg.NetParams,
 			Quit:        p.quit,
+			Clock:       clock.NewDefaultClock(),
 		},
 		deliveryScript,
 		fee,
@@ -0,0 +1,257 @@
+package peer
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+const (
+	// DefaultLatencyWindow is the default window within which a ping
+	// failure is considered recent, and outside of which the stats of a
+	// disconnected peer are forgotten.
+	DefaultLatencyWindow = 10 * time.Minute
+
+	// DefaultDegradedP95 is the default node-wide p95 RTT above which
+	// connectivity is considered degraded.
+	DefaultDegradedP95 = 5 * time.Second
+
+	// DefaultDegradedFailureFraction is the default fraction of peers with
+	// a recent ping failure above which connectivity is considered
+	// degraded.
+	DefaultDegradedFailureFraction = 0.25
+
+	// DefaultLatencyHysteresis is the default number of consecutive
+	// evaluations that must agree before the health state flips.
+	DefaultLatencyHysteresis = 3
+)
+
+// PingStats is a snapshot of the ping statistics gathered by a PingManager.
+type PingStats struct {
+	// RTT is the last measured round-trip-time to the peer, if any pong
+	// has been received yet.
+	RTT fn.Option[time.Duration]
+
+	// LastFailure is the time of the last failed ping attempt, if any.
+	LastFailure fn.Option[time.Time]
+}
+
+// LatencySummary is a node-wide rollup of the ping statistics of all peers.
+type LatencySummary struct {
+	// NumPeers is the number of peers the summary was computed over.
+	NumPeers int
+
+	// P50 is the median RTT of all peers with a known RTT.
+	P50 time.Duration
+
+	// P95 is the 95th percentile RTT of all peers with a known RTT.
+	P95 time.Duration
+
+	// FailureFraction is the fraction of peers that had a ping failure
+	// within the evaluation window.
+	FailureFraction float64
+}
+
+// SummarizePingStats computes a LatencySummary over the passed per-peer
+// stats. A failure is counted as recent if it happened within window of now.
+func SummarizePingStats(stats []PingStats, now time.Time,
+	window time.Duration) LatencySummary {
+
+	summary := LatencySummary{
+		NumPeers: len(stats),
+	}
+	if len(stats) == 0 {
+		return summary
+	}
+
+	var (
+		rtts     []time.Duration
+		failures int
+	)
+	for _, s := range stats {
+		s.RTT.WhenSome(func(rtt time.Duration) {
+			rtts = append(rtts, rtt)
+		})
+
+		s.LastFailure.WhenSome(func(t time.Time) {
+			if now.Sub(t) <= window {
+				failures++
+			}
+		})
+	}
+
+	sort.Slice(rtts, func(i, j int) bool {
+		return rtts[i] < rtts[j]
+	})
+
+	summary.P50 = percentile(rtts, 0.50)
+	summary.P95 = percentile(rtts, 0.95)
+	summary.FailureFraction = float64(failures) / float64(len(stats))
+
+	return summary
+}
+
+// percentile returns the nearest-rank percentile p (in the range [0, 1]) of
+// the passed sorted durations, or zero if there are none.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	// Use the nearest-rank method: the smallest value such that at least
+	// p of the samples are less than or equal to it.
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+
+	return sorted[rank-1]
+}
+
+// LatencyHealthConfig houses the parameters that govern when the node-wide
+// connectivity is considered degraded.
+type LatencyHealthConfig struct {
+	// Window is the duration within which a ping failure is considered
+	// recent. The stats of peers that haven't been observed within this
+	// window are dropped.
+	Window time.Duration
+
+	// DegradedP95 is the p95 RTT above which connectivity is considered
+	// degraded.
+	DegradedP95 time.Duration
+
+	// DegradedFailureFraction is the fraction of peers with recent ping
+	// failures above which connectivity is considered degraded.
+	DegradedFailureFraction float64
+
+	// Hysteresis is the number of consecutive evaluations that must
+	// disagree with the current state before it is flipped. This prevents
+	// transient single-peer flaps from toggling the health state.
+	Hysteresis int
+}
+
+// DefaultLatencyHealthConfig returns a LatencyHealthConfig populated with the
+// default values.
+func DefaultLatencyHealthConfig() LatencyHealthConfig {
+	return LatencyHealthConfig{
+		Window:                  DefaultLatencyWindow,
+		DegradedP95:             DefaultDegradedP95,
+		DegradedFailureFraction: DefaultDegradedFailureFraction,
+		Hysteresis:              DefaultLatencyHysteresis,
+	}
+}
+
+// peerObservation is the last set of stats observed for a peer along with the
+// time they were observed.
+type peerObservation struct {
+	stats      PingStats
+	observedAt time.Time
+}
+
+// LatencyHealth aggregates the ping statistics of all peers into a single
+// node-wide connectivity health signal.
+type LatencyHealth struct {
+	cfg LatencyHealthConfig
+
+	mu sync.Mutex
+
+	// peers holds the last observation for each peer. Observations of
+	// disconnected peers are kept until they fall out of the window so
+	// that the failures that caused the disconnection are accounted for.
+	peers map[route.Vertex]peerObservation
+
+	// degraded is the current health state.
+	degraded bool
+
+	// streak is the number of consecutive evaluations that disagreed with
+	// the current health state.
+	streak int
+
+	// last is the summary computed during the last evaluation.
+	last LatencySummary
+}
+
+// NewLatencyHealth creates a new LatencyHealth aggregator.
+func NewLatencyHealth(cfg LatencyHealthConfig) *LatencyHealth {
+	if cfg.Hysteresis < 1 {
+		cfg.Hysteresis = 1
+	}
+
+	return &LatencyHealth{
+		cfg:   cfg,
+		peers: make(map[route.Vertex]peerObservation),
+	}
+}
+
+// Observe records the latest ping stats for the given peer.
+func (l *LatencyHealth) Observe(peer route.Vertex, stats PingStats,
+	now time.Time) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.peers[peer] = peerObservation{
+		stats:      stats,
+		observedAt: now,
+	}
+}
+
+// Evaluate computes a new node-wide summary from the observed stats and
+// updates the health state. The returned boolean is true if connectivity is
+// considered degraded after this evaluation.
+func (l *LatencyHealth) Evaluate(now time.Time) (LatencySummary, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := make([]PingStats, 0, len(l.peers))
+	for peer, obs := range l.peers {
+		if now.Sub(obs.observedAt) > l.cfg.Window {
+			delete(l.peers, peer)
+			continue
+		}
+
+		stats = append(stats, obs.stats)
+	}
+
+	summary := SummarizePingStats(stats, now, l.cfg.Window)
+	l.last = summary
+
+	// An evaluation without any peers carries no latency information, so
+	// we leave the state untouched.
+	if summary.NumPeers == 0 {
+		l.streak = 0
+		return summary, l.degraded
+	}
+
+	degraded := summary.P95 > l.cfg.DegradedP95 ||
+		summary.FailureFraction > l.cfg.DegradedFailureFraction
+
+	if degraded == l.degraded {
+		l.streak = 0
+		return summary, l.degraded
+	}
+
+	l.streak++
+	if l.streak >= l.cfg.Hysteresis {
+		l.degraded = degraded
+		l.streak = 0
+	}
+
+	return summary, l.degraded
+}
+
+// Status returns the summary computed during the last evaluation and whether
+// connectivity is currently considered degraded.
+func (l *LatencyHealth) Status() (LatencySummary, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.last, l.degraded
+}
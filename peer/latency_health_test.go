@@ -0,0 +1,181 @@
+package peer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/fn"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/stretchr/testify/require"
+)
+
+// rttStats returns PingStats with the given RTT.
+func rttStats(rtt time.Duration) PingStats {
+	return PingStats{
+		RTT: fn.Some(rtt),
+	}
+}
+
+// TestSummarizePingStats asserts that the node-wide percentiles and the
+// failure fraction are computed correctly from synthetic per-peer stats.
+func TestSummarizePingStats(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_000_000, 0)
+	window := time.Minute
+
+	testCases := []struct {
+		name     string
+		stats    []PingStats
+		expected LatencySummary
+	}{
+		{
+			name:     "no peers",
+			expected: LatencySummary{},
+		},
+		{
+			name: "single peer",
+			stats: []PingStats{
+				rttStats(100 * time.Millisecond),
+			},
+			expected: LatencySummary{
+				NumPeers: 1,
+				P50:      100 * time.Millisecond,
+				P95:      100 * time.Millisecond,
+			},
+		},
+		{
+			name: "twenty peers",
+			stats: func() []PingStats {
+				// Insert in reverse order to make sure the
+				// samples are sorted.
+				var stats []PingStats
+				for i := 20; i > 0; i-- {
+					stats = append(stats, rttStats(
+						time.Duration(i)*time.Millisecond,
+					))
+				}
+
+				return stats
+			}(),
+			expected: LatencySummary{
+				NumPeers: 20,
+				P50:      10 * time.Millisecond,
+				P95:      19 * time.Millisecond,
+			},
+		},
+		{
+			name: "recent and stale failures",
+			stats: []PingStats{
+				rttStats(10 * time.Millisecond),
+				rttStats(30 * time.Millisecond),
+				{
+					// A peer that never answered a ping
+					// doesn't count towards the RTTs.
+					LastFailure: fn.Some(
+						now.Add(-time.Second),
+					),
+				},
+				{
+					RTT: fn.Some(20 * time.Millisecond),
+					LastFailure: fn.Some(
+						now.Add(-2 * window),
+					),
+				},
+			},
+			expected: LatencySummary{
+				NumPeers:        4,
+				P50:             20 * time.Millisecond,
+				P95:             30 * time.Millisecond,
+				FailureFraction: 0.25,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			summary := SummarizePingStats(tc.stats, now, window)
+			require.Equal(t, tc.expected, summary)
+		})
+	}
+}
+
+// TestLatencyHealthHysteresis asserts that the health state only flips after
+// the configured number of consecutive evaluations disagree with it, and that
+// the observations of peers fall out of the window.
+func TestLatencyHealthHysteresis(t *testing.T) {
+	t.Parallel()
+
+	cfg := LatencyHealthConfig{
+		Window:                  time.Minute,
+		DegradedP95:             time.Second,
+		DegradedFailureFraction: 0.5,
+		Hysteresis:              2,
+	}
+	health := NewLatencyHealth(cfg)
+
+	var (
+		peerA = route.Vertex{1}
+		peerB = route.Vertex{2}
+		now   = time.Unix(1_000_000, 0)
+	)
+
+	// Two healthy peers keep the node healthy.
+	health.Observe(peerA, rttStats(100*time.Millisecond), now)
+	health.Observe(peerB, rttStats(200*time.Millisecond), now)
+	_, degraded := health.Evaluate(now)
+	require.False(t, degraded)
+
+	// A single slow evaluation caused by one flapping peer doesn't flip
+	// the state.
+	health.Observe(peerB, rttStats(3*time.Second), now)
+	summary, degraded := health.Evaluate(now)
+	require.False(t, degraded)
+	require.Equal(t, 3*time.Second, summary.P95)
+
+	// The peer recovers, which resets the streak.
+	health.Observe(peerB, rttStats(200*time.Millisecond), now)
+	_, degraded = health.Evaluate(now)
+	require.False(t, degraded)
+
+	// Two consecutive evaluations with a high failure fraction flip the
+	// state to degraded.
+	failing := PingStats{
+		RTT:         fn.Some(200 * time.Millisecond),
+		LastFailure: fn.Some(now),
+	}
+	health.Observe(peerA, failing, now)
+	health.Observe(peerB, failing, now)
+
+	_, degraded = health.Evaluate(now)
+	require.False(t, degraded)
+
+	summary, degraded = health.Evaluate(now)
+	require.True(t, degraded)
+	require.Equal(t, 1.0, summary.FailureFraction)
+
+	_, degraded = health.Status()
+	require.True(t, degraded)
+
+	// An evaluation without any peers leaves the state untouched, even
+	// once it is repeated.
+	later := now.Add(2 * cfg.Window)
+	for i := 0; i < cfg.Hysteresis; i++ {
+		summary, degraded = health.Evaluate(later)
+		require.True(t, degraded)
+		require.Zero(t, summary.NumPeers)
+	}
+
+	// Once fresh healthy observations arrive, it takes two evaluations to
+	// recover.
+	health.Observe(peerA, rttStats(100*time.Millisecond), later)
+	_, degraded = health.Evaluate(later)
+	require.True(t, degraded)
+
+	_, degraded = health.Evaluate(later)
+	require.False(t, degraded)
+}
@@ -0,0 +1,384 @@
+//go:build soak
+
+package peer
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"math/rand"
+	"net"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// The soak tests in this file flap connections for a long time and are
+// therefore excluded from the default test suite. They are meant to be run
+// with the race detector:
+//
+//	go test -race -tags=soak -run Soak ./peer -soakduration=5m
+var soakDuration = flag.Duration(
+	"soakduration", 30*time.Second, "how long to run the soak tests for",
+)
+
+const (
+	// soakPingInterval is the ping interval used during the soak tests.
+	// It is short so that pings are in flight whenever a connection
+	// flaps.
+	soakPingInterval = 10 * time.Millisecond
+
+	// soakPingTimeout is the ping timeout used during the soak tests.
+	soakPingTimeout = 5 * time.Millisecond
+
+	// maxFlapInterval is the maximum amount of time a connection is up
+	// before it flaps.
+	maxFlapInterval = 300 * time.Millisecond
+)
+
+var errFlap = errors.New("connection flapped")
+
+// lifecycleChecker records callbacks that are executed after the component
+// they belong to was stopped.
+type lifecycleChecker struct {
+	stopped atomic.Bool
+
+	violations atomic.Int32
+}
+
+// check records a violation if the component was already stopped.
+func (l *lifecycleChecker) check(t *testing.T, callback string) {
+	if l.stopped.Load() {
+		l.violations.Add(1)
+		t.Errorf("%s called after stop", callback)
+	}
+}
+
+// leakedGoroutines returns the stacks of all goroutines that are still running
+// code of the peer or its ping manager.
+func leakedGoroutines() []string {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+
+	var leaked []string
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		if strings.Contains(stack, "peer.(*Brontide).") ||
+			strings.Contains(stack, "peer.(*PingManager).") {
+
+			leaked = append(leaked, stack)
+		}
+	}
+
+	return leaked
+}
+
+// assertNoLeakedGoroutines asserts that all goroutines of the peer and its
+// ping manager exit.
+func assertNoLeakedGoroutines(t *testing.T) {
+	t.Helper()
+
+	var leaked []string
+	require.Eventuallyf(t, func() bool {
+		leaked = leakedGoroutines()
+		return len(leaked) == 0
+	}, timeout, 10*time.Millisecond, "leaked goroutines: %v", leaked)
+}
+
+// soakBlockView is a chainntnfs.BestBlockView that always returns the same
+// block, which the peer uses for its ping payloads.
+type soakBlockView struct{}
+
+// BestHeight returns the height of the best block.
+func (soakBlockView) BestHeight() (uint32, error) {
+	return broadcastHeight, nil
+}
+
+// BestBlockHeader returns the header of the best block.
+func (soakBlockView) BestBlockHeader() (*wire.BlockHeader, error) {
+	return &wire.BlockHeader{}, nil
+}
+
+// flappingConn is a MessageConn that plays the remote peer. It answers the
+// init message and pings, and can be closed at any point, after which reads
+// and writes fail like they would on a closed net.Conn.
+type flappingConn struct {
+	// MessageConn embeds our interface so that the mock does not need to
+	// implement every function. The mock will panic if an unspecified
+	// function is called.
+	MessageConn
+
+	readMessages   chan []byte
+	curReadMessage []byte
+
+	// badPongs makes the remote peer reply to some pings with a pong of
+	// the wrong size, and ignore others entirely.
+	badPongs bool
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// newFlappingConn creates a new connection that is up until it is closed.
+func newFlappingConn(badPongs bool) *flappingConn {
+	return &flappingConn{
+		readMessages: make(chan []byte, 16),
+		badPongs:     badPongs,
+		closed:       make(chan struct{}),
+	}
+}
+
+// reply queues a message for the local peer to read. The message is dropped
+// if the connection is closed or the local peer isn't reading.
+func (f *flappingConn) reply(msg lnwire.Message) {
+	var b bytes.Buffer
+	if _, err := lnwire.WriteMessage(&b, msg, 0); err != nil {
+		panic(err)
+	}
+
+	select {
+	case f.readMessages <- b.Bytes():
+	case <-f.closed:
+	default:
+	}
+}
+
+// WriteMessage delivers the message to the remote peer, which answers init
+// messages and pings.
+func (f *flappingConn) WriteMessage(msg []byte) error {
+	select {
+	case <-f.closed:
+		return net.ErrClosed
+	default:
+	}
+
+	nextMsg, err := lnwire.ReadMessage(bytes.NewReader(msg), 0)
+	if err != nil {
+		return err
+	}
+
+	switch m := nextMsg.(type) {
+	case *lnwire.Init:
+		f.reply(lnwire.NewInitMessage(
+			lnwire.NewRawFeatureVector(
+				lnwire.DataLossProtectRequired,
+			),
+			lnwire.NewRawFeatureVector(),
+		))
+
+	case *lnwire.Ping:
+		pongSize := m.NumPongBytes
+		if f.badPongs {
+			switch rand.Intn(10) {
+			case 0:
+				return nil
+			case 1:
+				pongSize++
+			}
+		}
+
+		f.reply(lnwire.NewPong(make([]byte, pongSize)))
+	}
+
+	return nil
+}
+
+// SetWriteDeadline mocks setting the write deadline of the connection.
+func (f *flappingConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// SetReadDeadline mocks setting the read deadline of the connection.
+func (f *flappingConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+// Flush mocks flushing the connection.
+func (f *flappingConn) Flush() (int, error) {
+	return 0, nil
+}
+
+// ReadNextHeader blocks until the remote peer sent a message or the
+// connection is closed.
+func (f *flappingConn) ReadNextHeader() (uint32, error) {
+	select {
+	case f.curReadMessage = <-f.readMessages:
+		return uint32(len(f.curReadMessage)), nil
+
+	case <-f.closed:
+		return 0, net.ErrClosed
+	}
+}
+
+// ReadNextBody returns the message whose header was read last.
+func (f *flappingConn) ReadNextBody(_ []byte) ([]byte, error) {
+	return f.curReadMessage, nil
+}
+
+// RemoteAddr returns the address of the remote peer.
+func (f *flappingConn) RemoteAddr() net.Addr {
+	return nil
+}
+
+// LocalAddr returns our address.
+func (f *flappingConn) LocalAddr() net.Addr {
+	return nil
+}
+
+// Close closes the connection, failing all pending and future reads and
+// writes.
+func (f *flappingConn) Close() error {
+	f.closeOnce.Do(func() {
+		close(f.closed)
+	})
+
+	return nil
+}
+
+// TestPingManagerSoak starts and stops ping managers while pings and pongs are
+// in flight, and asserts that no callback is executed after Stop returns and
+// that no goroutines are leaked.
+func TestPingManagerSoak(t *testing.T) {
+	for start := time.Now(); time.Since(start) < *soakDuration; {
+		var (
+			checker  lifecycleChecker
+			pongSize = uint16(rand.Intn(lnwire.MaxPongBytes))
+			mgr      *PingManager
+		)
+		mgr = NewPingManager(&PingManagerConfig{
+			NewPingPayload: func() []byte {
+				return nil
+			},
+			NewPongSize: func() uint16 {
+				return pongSize
+			},
+			IntervalDuration: soakPingInterval,
+			TimeoutDuration:  soakPingTimeout,
+			SendPing: func(ping *lnwire.Ping) {
+				checker.check(t, "SendPing")
+
+				// Answer the ping from another goroutine,
+				// possibly with a pong of the wrong size.
+				size := ping.NumPongBytes + uint16(rand.Intn(2))
+				go mgr.ReceivedPong(
+					lnwire.NewPong(make([]byte, size)),
+				)
+			},
+			OnPongFailure: func(error) {
+				checker.check(t, "OnPongFailure")
+
+				// Like the peer does, stop the ping manager
+				// from another goroutine.
+				go mgr.Stop()
+			},
+		})
+
+		// Start the ping manager concurrently with unsolicited pongs
+		// and a flap that stops it from several goroutines, possibly
+		// before it was started.
+		var wg sync.WaitGroup
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, mgr.Start())
+		}()
+		go func() {
+			defer wg.Done()
+			mgr.ReceivedPong(lnwire.NewPong(nil))
+		}()
+		go func() {
+			defer wg.Done()
+
+			time.Sleep(time.Duration(
+				rand.Int63n(int64(soakPingInterval * 5)),
+			))
+			mgr.Stop()
+		}()
+
+		mgr.Stop()
+		checker.stopped.Store(true)
+		wg.Wait()
+
+		// Give callbacks that are still racing with Stop a chance to
+		// run, so that they're caught by the checker.
+		time.Sleep(soakPingInterval)
+		require.Zero(t, checker.violations.Load())
+	}
+
+	assertNoLeakedGoroutines(t)
+}
+
+// TestPeerLifecycleSoak connects and disconnects a peer over and over, with
+// connections that flap every few hundred milliseconds while pings are in
+// flight. It asserts that the ping manager never sends pings or reports
+// failures once the peer has disconnected, and that the peer's goroutines
+// exit after every flap.
+func TestPeerLifecycleSoak(t *testing.T) {
+	params := createTestPeer(t)
+	baseCfg := params.peer.cfg
+	baseCfg.BestBlockView = soakBlockView{}
+
+	for start := time.Now(); time.Since(start) < *soakDuration; {
+		conn := newFlappingConn(rand.Intn(2) == 0)
+
+		cfg := baseCfg
+		cfg.Conn = conn
+		p := NewBrontide(cfg)
+
+		// Replace the peer's ping manager with one that pings a lot
+		// more often, keeping the peer's callbacks.
+		var checker lifecycleChecker
+		pingCfg := *p.pingManager.cfg
+		pingCfg.IntervalDuration = soakPingInterval
+		pingCfg.TimeoutDuration = soakPingTimeout
+		sendPing := pingCfg.SendPing
+		onPongFailure := pingCfg.OnPongFailure
+		pingCfg.SendPing = func(ping *lnwire.Ping) {
+			checker.check(t, "SendPing")
+			sendPing(ping)
+		}
+		pingCfg.OnPongFailure = func(err error) {
+			checker.check(t, "OnPongFailure")
+			onPongFailure(err)
+		}
+		p.pingManager = NewPingManager(&pingCfg)
+
+		startErr := make(chan error, 1)
+		go func() {
+			startErr <- p.Start()
+		}()
+
+		// Flap the connection, either by the remote peer closing it or
+		// by us disconnecting, possibly before the peer was started.
+		time.Sleep(time.Duration(rand.Int63n(int64(maxFlapInterval))))
+		if rand.Intn(2) == 0 {
+			require.NoError(t, conn.Close())
+		} else {
+			p.Disconnect(errFlap)
+		}
+
+		select {
+		case <-startErr:
+		case <-time.After(timeout):
+			t.Fatalf("peer start didn't return")
+		}
+
+		// A peer that failed to start is disconnected by the server,
+		// so we disconnect in any case.
+		p.Disconnect(errFlap)
+
+		ready := make(chan struct{})
+		close(ready)
+		p.WaitForDisconnect(ready)
+		checker.stopped.Store(true)
+
+		assertNoLeakedGoroutines(t)
+		require.Zero(t, checker.violations.Load())
+	}
+}
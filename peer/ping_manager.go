@@ -3,13 +3,20 @@ package peer
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/lightningnetwork/lnd/fn"
 	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/queue"
 )
 
+// numRTTSamples is the number of most recent RTT samples the PingManager keeps
+// around to compute percentiles over.
+const numRTTSamples = 32
+
 // PingManagerConfig is a structure containing various parameters that govern
 // how the PingManager behaves.
 type PingManagerConfig struct {
@@ -35,8 +42,42 @@ type PingManagerConfig struct {
 
 	// OnPongFailure is a closure that is responsible for executing the
 	// logic when a Pong message is either late or does not match our
-	// expectations for that Pong
+	// expectations for that Pong. The error passed to it is a
+	// *PingFailure. The PingManager keeps pinging the peer after a
+	// failure, so the closure needs to stop it if the connection should
+	// be torn down.
 	OnPongFailure func(error)
+
+	// OnPongSuccess is an optional closure that is executed when a Pong
+	// message matching our Ping is received in time, with the measured
+	// RTT.
+	OnPongSuccess func(rtt time.Duration)
+}
+
+// PingFailure is the error that is handed to OnPongFailure when a ping attempt
+// fails. Next to the reason of the failure, it carries the context needed to
+// score the connection to the peer.
+type PingFailure struct {
+	// Err is the reason the ping attempt failed.
+	Err error
+
+	// NumFailures is the number of consecutive ping attempts that failed,
+	// including this one. It is reset whenever a pong is received in time
+	// or ResetFailures is called.
+	NumFailures uint32
+
+	// LastRTT is the RTT of the last successful ping attempt, if any.
+	LastRTT fn.Option[time.Duration]
+}
+
+// Error returns the reason the ping attempt failed.
+func (p *PingFailure) Error() string {
+	return p.Err.Error()
+}
+
+// Unwrap returns the reason the ping attempt failed.
+func (p *PingFailure) Unwrap() error {
+	return p.Err
 }
 
 // PingManager is a structure that is designed to manage the internal state
@@ -53,6 +94,19 @@ type PingManager struct {
 	// TODO(roasbeef): also use a WMA or EMA?
 	pingTime atomic.Pointer[time.Duration]
 
+	// rttSamples holds the most recent RTT samples, which are used to
+	// compute RTT percentiles.
+	rttSamples *queue.CircularBuffer
+	rttMtx     sync.Mutex
+
+	// lastFailure is the time at which the last ping attempt failed,
+	// either by timing out or by receiving a mismatched pong.
+	// To be used atomically.
+	lastFailure atomic.Pointer[time.Time]
+
+	// numFailures is the number of consecutive failed ping attempts.
+	numFailures atomic.Uint32
+
 	// pingLastSend is the time when we sent our last ping message.
 	// To be used atomically.
 	pingLastSend *time.Time
@@ -84,8 +138,12 @@ type PingManager struct {
 // NewPingManager constructs a pingManager in a valid state. It must be started
 // before it does anything useful, though.
 func NewPingManager(cfg *PingManagerConfig) *PingManager {
+	// The buffer size is a positive constant, so this can't fail.
+	rttSamples, _ := queue.NewCircularBuffer(numRTTSamples)
+
 	m := PingManager{
 		cfg:                 cfg,
+		rttSamples:          rttSamples,
 		outstandingPongSize: -1,
 		pongChan:            make(chan *lnwire.Pong, 1),
 		quit:                make(chan struct{}),
@@ -112,6 +170,7 @@ func (m *PingManager) Start() error {
 // protocol.
 func (m *PingManager) pingHandler() {
 	defer m.wg.Done()
+	defer m.pingTicker.Stop()
 	defer m.pingTimeout.Stop()
 
 	// Ensure that the pingTimeout channel is empty.
@@ -130,7 +189,7 @@ func (m *PingManager) pingHandler() {
 				e := errors.New("impossible: new ping" +
 					"in unclean state",
 				)
-				m.cfg.OnPongFailure(e)
+				m.fail(e)
 
 				return
 			}
@@ -143,7 +202,7 @@ func (m *PingManager) pingHandler() {
 
 			// Set up our bookkeeping for the new Ping.
 			if err := m.setPingState(pongSize); err != nil {
-				m.cfg.OnPongFailure(err)
+				m.fail(err)
 
 				return
 			}
@@ -157,9 +216,7 @@ func (m *PingManager) pingHandler() {
 				"pong response",
 			)
 
-			m.cfg.OnPongFailure(e)
-
-			return
+			m.fail(e)
 
 		case pong := <-m.pongChan:
 			pongSize := int32(len(pong.PongBytes))
@@ -178,16 +235,24 @@ func (m *PingManager) pingHandler() {
 					"not match expected size",
 				)
 
-				m.cfg.OnPongFailure(e)
+				m.fail(e)
 
-				return
+				continue
 			}
 
+			// The peer recovered, so any further failure starts
+			// a new streak.
+			m.ResetFailures()
+
 			// Compute RTT of ping and save that for future
 			// querying.
 			if lastPing != nil {
 				rtt := time.Since(*lastPing)
-				m.pingTime.Store(&rtt)
+				m.addRTTSample(rtt)
+
+				if m.cfg.OnPongSuccess != nil {
+					m.cfg.OnPongSuccess(rtt)
+				}
 			}
 
 		case <-m.quit:
@@ -196,21 +261,45 @@ func (m *PingManager) pingHandler() {
 	}
 }
 
-// Stop interrupts the goroutines that the PingManager owns.
+// Stop interrupts the goroutines that the PingManager owns. Once Stop
+// returns, none of the PingManager's callbacks will be executed anymore. It is
+// safe to call Stop concurrently with Start, or before the PingManager was
+// started, in which case it can't be started anymore.
 func (m *PingManager) Stop() {
-	if m.pingTicker == nil {
-		return
-	}
-
 	m.stopped.Do(func() {
+		// Wait for a concurrent call to Start to finish launching the
+		// ping handler, or prevent it from being launched at all.
+		m.started.Do(func() {})
+
 		close(m.quit)
 		m.wg.Wait()
-
-		m.pingTicker.Stop()
-		m.pingTimeout.Stop()
 	})
 }
 
+// fail records the time of a failed ping attempt and hands the error off to
+// the OnPongFailure closure, wrapped in a *PingFailure.
+func (m *PingManager) fail(err error) {
+	now := time.Now()
+	m.lastFailure.Store(&now)
+
+	failure := &PingFailure{
+		Err:         err,
+		NumFailures: m.numFailures.Add(1),
+	}
+	if rtt := m.pingTime.Load(); rtt != nil {
+		failure.LastRTT = fn.Some(*rtt)
+	}
+
+	m.cfg.OnPongFailure(failure)
+}
+
+// ResetFailures resets the number of consecutive failed ping attempts, so
+// that the next failure is counted as the first one again. It is called
+// automatically whenever a pong is received in time.
+func (m *PingManager) ResetFailures() {
+	m.numFailures.Store(0)
+}
+
 // setPingState is a private method to keep track of all of the fields we need
 // to set when we send out a Ping.
 func (m *PingManager) setPingState(pongSize uint16) error {
@@ -250,6 +339,48 @@ func (m *PingManager) GetPingTimeMicroSeconds() int64 {
 	return rtt.Microseconds()
 }
 
+// addRTTSample records a newly measured RTT.
+func (m *PingManager) addRTTSample(rtt time.Duration) {
+	m.pingTime.Store(&rtt)
+
+	m.rttMtx.Lock()
+	m.rttSamples.Add(rtt)
+	m.rttMtx.Unlock()
+}
+
+// RTTPercentile returns the nearest-rank percentile p, in the range [0, 1],
+// of the most recent RTT samples. Zero is returned if no pong has been
+// received yet.
+func (m *PingManager) RTTPercentile(p float64) time.Duration {
+	m.rttMtx.Lock()
+	items := m.rttSamples.List()
+	m.rttMtx.Unlock()
+
+	samples := make([]time.Duration, 0, len(items))
+	for _, item := range items {
+		samples = append(samples, item.(time.Duration))
+	}
+
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i] < samples[j]
+	})
+
+	return percentile(samples, p)
+}
+
+// Stats returns a snapshot of the ping statistics gathered for the peer.
+func (m *PingManager) Stats() PingStats {
+	var stats PingStats
+	if rtt := m.pingTime.Load(); rtt != nil {
+		stats.RTT = fn.Some(*rtt)
+	}
+	if lastFailure := m.lastFailure.Load(); lastFailure != nil {
+		stats.LastFailure = fn.Some(*lastFailure)
+	}
+
+	return stats
+}
+
 // ReceivedPong is called to evaluate a Pong message against the expectations
 // we have for it. It will cause the PingManager to invoke the supplied
 // OnPongFailure function if the Pong argument supplied violates expectations.
@@ -1,9 +1,11 @@
 package peer
 
 import (
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/lightningnetwork/lnd/fn"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/stretchr/testify/require"
 )
@@ -44,7 +46,9 @@ func TestPingManager(t *testing.T) {
 
 	payload := make([]byte, 4)
 	for _, test := range testCases {
-		// Set up PingManager.
+		// Set up PingManager. It keeps pinging after a failure, so we
+		// only signal the first ping and failure.
+		var pingOnce, failOnce sync.Once
 		pingSent := make(chan struct{})
 		disconnected := make(chan struct{})
 		mgr := NewPingManager(&PingManagerConfig{
@@ -57,10 +61,14 @@ func TestPingManager(t *testing.T) {
 			IntervalDuration: time.Second * 2,
 			TimeoutDuration:  time.Second,
 			SendPing: func(ping *lnwire.Ping) {
-				close(pingSent)
+				pingOnce.Do(func() {
+					close(pingSent)
+				})
 			},
 			OnPongFailure: func(err error) {
-				close(disconnected)
+				failOnce.Do(func() {
+					close(disconnected)
+				})
 			},
 		})
 		require.NoError(t, mgr.Start(), "Could not start pingManager")
@@ -86,3 +94,163 @@ func TestPingManager(t *testing.T) {
 		mgr.Stop()
 	}
 }
+
+// TestPingManagerCallbacks asserts that the success and failure callbacks are
+// executed for a mixed sequence of successful and failed ping attempts, and
+// that they carry the RTT and failure count needed to score the peer.
+func TestPingManagerCallbacks(t *testing.T) {
+	t.Parallel()
+
+	const pongSize = 4
+
+	var (
+		pings     = make(chan *lnwire.Ping, 1)
+		successes = make(chan time.Duration, 1)
+		failures  = make(chan error, 1)
+	)
+	mgr := NewPingManager(&PingManagerConfig{
+		NewPingPayload: func() []byte {
+			return nil
+		},
+		NewPongSize: func() uint16 {
+			return pongSize
+		},
+		IntervalDuration: 100 * time.Millisecond,
+		TimeoutDuration:  50 * time.Millisecond,
+		SendPing: func(ping *lnwire.Ping) {
+			pings <- ping
+		},
+		OnPongSuccess: func(rtt time.Duration) {
+			successes <- rtt
+		},
+		OnPongFailure: func(err error) {
+			failures <- err
+		},
+	})
+	require.NoError(t, mgr.Start())
+	t.Cleanup(mgr.Stop)
+
+	// nextPing waits for the next ping to be sent.
+	nextPing := func() {
+		t.Helper()
+
+		select {
+		case <-pings:
+		case <-time.After(time.Second):
+			t.Fatalf("no ping sent")
+		}
+	}
+
+	// pongSuccess answers the next ping and asserts that the success
+	// callback is executed with the measured RTT.
+	var lastRTT time.Duration
+	pongSuccess := func() {
+		t.Helper()
+
+		nextPing()
+		mgr.ReceivedPong(lnwire.NewPong(make([]byte, pongSize)))
+
+		select {
+		case rtt := <-successes:
+			require.Positive(t, rtt)
+			require.EqualValues(
+				t, rtt.Microseconds(),
+				mgr.GetPingTimeMicroSeconds(),
+			)
+			lastRTT = rtt
+
+		case err := <-failures:
+			t.Fatalf("unexpected failure: %v", err)
+
+		case <-time.After(time.Second):
+			t.Fatalf("success callback not executed")
+		}
+	}
+
+	// pongFailure asserts that the failure callback is executed with the
+	// failure count and the RTT of the last successful ping.
+	pongFailure := func(numFailures uint32) {
+		t.Helper()
+
+		select {
+		case err := <-failures:
+			var failure *PingFailure
+			require.ErrorAs(t, err, &failure)
+			require.Equal(t, numFailures, failure.NumFailures)
+			require.Equal(t, fn.Some(lastRTT), failure.LastRTT)
+
+		case rtt := <-successes:
+			t.Fatalf("unexpected success with rtt %v", rtt)
+
+		case <-time.After(time.Second):
+			t.Fatalf("failure callback not executed")
+		}
+	}
+
+	pongSuccess()
+	pongSuccess()
+
+	// A pong of the wrong size fails the ping attempt.
+	nextPing()
+	mgr.ReceivedPong(lnwire.NewPong(make([]byte, pongSize+1)))
+	pongFailure(1)
+
+	// The ping manager keeps pinging after a failure.
+	pongSuccess()
+
+	// Not answering a ping at all results in a timeout. As the previous
+	// ping succeeded, the failure count starts fresh.
+	nextPing()
+	pongFailure(1)
+
+	// Consecutive failures are counted until a ping succeeds again.
+	nextPing()
+	pongFailure(2)
+
+	pongSuccess()
+
+	nextPing()
+	pongFailure(1)
+
+	// The failure count can also be reset explicitly.
+	mgr.ResetFailures()
+
+	nextPing()
+	pongFailure(1)
+
+	pongSuccess()
+}
+
+// TestPingManagerRTTPercentile asserts that RTT percentiles are computed over
+// the most recent samples only.
+func TestPingManagerRTTPercentile(t *testing.T) {
+	t.Parallel()
+
+	mgr := NewPingManager(&PingManagerConfig{})
+
+	// Without any samples, all percentiles are zero.
+	require.Zero(t, mgr.RTTPercentile(0.5))
+
+	// Feed the samples 1ms to 20ms in reverse order, to make sure they
+	// are sorted before computing the percentiles.
+	for i := 20; i > 0; i-- {
+		mgr.addRTTSample(time.Duration(i) * time.Millisecond)
+	}
+
+	require.Equal(t, time.Millisecond, mgr.RTTPercentile(0))
+	require.Equal(t, 10*time.Millisecond, mgr.RTTPercentile(0.5))
+	require.Equal(t, 19*time.Millisecond, mgr.RTTPercentile(0.95))
+	require.Equal(t, 20*time.Millisecond, mgr.RTTPercentile(1))
+
+	// The last sample is also reported as the current ping time.
+	require.EqualValues(t, 1000, mgr.GetPingTimeMicroSeconds())
+
+	// Once the buffer is full, the oldest samples are evicted. Adding a
+	// full buffer of 100ms samples pushes all the previous ones out.
+	for i := 0; i < numRTTSamples; i++ {
+		mgr.addRTTSample(100 * time.Millisecond)
+	}
+
+	require.Equal(t, 100*time.Millisecond, mgr.RTTPercentile(0))
+	require.Equal(t, 100*time.Millisecond, mgr.RTTPercentile(0.95))
+}
@@ -224,7 +224,7 @@ func initAutoPilot(svr *server, cfg *lncfg.AutoPilot,
 				}
 
 				err := svr.ConnectToPeer(
-					lnAddr, false, svr.cfg.ConnectionTimeout,
+					lnAddr, false, svr.dialTimeout(lnAddr),
 				)
 				if err != nil {
 					// If we weren't able to connect to the
@@ -3,13 +3,74 @@ package record
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
+	"math/bits"
+	"sort"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/tlv"
 )
 
+const (
+	// feeRateParts is the total number of parts used to express fee rates.
+	feeRateParts = 1_000_000
+)
+
+var (
+	// ErrFeeOverflow is returned when the fee for a payment amount can't
+	// be represented.
+	ErrFeeOverflow = errors.New("fee overflows")
+
+	// ErrEmptyBlindedPath is returned when decoding a blinded path that
+	// has no hops.
+	ErrEmptyBlindedPath = errors.New("blinded path has no hops")
+
+	// ErrMissingPathID is returned when the final hop of a blinded path
+	// has no path_id.
+	ErrMissingPathID = errors.New("final hop has no path_id")
+
+	// ErrUnexpectedPathID is returned when a hop other than the final hop
+	// of a blinded path has a path_id, which means that the hops are out
+	// of order.
+	ErrUnexpectedPathID = errors.New("path_id set before final hop")
+
+	// ErrMaxCltvNotAccumulated is returned when the max cltv expiry of a
+	// hop in a blinded path doesn't cover the max cltv expiry of the next
+	// hop plus its own cltv expiry delta.
+	ErrMaxCltvNotAccumulated = errors.New("max cltv expiry doesn't " +
+		"accumulate cltv expiry delta")
+
+	// ErrUnknownEvenType is returned when strictly decoding blinded route
+	// data that contains a record of an unknown even type.
+	ErrUnknownEvenType = errors.New("unknown even type in blinded route " +
+		"data")
+
+	// ErrInvalidConstraints is returned when strictly decoding blinded
+	// route data with payment constraints that can't be satisfied by any
+	// payment.
+	ErrInvalidConstraints = errors.New("invalid payment constraints")
+)
+
+// BlindedDataDecodeOpts controls how strictly the encrypted data of a blinded
+// hop is validated when decoding it. The zero value is the most lenient.
+type BlindedDataDecodeOpts struct {
+	// StrictOrdering rejects data whose records aren't sorted by type. If
+	// it isn't set, the records are sorted before they're decoded.
+	// Duplicate records are rejected either way.
+	StrictOrdering bool
+
+	// RejectUnknownEven rejects data that contains a record of an unknown
+	// even type, which the spec requires readers to understand.
+	RejectUnknownEven bool
+
+	// CheckConstraints rejects data whose payment constraints have a max
+	// cltv expiry that doesn't cover the hop's own cltv expiry delta.
+	CheckConstraints bool
+}
+
 // BlindedRouteData contains the information that is included in a blinded
 // route encrypted data blob that is created by the recipient to provide
 // forwarding information.
@@ -17,6 +78,11 @@ type BlindedRouteData struct {
 	// ShortChannelID is the channel ID of the next hop.
 	ShortChannelID tlv.RecordT[tlv.TlvType2, lnwire.ShortChannelID]
 
+	// PathID is set by the recipient in the data of the final hop of a
+	// blinded path, so that it can recognize payments made over the paths
+	// it created.
+	PathID tlv.OptionalRecordT[tlv.TlvType6, []byte]
+
 	// NextBlindingOverride is a blinding point that should be switched
 	// in for the next hop. This is used to combine two blinded paths into
 	// one (which primarily is used in onion messaging, but in theory
@@ -65,10 +131,22 @@ func NewBlindedRouteData(chanID lnwire.ShortChannelID,
 }
 
 // DecodeBlindedRouteData decodes the data provided within a blinded route.
+// Records that aren't sorted by type are rejected.
 func DecodeBlindedRouteData(r io.Reader) (*BlindedRouteData, error) {
+	return DecodeBlindedRouteDataWithOpts(r, BlindedDataDecodeOpts{
+		StrictOrdering: true,
+	})
+}
+
+// DecodeBlindedRouteDataWithOpts decodes the data provided within a blinded
+// route, validating it as strictly as the passed options require.
+func DecodeBlindedRouteDataWithOpts(r io.Reader,
+	opts BlindedDataDecodeOpts) (*BlindedRouteData, error) {
+
 	var (
 		d BlindedRouteData
 
+		pathID           = d.PathID.Zero()
 		blindingOverride = d.NextBlindingOverride.Zero()
 		constraints      = d.Constraints.Zero()
 		features         = d.Features.Zero()
@@ -79,8 +157,14 @@ func DecodeBlindedRouteData(r io.Reader) (*BlindedRouteData, error) {
 		return nil, err
 	}
 
-	typeMap, err := tlvRecords.ExtractRecords(
-		&d.ShortChannelID,
+	if !opts.StrictOrdering {
+		tlvRecords = sortRecords(tlvRecords)
+	}
+
+	// The encrypted data isn't part of a wire message, so there's no
+	// message type to report if the records can't be decoded.
+	typeMap, err := tlvRecords.ExtractRecordsDetailed(
+		0, &d.ShortChannelID, &pathID,
 		&blindingOverride, &d.RelayInfo.Val, &constraints,
 		&features,
 	)
@@ -88,6 +172,10 @@ func DecodeBlindedRouteData(r io.Reader) (*BlindedRouteData, error) {
 		return nil, err
 	}
 
+	if val, ok := typeMap[d.PathID.TlvType()]; ok && val == nil {
+		d.PathID = tlv.SomeRecordT(pathID)
+	}
+
 	val, ok := typeMap[d.NextBlindingOverride.TlvType()]
 	if ok && val == nil {
 		d.NextBlindingOverride = tlv.SomeRecordT(blindingOverride)
@@ -101,43 +189,199 @@ func DecodeBlindedRouteData(r io.Reader) (*BlindedRouteData, error) {
 		d.Features = tlv.SomeRecordT(features)
 	}
 
+	if opts.RejectUnknownEven {
+		for tlvType, val := range typeMap {
+			// Known records are stored with a nil value.
+			if val != nil && tlvType%2 == 0 {
+				return nil, fmt.Errorf("%w: %d",
+					ErrUnknownEvenType, tlvType)
+			}
+		}
+	}
+
+	if opts.CheckConstraints && d.Constraints.IsSome() {
+		maxCltv := d.Constraints.UnwrapOr(
+			d.Constraints.Zero(),
+		).Val.MaxCltvExpiry
+		delta := d.RelayInfo.Val.CltvExpiryDelta
+
+		if maxCltv < uint32(delta) {
+			return nil, fmt.Errorf("%w: max cltv expiry %d below "+
+				"cltv expiry delta %d", ErrInvalidConstraints,
+				maxCltv, delta)
+		}
+	}
+
 	return &d, nil
 }
 
-// EncodeBlindedRouteData encodes the blinded route data provided.
-func EncodeBlindedRouteData(data *BlindedRouteData) ([]byte, error) {
+// sortRecords returns the records of the given TLV stream sorted by type. The
+// relative order of records of the same type is kept, so that the decoder
+// still rejects them as duplicates. If the stream can't be split into records,
+// it is returned as-is so that the decoder reports the error.
+func sortRecords(stream []byte) []byte {
+	type rawRecord struct {
+		tlvType uint64
+		bytes   []byte
+	}
+
 	var (
-		e               lnwire.ExtraOpaqueData
-		recordProducers = make([]tlv.RecordProducer, 0, 5)
+		r       = bytes.NewReader(stream)
+		buf     [8]byte
+		records []rawRecord
 	)
+	for r.Len() > 0 {
+		start := len(stream) - r.Len()
+
+		t, err := tlv.ReadVarInt(r, &buf)
+		if err != nil {
+			return stream
+		}
+
+		length, err := tlv.ReadVarInt(r, &buf)
+		if err != nil || length > uint64(r.Len()) {
+			return stream
+		}
+
+		end := len(stream) - r.Len() + int(length)
+		records = append(records, rawRecord{
+			tlvType: t,
+			bytes:   stream[start:end],
+		})
+
+		if _, err := r.Seek(int64(length), io.SeekCurrent); err != nil {
+			return stream
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].tlvType < records[j].tlvType
+	})
+
+	sorted := make([]byte, 0, len(stream))
+	for _, record := range records {
+		sorted = append(sorted, record.bytes...)
+	}
+
+	return sorted
+}
+
+// DecodeBlindedPath decodes the encrypted recipient data of all the hops of a
+// blinded path, ordered from the introduction node to the recipient. Only the
+// final hop may carry a path_id, and it must do so. For every pair of
+// consecutive hops that both set payment constraints, the max cltv expiry of
+// the first hop must be at least that of the next hop plus the first hop's
+// cltv expiry delta, as the recipient accumulates the deltas along the path.
+func DecodeBlindedPath(blobs [][]byte) ([]*BlindedRouteData, error) {
+	if len(blobs) == 0 {
+		return nil, ErrEmptyBlindedPath
+	}
+
+	hops := make([]*BlindedRouteData, len(blobs))
+	for i, blob := range blobs {
+		hop, err := DecodeBlindedRouteData(bytes.NewReader(blob))
+		if err != nil {
+			return nil, fmt.Errorf("hop %d: %w", i, err)
+		}
+
+		isFinal := i == len(blobs)-1
+		hasPathID := hop.PathID.IsSome()
+		switch {
+		case isFinal && !hasPathID:
+			return nil, fmt.Errorf("hop %d: %w", i,
+				ErrMissingPathID)
+
+		case !isFinal && hasPathID:
+			return nil, fmt.Errorf("hop %d: %w", i,
+				ErrUnexpectedPathID)
+		}
 
-	recordProducers = append(recordProducers, &data.ShortChannelID)
+		hops[i] = hop
+	}
+
+	for i := 0; i < len(hops)-1; i++ {
+		hop, next := hops[i], hops[i+1]
+		if hop.Constraints.IsNone() || next.Constraints.IsNone() {
+			continue
+		}
+
+		maxCltv := hop.Constraints.UnwrapOr(
+			hop.Constraints.Zero(),
+		).Val.MaxCltvExpiry
+		nextMaxCltv := next.Constraints.UnwrapOr(
+			next.Constraints.Zero(),
+		).Val.MaxCltvExpiry
+		delta := hop.RelayInfo.Val.CltvExpiryDelta
+
+		// We compute the sum as an uint64 so that it can't overflow.
+		if uint64(maxCltv) < uint64(nextMaxCltv)+uint64(delta) {
+			return nil, fmt.Errorf("hop %d: %w: max cltv expiry "+
+				"%d, next hop max cltv expiry %d, cltv expiry "+
+				"delta %d", i, ErrMaxCltvNotAccumulated,
+				maxCltv, nextMaxCltv, delta)
+		}
+	}
+
+	return hops, nil
+}
+
+// EncodeBlindedRouteData encodes the blinded route data provided.
+func EncodeBlindedRouteData(data *BlindedRouteData) ([]byte, error) {
+	var e lnwire.ExtraOpaqueData
+	if err := e.PackRecords(data.recordProducers()...); err != nil {
+		return nil, err
+	}
+
+	return e[:], nil
+}
+
+// EncodedLen returns the length of the encoded blinded route data, without
+// encoding it.
+func (b *BlindedRouteData) EncodedLen() int {
+	var length uint64
+	for _, producer := range b.recordProducers() {
+		record := producer.Record()
+		size := record.Size()
+
+		length += tlv.VarIntSize(uint64(record.Type())) +
+			tlv.VarIntSize(size) + size
+	}
+
+	return int(length)
+}
+
+// recordProducers returns the record producers for all the records that are
+// set in the blinded route data, in the order they are encoded in.
+func (b *BlindedRouteData) recordProducers() []tlv.RecordProducer {
+	recordProducers := make([]tlv.RecordProducer, 0, 6)
+
+	recordProducers = append(recordProducers, &b.ShortChannelID)
+
+	b.PathID.WhenSome(func(id tlv.RecordT[tlv.TlvType6, []byte]) {
+		recordProducers = append(recordProducers, &id)
+	})
 
-	data.NextBlindingOverride.WhenSome(func(pk tlv.RecordT[tlv.TlvType8,
+	b.NextBlindingOverride.WhenSome(func(pk tlv.RecordT[tlv.TlvType8,
 		*btcec.PublicKey]) {
 
 		recordProducers = append(recordProducers, &pk)
 	})
 
-	recordProducers = append(recordProducers, &data.RelayInfo.Val)
+	recordProducers = append(recordProducers, &b.RelayInfo.Val)
 
-	data.Constraints.WhenSome(func(cs tlv.RecordT[tlv.TlvType12,
+	b.Constraints.WhenSome(func(cs tlv.RecordT[tlv.TlvType12,
 		PaymentConstraints]) {
 
 		recordProducers = append(recordProducers, &cs)
 	})
 
-	data.Features.WhenSome(func(f tlv.RecordT[tlv.TlvType14,
+	b.Features.WhenSome(func(f tlv.RecordT[tlv.TlvType14,
 		lnwire.FeatureVector]) {
 
 		recordProducers = append(recordProducers, &f)
 	})
 
-	if err := e.PackRecords(recordProducers...); err != nil {
-		return nil, err
-	}
-
-	return e[:], nil
+	return recordProducers
 }
 
 // PaymentRelayInfo describes the relay policy for a blinded path.
@@ -153,6 +397,68 @@ type PaymentRelayInfo struct {
 	BaseFee uint32
 }
 
+// ComputeFee returns the fee that the hop charges for forwarding the given
+// amount. The proportional part of the fee is rounded up, so that the result
+// is never less than what the forwarding node expects. An error is returned
+// if the fee overflows.
+func (i *PaymentRelayInfo) ComputeFee(
+	amt lnwire.MilliSatoshi) (lnwire.MilliSatoshi, error) {
+
+	hi, lo := bits.Mul64(uint64(amt), uint64(i.FeeRate))
+	if hi != 0 {
+		return 0, ErrFeeOverflow
+	}
+
+	// Round up the proportional fee, taking care not to overflow while
+	// doing so.
+	propFee := lo / feeRateParts
+	if lo%feeRateParts != 0 {
+		propFee++
+	}
+
+	fee, carry := bits.Add64(propFee, uint64(i.BaseFee), 0)
+	if carry != 0 {
+		return 0, ErrFeeOverflow
+	}
+
+	return lnwire.MilliSatoshi(fee), nil
+}
+
+// SamePolicy returns true if the other relay info advertises the same
+// forwarding policy, meaning that it charges the same fees and requires the
+// same cltv expiry delta.
+func (i PaymentRelayInfo) SamePolicy(other PaymentRelayInfo) bool {
+	return i.FeeRate == other.FeeRate &&
+		i.BaseFee == other.BaseFee &&
+		i.CltvExpiryDelta == other.CltvExpiryDelta
+}
+
+// PathIncomingAmount computes the amount that must enter a path made up of
+// the hops with the given relay info, in order for amt to arrive at the final
+// hop. The relay info must be ordered from the first hop of the path to the
+// last forwarding hop. Fees are accumulated in reverse, as each hop charges
+// its fee on the amount it forwards, which includes the fees of all the hops
+// that follow it.
+func PathIncomingAmount(relayInfo []PaymentRelayInfo,
+	amt lnwire.MilliSatoshi) (lnwire.MilliSatoshi, error) {
+
+	for i := len(relayInfo) - 1; i >= 0; i-- {
+		fee, err := relayInfo[i].ComputeFee(amt)
+		if err != nil {
+			return 0, fmt.Errorf("hop %d: %w", i, err)
+		}
+
+		total, carry := bits.Add64(uint64(amt), uint64(fee), 0)
+		if carry != 0 {
+			return 0, fmt.Errorf("hop %d: %w", i, ErrFeeOverflow)
+		}
+
+		amt = lnwire.MilliSatoshi(total)
+	}
+
+	return amt, nil
+}
+
 // newPaymentRelayRecord creates a tlv.Record that encodes the payment relay
 // (type 10) type for an encrypted blob payload.
 func (i *PaymentRelayInfo) Record() tlv.Record {
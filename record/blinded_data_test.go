@@ -9,6 +9,7 @@ import (
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/tlv"
 	"github.com/stretchr/testify/require"
 )
 
@@ -109,6 +110,9 @@ func TestBlindedDataEncoding(t *testing.T) {
 			encoded, err := EncodeBlindedRouteData(encodedData)
 			require.NoError(t, err)
 
+			// The length hint must match the actual encoding.
+			require.Equal(t, len(encoded), encodedData.EncodedLen())
+
 			b := bytes.NewBuffer(encoded)
 			decodedData, err := DecodeBlindedRouteData(b)
 			require.NoError(t, err)
@@ -195,3 +199,431 @@ func TestBlindingSpecTestVectors(t *testing.T) {
 		})
 	}
 }
+
+// TestPathIncomingAmount tests computing the amount that must enter a path of
+// blinded hops for a given destination amount.
+func TestPathIncomingAmount(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		relayInfo []PaymentRelayInfo
+		amt       lnwire.MilliSatoshi
+		expected  lnwire.MilliSatoshi
+		err       error
+	}{
+		{
+			name:     "no hops",
+			amt:      1_000_000,
+			expected: 1_000_000,
+		},
+		{
+			// The last hop charges 500 + 1_000_000 * 1000 / 1e6 =
+			// 1500 msat, so it needs to receive 1_001_500 msat.
+			// The first hop then charges 1000 + 1_001_500 * 100 /
+			// 1e6 = 1100.15 msat, which is rounded up to 1101
+			// msat.
+			name: "two hops",
+			relayInfo: []PaymentRelayInfo{
+				{
+					FeeRate: 100,
+					BaseFee: 1000,
+				},
+				{
+					FeeRate: 1000,
+					BaseFee: 500,
+				},
+			},
+			amt:      1_000_000,
+			expected: 1_002_601,
+		},
+		{
+			name: "proportional fee overflow",
+			relayInfo: []PaymentRelayInfo{
+				{
+					FeeRate: math.MaxUint32,
+				},
+			},
+			amt: math.MaxUint64 / 2,
+			err: ErrFeeOverflow,
+		},
+		{
+			name: "total amount overflow",
+			relayInfo: []PaymentRelayInfo{
+				{
+					BaseFee: 1,
+				},
+			},
+			amt: math.MaxUint64,
+			err: ErrFeeOverflow,
+		},
+	}
+
+	for _, testCase := range tests {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			amt, err := PathIncomingAmount(
+				testCase.relayInfo, testCase.amt,
+			)
+			require.ErrorIs(t, err, testCase.err)
+			require.Equal(t, testCase.expected, amt)
+		})
+	}
+}
+
+// TestSamePolicy tests comparing the forwarding policies of relay infos.
+func TestSamePolicy(t *testing.T) {
+	t.Parallel()
+
+	policy := PaymentRelayInfo{
+		CltvExpiryDelta: 40,
+		FeeRate:         100,
+		BaseFee:         1000,
+	}
+
+	tests := []struct {
+		name  string
+		other PaymentRelayInfo
+		same  bool
+	}{
+		{
+			name:  "identical",
+			other: policy,
+			same:  true,
+		},
+		{
+			name: "different fee rate",
+			other: PaymentRelayInfo{
+				CltvExpiryDelta: 40,
+				FeeRate:         101,
+				BaseFee:         1000,
+			},
+		},
+		{
+			name: "different base fee",
+			other: PaymentRelayInfo{
+				CltvExpiryDelta: 40,
+				FeeRate:         100,
+				BaseFee:         0,
+			},
+		},
+		{
+			name: "different cltv delta",
+			other: PaymentRelayInfo{
+				CltvExpiryDelta: 144,
+				FeeRate:         100,
+				BaseFee:         1000,
+			},
+		},
+	}
+
+	for _, testCase := range tests {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			other := testCase.other
+			require.Equal(t, testCase.same, policy.SamePolicy(other))
+			require.Equal(t, testCase.same, other.SamePolicy(policy))
+		})
+	}
+}
+
+// TestBlindedDataDecodeError tests that truncated blinded route data fails to
+// decode with an error that reports the record that was cut off.
+func TestBlindedDataDecodeError(t *testing.T) {
+	t.Parallel()
+
+	data := NewBlindedRouteData(
+		lnwire.NewShortChanIDFromInt(1), nil,
+		PaymentRelayInfo{
+			FeeRate:         2,
+			CltvExpiryDelta: 3,
+			BaseFee:         4,
+		},
+		&PaymentConstraints{
+			MaxCltvExpiry:   5,
+			HtlcMinimumMsat: 6,
+		}, nil,
+	)
+
+	encoded, err := EncodeBlindedRouteData(data)
+	require.NoError(t, err)
+
+	// The short channel ID takes up the first 10 bytes of the encoding,
+	// followed by the relay info and the constraints.
+	var (
+		relayOffset       = 10
+		constraintsOffset = relayOffset + 2 + int(encoded[relayOffset+1])
+	)
+
+	tests := []struct {
+		name      string
+		truncated int
+		tlvType   uint64
+		offset    int
+	}{
+		{
+			name:      "short channel id",
+			truncated: 5,
+			tlvType:   2,
+			offset:    0,
+		},
+		{
+			name:      "relay info",
+			truncated: relayOffset + 3,
+			tlvType:   10,
+			offset:    relayOffset,
+		},
+		{
+			name:      "constraints",
+			truncated: len(encoded) - 1,
+			tlvType:   12,
+			offset:    constraintsOffset,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := DecodeBlindedRouteData(
+				bytes.NewReader(encoded[:test.truncated]),
+			)
+
+			var decodeErr *lnwire.DecodeError
+			require.ErrorAs(t, err, &decodeErr)
+			require.EqualValues(t, test.tlvType, decodeErr.TlvType)
+			require.Equal(t, test.offset, decodeErr.Offset)
+		})
+	}
+}
+
+// TestDecodeBlindedPath tests decoding all the hops of a blinded path and
+// validating that they're consistent with each other.
+//
+//nolint:lll
+func TestDecodeBlindedPath(t *testing.T) {
+	t.Parallel()
+
+	// The data of the introduction node is taken from the spec test
+	// vectors. It forwards with a cltv expiry delta of 36 and a max cltv
+	// expiry of 748005.
+	intro, err := hex.DecodeString("011a0000000000000000000000000000000000000000000000000000020800000000000006c10a0800240000009627100c06000b69e505dc0e00fd023103123456")
+	require.NoError(t, err)
+
+	pathID := []byte{1, 2, 3, 4}
+	newFinalHop := func(maxCltv uint32, pathID []byte) []byte {
+		data := NewBlindedRouteData(
+			lnwire.ShortChannelID{}, nil, PaymentRelayInfo{},
+			&PaymentConstraints{
+				MaxCltvExpiry:   maxCltv,
+				HtlcMinimumMsat: 1500,
+			}, nil,
+		)
+		if pathID != nil {
+			data.PathID = tlv.SomeRecordT(
+				tlv.NewPrimitiveRecord[tlv.TlvType6](pathID),
+			)
+		}
+
+		encoded, err := EncodeBlindedRouteData(data)
+		require.NoError(t, err)
+
+		return encoded
+	}
+
+	final := newFinalHop(748005-36, pathID)
+
+	hops, err := DecodeBlindedPath([][]byte{intro, final})
+	require.NoError(t, err)
+	require.Len(t, hops, 2)
+
+	require.True(t, hops[0].PathID.IsNone())
+	require.EqualValues(t, 36, hops[0].RelayInfo.Val.CltvExpiryDelta)
+	require.Equal(t, pathID, hops[1].PathID.UnwrapOrFailV(t))
+
+	tests := []struct {
+		name  string
+		blobs [][]byte
+		err   error
+	}{
+		{
+			name: "no hops",
+			err:  ErrEmptyBlindedPath,
+		},
+		{
+			name: "final hop without path id",
+			blobs: [][]byte{
+				intro, newFinalHop(748005-36, nil),
+			},
+			err: ErrMissingPathID,
+		},
+		{
+			name:  "hops out of order",
+			blobs: [][]byte{final, intro},
+			err:   ErrUnexpectedPathID,
+		},
+		{
+			name: "cltv delta not accumulated",
+			blobs: [][]byte{
+				intro, newFinalHop(748005-35, pathID),
+			},
+			err: ErrMaxCltvNotAccumulated,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := DecodeBlindedPath(test.blobs)
+			require.ErrorIs(t, err, test.err)
+		})
+	}
+
+	// A hop that fails to decode should be reported as such.
+	_, err = DecodeBlindedPath([][]byte{intro, final[:len(final)-1]})
+	var decodeErr *lnwire.DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+}
+
+// TestDecodeBlindedRouteDataWithOpts tests that each of the decoding options
+// only rejects the data that it is meant to reject.
+func TestDecodeBlindedRouteDataWithOpts(t *testing.T) {
+	t.Parallel()
+
+	encode := func(maxCltv uint32) []byte {
+		data := NewBlindedRouteData(
+			lnwire.NewShortChanIDFromInt(1), nil,
+			PaymentRelayInfo{
+				FeeRate:         2,
+				CltvExpiryDelta: 3,
+				BaseFee:         4,
+			},
+			&PaymentConstraints{
+				MaxCltvExpiry:   maxCltv,
+				HtlcMinimumMsat: 6,
+			}, nil,
+		)
+
+		encoded, err := EncodeBlindedRouteData(data)
+		require.NoError(t, err)
+
+		return encoded
+	}
+
+	valid := encode(5)
+
+	// The short channel ID record takes up the first 10 bytes of the
+	// encoding, so moving it to the end puts the records out of order.
+	scidRecord := valid[:10]
+	outOfOrder := append(append([]byte{}, valid[10:]...), scidRecord...)
+	duplicate := append(append([]byte{}, valid...), scidRecord...)
+
+	// Records of type 20 and 21 are appended in order, as all the known
+	// types are smaller.
+	unknownEven := append(append([]byte{}, valid...), 20, 1, 0)
+	unknownOdd := append(append([]byte{}, valid...), 21, 1, 0)
+
+	// A max cltv expiry below the cltv expiry delta of 3 can't be
+	// satisfied by any payment.
+	badConstraints := encode(2)
+
+	strict := BlindedDataDecodeOpts{
+		StrictOrdering:    true,
+		RejectUnknownEven: true,
+		CheckConstraints:  true,
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		opts BlindedDataDecodeOpts
+		err  error
+	}{
+		{
+			name: "valid lenient",
+			data: valid,
+		},
+		{
+			name: "valid strict",
+			data: valid,
+			opts: strict,
+		},
+		{
+			name: "out of order lenient",
+			data: outOfOrder,
+		},
+		{
+			name: "out of order strict",
+			data: outOfOrder,
+			opts: BlindedDataDecodeOpts{StrictOrdering: true},
+			err:  tlv.ErrStreamNotCanonical,
+		},
+		{
+			name: "duplicate lenient",
+			data: duplicate,
+			err:  tlv.ErrStreamNotCanonical,
+		},
+		{
+			name: "unknown even lenient",
+			data: unknownEven,
+		},
+		{
+			name: "unknown even strict",
+			data: unknownEven,
+			opts: BlindedDataDecodeOpts{RejectUnknownEven: true},
+			err:  ErrUnknownEvenType,
+		},
+		{
+			name: "unknown odd strict",
+			data: unknownOdd,
+			opts: strict,
+		},
+		{
+			name: "bad constraints lenient",
+			data: badConstraints,
+		},
+		{
+			name: "bad constraints strict",
+			data: badConstraints,
+			opts: BlindedDataDecodeOpts{CheckConstraints: true},
+			err:  ErrInvalidConstraints,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			decoded, err := DecodeBlindedRouteDataWithOpts(
+				bytes.NewReader(test.data), test.opts,
+			)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(
+				t, lnwire.NewShortChanIDFromInt(1),
+				decoded.ShortChannelID.Val,
+			)
+		})
+	}
+
+	// The default decoder keeps rejecting out of order records.
+	_, err := DecodeBlindedRouteData(bytes.NewReader(outOfOrder))
+	require.ErrorIs(t, err, tlv.ErrStreamNotCanonical)
+}
@@ -3,13 +3,27 @@ package routing
 import (
 	"errors"
 	"fmt"
+	"math"
 
 	sphinx "github.com/lightningnetwork/lightning-onion"
 	"github.com/lightningnetwork/lnd/channeldb/models"
 	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
 	"github.com/lightningnetwork/lnd/routing/route"
 )
 
+const (
+	// BlindedPathExpiryDelta is the number of blocks, on top of a hop's
+	// own cltv delta, that a blinded route created from a channel policy
+	// can be used for before the hop rejects HTLCs along it.
+	BlindedPathExpiryDelta = 2016
+
+	// blindedDataTagSize is the size of the poly1305 authentication tag
+	// that is appended to the blinded route data of each hop when it is
+	// encrypted.
+	blindedDataTagSize = 16
+)
+
 var (
 	// ErrNoBlindedPath is returned when the blinded path in a blinded
 	// payment is missing.
@@ -23,6 +37,15 @@ var (
 	// ErrHTLCRestrictions is returned when a blinded path has invalid
 	// HTLC maximum and minimum values.
 	ErrHTLCRestrictions = errors.New("invalid htlc minimum and maximum")
+
+	// ErrBlindedPathTooLarge is returned when the encrypted data of a
+	// blinded path doesn't fit into the space available in the onion.
+	ErrBlindedPathTooLarge = errors.New("blinded path too large for onion")
+
+	// ErrBlindedFeeOverflow is returned when the fees of a channel policy
+	// can't be represented in the relay info of a blinded route.
+	ErrBlindedFeeOverflow = errors.New("policy fee overflows blinded " +
+		"relay info")
 )
 
 // BlindedPayment provides the path and payment parameters required to send a
@@ -180,3 +203,80 @@ func (b *BlindedPayment) toRouteHints() RouteHints {
 
 	return hints
 }
+
+// BlindedRouteDataFromPolicy creates the blinded route data for a hop that
+// forwards over the channel with the given short channel ID. The relay info
+// and constraints are taken directly from the channel's policy, and the route
+// expires BlindedPathExpiryDelta blocks after the current height (plus the
+// hop's cltv delta). No features are required from the payer. An error is
+// returned if the policy's fees don't fit into the 32-bit fields of the relay
+// info.
+func BlindedRouteDataFromPolicy(scid lnwire.ShortChannelID,
+	policy *models.ChannelEdgePolicy,
+	currentHeight uint32) (*record.BlindedRouteData, error) {
+
+	if policy.FeeBaseMSat > math.MaxUint32 {
+		return nil, fmt.Errorf("%w: base fee %v", ErrBlindedFeeOverflow,
+			policy.FeeBaseMSat)
+	}
+
+	if policy.FeeProportionalMillionths > math.MaxUint32 {
+		return nil, fmt.Errorf("%w: fee rate %v", ErrBlindedFeeOverflow,
+			uint64(policy.FeeProportionalMillionths))
+	}
+
+	relayInfo := record.PaymentRelayInfo{
+		CltvExpiryDelta: policy.TimeLockDelta,
+		FeeRate:         uint32(policy.FeeProportionalMillionths),
+		BaseFee:         uint32(policy.FeeBaseMSat),
+	}
+
+	maxCltvExpiry := currentHeight + BlindedPathExpiryDelta +
+		uint32(policy.TimeLockDelta)
+
+	constraints := &record.PaymentConstraints{
+		MaxCltvExpiry:   maxCltvExpiry,
+		HtlcMinimumMsat: policy.MinHTLC,
+	}
+
+	return record.NewBlindedRouteData(
+		scid, nil, relayInfo, constraints,
+		lnwire.EmptyFeatureVector(),
+	), nil
+}
+
+// BlindedPathOnionSize returns the number of bytes of onion payload space that
+// the given blinded route data of a path's hops takes up once it is encrypted.
+// Next to the encrypted data record itself, this includes the length prefix
+// and the HMAC of every hop payload. Any other records the sender adds to the
+// payloads, like the blinding point of the introduction node or the amount and
+// expiry of the final hop, are not included.
+func BlindedPathOnionSize(hops []*record.BlindedRouteData) uint64 {
+	var size uint64
+	for _, data := range hops {
+		hop := route.Hop{
+			EncryptedData: make(
+				[]byte, data.EncodedLen()+blindedDataTagSize,
+			),
+		}
+
+		size += hop.PayloadSize(0)
+	}
+
+	return size
+}
+
+// CheckBlindedPathSize returns an error if the encrypted data of the given
+// blinded path hops doesn't fit into the available onion payload space.
+func CheckBlindedPathSize(hops []*record.BlindedRouteData,
+	availableSpace uint64) error {
+
+	size := BlindedPathOnionSize(hops)
+	if size > availableSpace {
+		return fmt.Errorf("%w: %v hops need %v bytes, only %v "+
+			"available", ErrBlindedPathTooLarge, len(hops), size,
+			availableSpace)
+	}
+
+	return nil
+}
@@ -2,12 +2,15 @@ package routing
 
 import (
 	"bytes"
+	"encoding/hex"
+	"math"
 	"testing"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	sphinx "github.com/lightningnetwork/lightning-onion"
 	"github.com/lightningnetwork/lnd/channeldb/models"
 	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/record"
 	"github.com/lightningnetwork/lnd/routing/route"
 	"github.com/stretchr/testify/require"
 )
@@ -213,3 +216,103 @@ func TestBlindedPaymentToHints(t *testing.T) {
 		require.Equal(t, expectedHint[0], actualHint[0])
 	}
 }
+
+// TestBlindedRouteDataFromPolicy tests that the blinded route data created from
+// a channel policy matches the specification's test vector for a hop with the
+// same policy.
+func TestBlindedRouteDataFromPolicy(t *testing.T) {
+	t.Parallel()
+
+	// This is the encrypted data of the second hop in the specification's
+	// route blinding test vector, without the next blinding override that
+	// isn't derived from the channel policy.
+	expected, err := hex.DecodeString(
+		"020800000000000004510a0800300000006401f40c06000b69c105dc0e00",
+	)
+	require.NoError(t, err)
+
+	policy := &models.ChannelEdgePolicy{
+		TimeLockDelta:             48,
+		MinHTLC:                   1500,
+		FeeBaseMSat:               500,
+		FeeProportionalMillionths: 100,
+	}
+
+	// The test vector's max cltv expiry of 747969 includes the hop's cltv
+	// delta and our path expiry.
+	currentHeight := uint32(747969 - BlindedPathExpiryDelta - 48)
+
+	data, err := BlindedRouteDataFromPolicy(
+		lnwire.ShortChannelID{TxPosition: 1105}, policy,
+		currentHeight,
+	)
+	require.NoError(t, err)
+
+	encoded, err := record.EncodeBlindedRouteData(data)
+	require.NoError(t, err)
+	require.Equal(t, expected, encoded)
+
+	// Fees that don't fit into the relay info are rejected rather than
+	// truncated.
+	policy.FeeBaseMSat = math.MaxUint32 + 1
+	_, err = BlindedRouteDataFromPolicy(
+		lnwire.ShortChannelID{TxPosition: 1105}, policy,
+		currentHeight,
+	)
+	require.ErrorIs(t, err, ErrBlindedFeeOverflow)
+
+	policy.FeeBaseMSat = 500
+	policy.FeeProportionalMillionths = math.MaxUint32 + 1
+	_, err = BlindedRouteDataFromPolicy(
+		lnwire.ShortChannelID{TxPosition: 1105}, policy,
+		currentHeight,
+	)
+	require.ErrorIs(t, err, ErrBlindedFeeOverflow)
+}
+
+// TestCheckBlindedPathSize tests that the onion space taken up by the encrypted
+// data of a blinded path is estimated correctly, and that paths too large for
+// the onion are rejected.
+func TestCheckBlindedPathSize(t *testing.T) {
+	t.Parallel()
+
+	data := record.NewBlindedRouteData(
+		lnwire.NewShortChanIDFromInt(1), nil,
+		record.PaymentRelayInfo{
+			FeeRate:         2,
+			CltvExpiryDelta: 3,
+			BaseFee:         4,
+		},
+		&record.PaymentConstraints{
+			MaxCltvExpiry:   5,
+			HtlcMinimumMsat: 6,
+		}, nil,
+	)
+
+	// Each hop payload consists of the encrypted data record, prefixed
+	// with the payload length and followed by the HMAC. All lengths are
+	// small enough to be encoded in a single byte.
+	encryptedLen := uint64(data.EncodedLen() + blindedDataTagSize)
+	hopSize := 1 + (1 + 1 + encryptedLen) + sphinx.HMACSize
+
+	path := []*record.BlindedRouteData{data, data, data}
+	require.Equal(t, 3*hopSize, BlindedPathOnionSize(path))
+
+	// The path fits exactly into its own size, but not into one byte
+	// less.
+	require.NoError(t, CheckBlindedPathSize(path, 3*hopSize))
+	require.ErrorIs(
+		t, CheckBlindedPathSize(path, 3*hopSize-1),
+		ErrBlindedPathTooLarge,
+	)
+
+	// A path with too many hops doesn't fit into an onion at all.
+	numHops := sphinx.MaxPayloadSize/hopSize + 1
+	tooLarge := make([]*record.BlindedRouteData, numHops)
+	for i := range tooLarge {
+		tooLarge[i] = data
+	}
+
+	err := CheckBlindedPathSize(tooLarge, sphinx.MaxPayloadSize)
+	require.ErrorIs(t, err, ErrBlindedPathTooLarge)
+}
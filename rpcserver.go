@@ -84,6 +84,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"gopkg.in/macaroon-bakery.v2/bakery"
+	"gopkg.in/macaroon.v2"
 )
 
 const (
@@ -91,6 +92,10 @@ const (
 	// to issuing an estimate for if a fee pre fence doesn't specify an
 	// explicit conf target or fee rate.
 	defaultNumBlocksEstimate = 6
+
+	// maxAddrOverrideExpiry is the maximum expiry of a peer address
+	// override.
+	maxAddrOverrideExpiry = 365 * 24 * time.Hour
 )
 
 var (
@@ -354,6 +359,10 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "peers",
 			Action: "read",
 		}},
+		"/lnrpc.Lightning/AuditGraphCache": {{
+			Entity: "info",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/GetRecoveryInfo": {{
 			Entity: "info",
 			Action: "read",
@@ -362,6 +371,18 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "peers",
 			Action: "read",
 		}},
+		"/lnrpc.Lightning/SetPeerAddrOverride": {{
+			Entity: "peers",
+			Action: "write",
+		}},
+		"/lnrpc.Lightning/ListPeerAddrOverrides": {{
+			Entity: "peers",
+			Action: "read",
+		}},
+		"/lnrpc.Lightning/DeletePeerAddrOverride": {{
+			Entity: "peers",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/WalletBalance": {{
 			Entity: "onchain",
 			Action: "read",
@@ -1764,8 +1785,9 @@ func (r *rpcServer) ConnectPeer(ctx context.Context,
 	rpcsLog.Debugf("[connectpeer] requested connection to %x@%s",
 		peerAddr.IdentityKey.SerializeCompressed(), peerAddr.Address)
 
-	// By default, we will use the global connection timeout value.
-	timeout := r.cfg.ConnectionTimeout
+	// By default, we will use the connection timeout configured for the
+	// address family of the peer's address.
+	timeout := r.server.dialTimeout(peerAddr)
 
 	// Check if the connection timeout is set. If set, we will use it in our
 	// request.
@@ -3177,9 +3199,55 @@ func (r *rpcServer) GetDebugInfo(_ context.Context,
 			logFileName, err)
 	}
 
+	summary, degraded := r.server.latencyHealth.Status()
+
 	return &lnrpc.GetDebugInfoResponse{
 		Config: flatConfig,
 		Log:    strings.Split(string(logContent), "\n"),
+		PeerLatency: &lnrpc.PeerLatencyHealth{
+			NumPeers:        uint32(summary.NumPeers),
+			P50PingTime:     summary.P50.Microseconds(),
+			P95PingTime:     summary.P95.Microseconds(),
+			FailureFraction: summary.FailureFraction,
+			Degraded:        degraded,
+		},
+	}, nil
+}
+
+// AuditGraphCache runs a consistency audit of the graph cache against the
+// graph store right away and returns a summary of the divergences found.
+func (r *rpcServer) AuditGraphCache(_ context.Context,
+	req *lnrpc.AuditGraphCacheRequest) (*lnrpc.AuditGraphCacheResponse,
+	error) {
+
+	numNodes := r.cfg.Caches.GraphCacheAuditSamples
+	if req.NumNodes != 0 {
+		numNodes = int(req.NumNodes)
+	}
+
+	summary, err := r.server.graphDB.AuditGraphCache(numNodes)
+	switch {
+	case errors.Is(err, channeldb.ErrGraphCacheDisabled):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+
+	case err != nil:
+		return nil, fmt.Errorf("unable to audit graph cache: %w", err)
+	}
+
+	if summary.NumDivergences() > 0 {
+		rpcsLog.Warnf("Graph cache audit found divergences: %v",
+			summary)
+	}
+
+	return &lnrpc.AuditGraphCacheResponse{
+		NodesAudited:       uint32(summary.NodesAudited),
+		ChannelsAudited:    uint32(summary.ChannelsAudited),
+		MissingNodes:       uint32(summary.MissingNodes),
+		MismatchedFeatures: uint32(summary.MismatchedFeatures),
+		MissingChannels:    uint32(summary.MissingChannels),
+		StaleChannels:      uint32(summary.StaleChannels),
+		MismatchedChannels: uint32(summary.MismatchedChannels),
+		RepairedNodes:      uint32(summary.RepairedNodes),
 	}, nil
 }
 
@@ -3214,6 +3282,13 @@ func (r *rpcServer) ListPeers(ctx context.Context,
 		Peers: make([]*lnrpc.Peer, 0, len(serverPeers)),
 	}
 
+	// Fetch all address overrides at once, so that we can mark the peers
+	// we connect to at an overridden address.
+	addrOverrides, err := r.server.miscDB.FetchAddrOverrides()
+	if err != nil {
+		return nil, err
+	}
+
 	for _, serverPeer := range serverPeers {
 		var (
 			satSent int64
@@ -3330,6 +3405,12 @@ func (r *rpcServer) ListPeers(ctx context.Context,
 			}
 		}
 
+		if override, ok := addrOverrides[nodePub]; ok {
+			rpcPeer.AddrOverride = marshalAddrOverride(
+				nodePub, override,
+			)
+		}
+
 		resp.Peers = append(resp.Peers, rpcPeer)
 	}
 
@@ -3338,6 +3419,148 @@ func (r *rpcServer) ListPeers(ctx context.Context,
 	return resp, nil
 }
 
+// SetPeerAddrOverride sets the addresses we connect to a peer at, taking
+// precedence over the addresses the peer announced.
+func (r *rpcServer) SetPeerAddrOverride(_ context.Context,
+	in *lnrpc.SetPeerAddrOverrideRequest) (
+	*lnrpc.SetPeerAddrOverrideResponse, error) {
+
+	vertex, err := route.NewVertexFromStr(in.PubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(in.Addresses) == 0 {
+		return nil, fmt.Errorf("at least one address is required")
+	}
+
+	override := &channeldb.AddrOverride{
+		Addresses: make([]net.Addr, 0, len(in.Addresses)),
+	}
+	for _, host := range in.Addresses {
+		addr, err := parseAddr(host, r.cfg.net)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse address %v: %w",
+				host, err)
+		}
+
+		override.Addresses = append(override.Addresses, addr)
+	}
+
+	maxExpirySeconds := uint64(maxAddrOverrideExpiry / time.Second)
+	if in.ExpirySeconds > maxExpirySeconds {
+		return nil, fmt.Errorf("expiry of %d seconds exceeds maximum "+
+			"of %d seconds", in.ExpirySeconds, maxExpirySeconds)
+	}
+
+	if in.ExpirySeconds != 0 {
+		override.Expiry = time.Now().Add(
+			time.Duration(in.ExpirySeconds) * time.Second,
+		)
+	}
+
+	err = r.server.miscDB.SetAddrOverride(vertex, override)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure we stop dialing the old addresses of the peer if we're
+	// currently trying to reconnect to it.
+	r.server.refreshPersistentPeerAddrs(vertex)
+
+	rpcsLog.Infof("[setpeeraddroverride] set address override %v for "+
+		"peer %v", override.Addresses, vertex)
+
+	return &lnrpc.SetPeerAddrOverrideResponse{
+		AddrOverride: marshalAddrOverride(vertex, override),
+	}, nil
+}
+
+// ListPeerAddrOverrides returns all address overrides that haven't expired.
+func (r *rpcServer) ListPeerAddrOverrides(_ context.Context,
+	_ *lnrpc.ListPeerAddrOverridesRequest) (
+	*lnrpc.ListPeerAddrOverridesResponse, error) {
+
+	overrides, err := r.server.miscDB.FetchAddrOverrides()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &lnrpc.ListPeerAddrOverridesResponse{
+		AddrOverrides: make(
+			[]*lnrpc.PeerAddrOverride, 0, len(overrides),
+		),
+	}
+	for vertex, override := range overrides {
+		rpcOverride := marshalAddrOverride(vertex, override)
+		resp.AddrOverrides = append(resp.AddrOverrides, rpcOverride)
+	}
+
+	// Sort the overrides by pubkey, so that the output is stable.
+	sort.Slice(resp.AddrOverrides, func(i, j int) bool {
+		return resp.AddrOverrides[i].PubKey <
+			resp.AddrOverrides[j].PubKey
+	})
+
+	return resp, nil
+}
+
+// DeletePeerAddrOverride removes the address override of a peer.
+func (r *rpcServer) DeletePeerAddrOverride(_ context.Context,
+	in *lnrpc.DeletePeerAddrOverrideRequest) (
+	*lnrpc.DeletePeerAddrOverrideResponse, error) {
+
+	vertex, err := route.NewVertexFromStr(in.PubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.server.miscDB.DeleteAddrOverride(vertex)
+	switch {
+	case errors.Is(err, channeldb.ErrNoAddrOverride):
+		return nil, status.Error(codes.NotFound, err.Error())
+
+	case err != nil:
+		return nil, err
+	}
+
+	// Make sure we go back to dialing the announced addresses of the
+	// peer if we're currently trying to reconnect to it.
+	r.server.refreshPersistentPeerAddrs(vertex)
+
+	rpcsLog.Infof("[deletepeeraddroverride] deleted address override "+
+		"for peer %v", vertex)
+
+	return &lnrpc.DeletePeerAddrOverrideResponse{}, nil
+}
+
+// marshalAddrOverride converts the address override of the given peer into
+// its RPC representation.
+func marshalAddrOverride(vertex route.Vertex,
+	override *channeldb.AddrOverride) *lnrpc.PeerAddrOverride {
+
+	rpcOverride := &lnrpc.PeerAddrOverride{
+		PubKey: hex.EncodeToString(vertex[:]),
+		Addresses: make(
+			[]*lnrpc.NodeAddress, 0, len(override.Addresses),
+		),
+	}
+	for _, addr := range override.Addresses {
+		rpcOverride.Addresses = append(
+			rpcOverride.Addresses, &lnrpc.NodeAddress{
+				Network: addr.Network(),
+				Addr:    addr.String(),
+			},
+		)
+	}
+
+	if !override.Expiry.IsZero() {
+		rpcOverride.Expiry = override.Expiry.Unix()
+	}
+
+	return rpcOverride
+}
+
 // SubscribePeerEvents returns a uni-directional stream (server -> client)
 // for notifying the client of peer online and offline events.
 func (r *rpcServer) SubscribePeerEvents(req *lnrpc.PeerEventSubscription,
@@ -4728,6 +4951,12 @@ func (r *rpcServer) createRPCClosedChannel(
 		return nil, err
 	}
 
+	if dbChannel.CoopCloseTelemetry != nil {
+		channel.CoopCloseTelemetry = marshalCoopCloseTelemetry(
+			dbChannel.CoopCloseTelemetry,
+		)
+	}
+
 	reports, err := r.server.miscDB.FetchChannelReports(
 		*r.cfg.ActiveNetParams.GenesisHash, &dbChannel.ChanPoint,
 	)
@@ -4759,6 +4988,33 @@ func (r *rpcServer) createRPCClosedChannel(
 	return channel, nil
 }
 
+// marshalCoopCloseTelemetry converts the telemetry of a cooperative close fee
+// negotiation to its rpc representation.
+func marshalCoopCloseTelemetry(
+	t *channeldb.CoopCloseTelemetry) *lnrpc.CoopCloseTelemetry {
+
+	rpcTelemetry := &lnrpc.CoopCloseTelemetry{
+		Rounds:             t.Rounds,
+		LocalProposalsSat:  make([]int64, 0, len(t.LocalProposals)),
+		RemoteProposalsSat: make([]int64, 0, len(t.RemoteProposals)),
+		FinalFeePerKw:      uint64(t.FinalFeeRate),
+		DurationNs:         uint64(t.Duration.Nanoseconds()),
+	}
+
+	for _, fee := range t.LocalProposals {
+		rpcTelemetry.LocalProposalsSat = append(
+			rpcTelemetry.LocalProposalsSat, int64(fee),
+		)
+	}
+	for _, fee := range t.RemoteProposals {
+		rpcTelemetry.RemoteProposalsSat = append(
+			rpcTelemetry.RemoteProposalsSat, int64(fee),
+		)
+	}
+
+	return rpcTelemetry
+}
+
 func rpcChannelResolution(report *channeldb.ResolverReport) (*lnrpc.Resolution,
 	error) {
 
@@ -5768,7 +6024,10 @@ func (r *rpcServer) AddInvoice(ctx context.Context,
 		GenAmpInvoiceFeatures: func() *lnwire.FeatureVector {
 			return r.server.featureMgr.Get(feature.SetInvoiceAmp)
 		},
-		GetAlias: r.server.aliasMgr.GetPeerAlias,
+		GetAlias:             r.server.aliasMgr.GetPeerAlias,
+		MaxMemoSize:          int(r.cfg.Invoices.MaxMemoSize),
+		TruncateMemo:         r.cfg.Invoices.TruncateMemo,
+		AddIdempotentInvoice: r.server.invoices.AddIdempotentInvoice,
 	}
 
 	value, err := lnrpc.UnmarshallAmt(invoice.Value, invoice.ValueMsat)
@@ -5776,6 +6035,13 @@ func (r *rpcServer) AddInvoice(ctx context.Context,
 		return nil, err
 	}
 
+	idempotencyKey, err := r.invoiceIdempotencyKey(
+		ctx, invoice.IdempotencyKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert the passed routing hints to the required format.
 	routeHints, err := invoicesrpc.CreateZpay32HopHints(invoice.RouteHints)
 	if err != nil {
@@ -5791,6 +6057,7 @@ func (r *rpcServer) AddInvoice(ctx context.Context,
 		Private:         invoice.Private,
 		RouteHints:      routeHints,
 		Amp:             invoice.IsAmp,
+		IdempotencyKey:  idempotencyKey,
 	}
 
 	if invoice.RPreimage != nil {
@@ -5816,6 +6083,44 @@ func (r *rpcServer) AddInvoice(ctx context.Context,
 	}, nil
 }
 
+// invoiceIdempotencyKey returns the idempotency key that the client set on an
+// invoice to add, or nil if it didn't set one. The key is scoped to the id of
+// the macaroon the client authenticated with, so that clients using different
+// macaroons can't collide with or probe each other's keys.
+func (r *rpcServer) invoiceIdempotencyKey(ctx context.Context,
+	rawKey []byte) (*invoices.IdempotencyKey, error) {
+
+	if len(rawKey) == 0 {
+		return nil, nil
+	}
+
+	key := &invoices.IdempotencyKey{
+		Key: rawKey,
+	}
+
+	// Without macaroons, all clients share the same scope.
+	if r.cfg.NoMacaroons {
+		return key, nil
+	}
+
+	macHex, err := macaroons.RawMacaroonFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	macBytes, err := hex.DecodeString(macHex)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := &macaroon.Macaroon{}
+	if err := mac.UnmarshalBinary(macBytes); err != nil {
+		return nil, err
+	}
+	key.Scope = mac.Id()
+
+	return key, nil
+}
+
 // LookupInvoice attempts to look up an invoice according to its payment hash.
 // The passed payment hash *must* be exactly 32 bytes, if not an error is
 // returned.
@@ -5852,6 +6157,8 @@ func (r *rpcServer) LookupInvoice(ctx context.Context,
 	switch {
 	case errors.Is(err, invoices.ErrInvoiceNotFound):
 		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return nil, status.Error(codes.DeadlineExceeded, err.Error())
 	case err != nil:
 		return nil, err
 	}
@@ -6404,12 +6711,29 @@ func (r *rpcServer) GetNodeInfo(ctx context.Context,
 		return nil, err
 	}
 
-	return &lnrpc.NodeInfo{
+	nodeInfo := &lnrpc.NodeInfo{
 		Node:          marshalNode(node),
 		NumChannels:   numChannels,
 		TotalCapacity: int64(totalCapacity),
 		Channels:      channels,
-	}, nil
+	}
+
+	// If the user overrode the addresses we connect to the node at, we
+	// include the override next to the addresses it announced.
+	override, err := r.server.miscDB.FetchAddrOverride(pubKey)
+	switch {
+	// The node has no override, so we connect to it at the addresses it
+	// announced.
+	case errors.Is(err, channeldb.ErrNoAddrOverride):
+
+	case err != nil:
+		return nil, err
+
+	default:
+		nodeInfo.AddrOverride = marshalAddrOverride(pubKey, override)
+	}
+
+	return nodeInfo, nil
 }
 
 func marshalNode(node *channeldb.LightningNode) *lnrpc.LightningNode {
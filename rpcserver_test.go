@@ -2,8 +2,14 @@ package lnd
 
 import (
 	"testing"
+	"time"
 
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetAllPermissions(t *testing.T) {
@@ -12,3 +18,31 @@ func TestGetAllPermissions(t *testing.T) {
 	// Currently there are there are 16 entity:action pairs in use.
 	assert.Equal(t, len(perms), 16)
 }
+
+// TestMarshalCoopCloseTelemetry tests that the telemetry of a cooperative
+// close fee negotiation is converted to its rpc representation.
+func TestMarshalCoopCloseTelemetry(t *testing.T) {
+	t.Parallel()
+
+	telemetry := &channeldb.CoopCloseTelemetry{
+		Rounds:          3,
+		LocalProposals:  []btcutil.Amount{1000, 1100, 1210},
+		RemoteProposals: []btcutil.Amount{1800, 1620, 1210},
+		FinalFeeRate:    chainfee.SatPerKWeight(1720),
+		Duration:        1500 * time.Millisecond,
+	}
+
+	require.Equal(t, &lnrpc.CoopCloseTelemetry{
+		Rounds:             3,
+		LocalProposalsSat:  []int64{1000, 1100, 1210},
+		RemoteProposalsSat: []int64{1800, 1620, 1210},
+		FinalFeePerKw:      1720,
+		DurationNs:         1_500_000_000,
+	}, marshalCoopCloseTelemetry(telemetry))
+
+	// Empty telemetry is marshalled to empty proposal lists.
+	require.Equal(t, &lnrpc.CoopCloseTelemetry{
+		LocalProposalsSat:  []int64{},
+		RemoteProposalsSat: []int64{},
+	}, marshalCoopCloseTelemetry(&channeldb.CoopCloseTelemetry{}))
+}
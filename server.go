@@ -325,6 +325,10 @@ type server struct {
 	// livenessMonitor monitors that lnd has access to critical resources.
 	livenessMonitor *healthcheck.Monitor
 
+	// latencyHealth rolls up the ping stats of all our peers into a
+	// node-wide connectivity health signal.
+	latencyHealth *peer.LatencyHealth
+
 	customMessageServer *subscribe.Server
 
 	// txPublisher is a publisher with fee-bumping capability.
@@ -464,12 +468,14 @@ func parseAddr(address string, netCfg tor.Net) (net.Addr, error) {
 
 // noiseDial is a factory function which creates a connmgr compliant dialing
 // function by returning a closure which includes the server's identity key.
-func noiseDial(idKey keychain.SingleKeyECDH,
-	netCfg tor.Net, timeout time.Duration) func(net.Addr) (net.Conn, error) {
+func noiseDial(idKey keychain.SingleKeyECDH, netCfg tor.Net,
+	timeouts *lncfg.DialTimeouts) func(net.Addr) (net.Conn, error) {
 
 	return func(a net.Addr) (net.Conn, error) {
 		lnAddr := a.(*lnwire.NetAddress)
-		return brontide.Dial(idKey, lnAddr, timeout, netCfg.Dial)
+		return brontide.Dial(
+			idKey, lnAddr, timeouts.ForAddr(lnAddr), netCfg.Dial,
+		)
 	}
 }
 
@@ -564,6 +570,8 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		GcCanceledInvoicesOnStartup: cfg.GcCanceledInvoicesOnStartup,
 		GcCanceledInvoicesOnTheFly:  cfg.GcCanceledInvoicesOnTheFly,
 		KeysendHoldTime:             cfg.KeysendHoldTime,
+		IdempotencyKeys:             dbs.ChanStateDB,
+		IdempotencyKeyTTL:           cfg.Invoices.IdempotencyKeyTTL,
 	}
 
 	s := &server{
@@ -613,6 +621,10 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 
 		customMessageServer: subscribe.NewServer(),
 
+		latencyHealth: peer.NewLatencyHealth(
+			peer.DefaultLatencyHealthConfig(),
+		),
+
 		tlsManager: tlsManager,
 
 		featureMgr: featureMgr,
@@ -998,6 +1010,10 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 	if err != nil {
 		return nil, err
 	}
+	gossipOutbox, err := discovery.NewMessageOutbox(dbs.ChanStateDB)
+	if err != nil {
+		return nil, err
+	}
 	waitingProofStore, err := channeldb.NewWaitingProofStore(dbs.ChanStateDB)
 	if err != nil {
 		return nil, err
@@ -1023,6 +1039,7 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		RebroadcastInterval:     time.Hour * 24,
 		WaitingProofStore:       waitingProofStore,
 		MessageStore:            gossipMessageStore,
+		Outbox:                  gossipOutbox,
 		AnnSigner:               s.nodeSigner,
 		RotateTicker:            ticker.New(discovery.DefaultSyncerRotationInterval),
 		HistoricalSyncTicker:    ticker.New(cfg.HistoricalSyncInterval),
@@ -1560,7 +1577,8 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 			dialer tor.DialFunc) (wtserver.Peer, error) {
 
 			return brontide.Dial(
-				localKey, netAddr, cfg.ConnectionTimeout, dialer,
+				localKey, netAddr,
+				cfg.dialTimeouts().ForAddr(netAddr), dialer,
 			)
 		}
 
@@ -1668,7 +1686,7 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		RetryDuration:  time.Second * 5,
 		TargetOutbound: 100,
 		Dial: noiseDial(
-			nodeKeyECDH, s.cfg.net, s.cfg.ConnectionTimeout,
+			nodeKeyECDH, s.cfg.net, s.cfg.dialTimeouts(),
 		),
 		OnConnection: s.OutboundPeerConnected,
 	})
@@ -1820,6 +1838,31 @@ func (s *server) createLivenessMonitor(cfg *Config, cc *chainreg.ChainControl) {
 		checks = append(checks, remoteSignerConnectionCheck)
 	}
 
+	// The peer latency check fails while the node-wide connectivity, as
+	// rolled up from the ping stats of all our peers, is degraded. The
+	// aggregator already applies hysteresis, so a single flapping peer
+	// won't fail this check.
+	peerLatencyCheck := healthcheck.NewObservation(
+		"peer latency",
+		func() error {
+			summary, degraded := s.latencyHealth.Status()
+			if !degraded {
+				return nil
+			}
+
+			return fmt.Errorf("peer connectivity degraded: "+
+				"num_peers=%d, p50=%v, p95=%v, "+
+				"failure_fraction=%.2f", summary.NumPeers,
+				summary.P50, summary.P95,
+				summary.FailureFraction)
+		},
+		cfg.HealthChecks.PeerLatency.Interval,
+		cfg.HealthChecks.PeerLatency.Timeout,
+		cfg.HealthChecks.PeerLatency.Backoff,
+		cfg.HealthChecks.PeerLatency.Attempts,
+	)
+	checks = append(checks, peerLatencyCheck)
+
 	// If we have not disabled all of our health checks, we create a
 	// liveness monitor with our configured checks.
 	s.livenessMonitor = healthcheck.NewMonitor(
@@ -2103,6 +2146,16 @@ func (s *server) Start() error {
 			go s.watchExternalIP()
 		}
 
+		s.wg.Add(1)
+		go s.watchPeerLatency()
+
+		if !s.cfg.DB.NoGraphCache &&
+			s.cfg.Caches.GraphCacheAuditInterval > 0 {
+
+			s.wg.Add(1)
+			go s.auditGraphCache()
+		}
+
 		// Start connmgr last to prevent connections before init.
 		s.connMgr.Start()
 		cleanup = cleanup.add(func() error {
@@ -2136,8 +2189,7 @@ func (s *server) Start() error {
 			}
 
 			err = s.ConnectToPeer(
-				peerAddr, true,
-				s.cfg.ConnectionTimeout,
+				peerAddr, true, s.dialTimeout(peerAddr),
 			)
 			if err != nil {
 				startErr = fmt.Errorf("unable to connect to "+
@@ -2260,6 +2312,23 @@ func (s *server) Stop() error {
 		// Shutdown connMgr first to prevent conns during shutdown.
 		s.connMgr.Stop()
 
+		// Give the HTLCs that are forwarded through us a chance to
+		// resolve before we tear down the links.
+		drainTimeout := s.cfg.Htlcswitch.ShutdownDrainTimeout
+		if drainTimeout > 0 {
+			srvrLog.Infof("Draining channel links for up to %v",
+				drainTimeout)
+
+			drained, abandoned := s.htlcSwitch.DrainLinks(
+				drainTimeout,
+			)
+			srvrLog.Infof("Drained %d channel links, abandoned %d "+
+				"with HTLCs still in flight or not yet "+
+				"reestablished (incoming HTLCs settling at "+
+				"us, e.g. held HTLCs, aren't drained)",
+				drained, abandoned)
+		}
+
 		// Shutdown the wallet, funding manager, and the rpc server.
 		if err := s.chanStatusMgr.Stop(); err != nil {
 			srvrLog.Warnf("failed to stop chanStatusMgr: %v", err)
@@ -2417,6 +2486,89 @@ func (s *server) removePortForwarding() {
 	}
 }
 
+// watchPeerLatency periodically feeds the ping stats of all connected peers
+// into the latency health aggregator and logs whenever the node-wide
+// connectivity health state changes.
+//
+// NOTE: This MUST be run as a goroutine.
+func (s *server) watchPeerLatency() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			for _, p := range s.Peers() {
+				s.latencyHealth.Observe(
+					p.PubKey(), p.PingStats(), now,
+				)
+			}
+
+			_, wasDegraded := s.latencyHealth.Status()
+			summary, degraded := s.latencyHealth.Evaluate(now)
+
+			switch {
+			case degraded && !wasDegraded:
+				srvrLog.Warnf("Peer connectivity degraded: "+
+					"num_peers=%d, p50=%v, p95=%v, "+
+					"failure_fraction=%.2f",
+					summary.NumPeers, summary.P50,
+					summary.P95, summary.FailureFraction)
+
+			case !degraded && wasDegraded:
+				srvrLog.Infof("Peer connectivity recovered: "+
+					"num_peers=%d, p50=%v, p95=%v, "+
+					"failure_fraction=%.2f",
+					summary.NumPeers, summary.P50,
+					summary.P95, summary.FailureFraction)
+			}
+
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// auditGraphCache periodically compares a random sample of the in-memory graph
+// cache against the graph store, repairing any entries that diverged.
+//
+// NOTE: This MUST be run as a goroutine.
+func (s *server) auditGraphCache() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg.Caches.GraphCacheAuditInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			summary, err := s.graphDB.AuditGraphCache(
+				s.cfg.Caches.GraphCacheAuditSamples,
+			)
+			if err != nil {
+				srvrLog.Errorf("Unable to audit graph cache: %v",
+					err)
+				continue
+			}
+
+			if summary.NumDivergences() > 0 {
+				srvrLog.Warnf("Graph cache audit found "+
+					"divergences: %v", summary)
+				continue
+			}
+
+			srvrLog.Debugf("Graph cache audit complete: %v",
+				summary)
+
+		case <-s.quit:
+			return
+		}
+	}
+}
+
 // watchExternalIP continuously checks for an updated external IP address every
 // 15 minutes. Once a new IP address has been detected, it will automatically
 // handle port forwarding rules and send updated node announcements to the
@@ -2736,8 +2888,7 @@ func (s *server) peerBootstrapper(numTargetPeers uint32,
 					// country diversity, etc
 					errChan := make(chan error, 1)
 					s.connectToPeer(
-						a, errChan,
-						s.cfg.ConnectionTimeout,
+						a, errChan, s.dialTimeout(a),
 					)
 					select {
 					case err := <-errChan:
@@ -2845,7 +2996,7 @@ func (s *server) initialPeerBootstrap(ignore map[autopilot.NodeID]struct{},
 
 				errChan := make(chan error, 1)
 				go s.connectToPeer(
-					addr, errChan, s.cfg.ConnectionTimeout,
+					addr, errChan, s.dialTimeout(addr),
 				)
 
 				// We'll only allow this connection attempt to
@@ -3464,11 +3615,27 @@ func (s *server) findPeerByPubStr(pubStr string) (*peer.Brontide, error) {
 func (s *server) nextPeerBackoff(pubStr string,
 	startTime time.Time) time.Duration {
 
+	// We don't want to retry before a dial to any of the peer's addresses
+	// could have timed out, so the minimum backoff is raised to the
+	// longest dial timeout configured for their address families.
+	minBackoff := s.cfg.MinBackoff
+	addrs := make([]net.Addr, 0, len(s.persistentPeerAddrs[pubStr]))
+	for _, addr := range s.persistentPeerAddrs[pubStr] {
+		addrs = append(addrs, addr)
+	}
+	retryDelay := s.cfg.dialTimeouts().MinRetryDelay(addrs)
+	if retryDelay > minBackoff {
+		minBackoff = retryDelay
+	}
+
 	// Now, determine the appropriate backoff to use for the retry.
 	backoff, ok := s.persistentPeersBackoff[pubStr]
 	if !ok {
 		// If an existing backoff was unknown, use the default.
-		return s.cfg.MinBackoff
+		return minBackoff
+	}
+	if backoff < minBackoff {
+		backoff = minBackoff
 	}
 
 	// If the peer failed to start properly, we'll just use the previous
@@ -3490,17 +3657,23 @@ func (s *server) nextPeerBackoff(pubStr string,
 	// reduce the timeout duration by the length of the connection after
 	// applying randomized exponential backoff. We'll only apply this in the
 	// case that:
-	//   reb(curBackoff) - connDuration > cfg.MinBackoff
+	//   reb(curBackoff) - connDuration > minBackoff
 	relaxedBackoff := computeNextBackoff(backoff, s.cfg.MaxBackoff) - connDuration
-	if relaxedBackoff > s.cfg.MinBackoff {
+	if relaxedBackoff > minBackoff {
 		return relaxedBackoff
 	}
 
-	// Lastly, if reb(currBackoff) - connDuration <= cfg.MinBackoff, meaning
+	// Lastly, if reb(currBackoff) - connDuration <= minBackoff, meaning
 	// the stable connection lasted much longer than our previous backoff.
 	// To reward such good behavior, we'll reconnect after the default
 	// timeout.
-	return s.cfg.MinBackoff
+	return minBackoff
+}
+
+// dialTimeout returns the timeout to use when dialing the given address, which
+// depends on its address family.
+func (s *server) dialTimeout(addr net.Addr) time.Duration {
+	return s.cfg.dialTimeouts().ForAddr(addr)
 }
 
 // shouldDropLocalConnection determines if our local connection to a remote peer
@@ -4049,6 +4222,11 @@ func (s *server) peerTerminationWatcher(p *peer.Brontide, ready chan struct{}) {
 
 	srvrLog.Debugf("Peer %v has been disconnected", p)
 
+	// Record the final ping stats of the peer, so that a ping failure
+	// that caused the disconnection is still accounted for in the
+	// node-wide latency health.
+	s.latencyHealth.Observe(p.PubKey(), p.PingStats(), time.Now())
+
 	// If the server is exiting then we can bail out early ourselves as all
 	// the other sub-systems will already be shutting down.
 	if s.Stopped() {
@@ -4229,22 +4407,97 @@ func (s *server) peerTerminationWatcher(p *peer.Brontide, ready chan struct{}) {
 	}()
 }
 
+// refreshPersistentPeerAddrs makes a persistent peer we're currently not
+// connected to pick up a changed address override right away. Otherwise, the
+// pending connection requests would keep dialing the old addresses until the
+// peer announces new ones or we restart.
+func (s *server) refreshPersistentPeerAddrs(pubKey route.Vertex) {
+	pubKeyStr := string(pubKey[:])
+
+	s.mu.RLock()
+	_, persistent := s.persistentPeers[pubKeyStr]
+	_, connected := s.peersByPub[pubKeyStr]
+	s.mu.RUnlock()
+
+	if !persistent || connected {
+		return
+	}
+
+	s.connectToPersistentPeer(pubKeyStr)
+}
+
+// addrOverrideDialAddrs returns the addresses of the address override the
+// user set for the given peer, or nil if the peer has no override. The
+// addresses the peer announced may be stale, so the override's addresses take
+// precedence over them.
+//
+// NOTE: This function performs a database lookup, so it should not be called
+// with the server's mutex held.
+func (s *server) addrOverrideDialAddrs(
+	pubKeyStr string) []*lnwire.NetAddress {
+
+	pubKey, err := btcec.ParsePubKey([]byte(pubKeyStr))
+	if err != nil {
+		srvrLog.Errorf("Invalid persistent peer key %x: %v",
+			pubKeyStr, err)
+
+		return nil
+	}
+	vertex := route.NewVertex(pubKey)
+
+	override, err := s.miscDB.FetchAddrOverride(vertex)
+	switch {
+	case err == channeldb.ErrNoAddrOverride:
+		return nil
+
+	case err != nil:
+		srvrLog.Errorf("Unable to fetch address override for %v: %v",
+			vertex, err)
+
+		return nil
+	}
+
+	srvrLog.Debugf("Using address override %v for peer %v",
+		override.Addresses, vertex)
+
+	overrideAddrs := make([]*lnwire.NetAddress, 0, len(override.Addresses))
+	for _, addr := range override.Addresses {
+		overrideAddrs = append(overrideAddrs, &lnwire.NetAddress{
+			IdentityKey: pubKey,
+			Address:     addr,
+			ChainNet:    s.cfg.ActiveNetParams.Net,
+		})
+	}
+
+	return overrideAddrs
+}
+
 // connectToPersistentPeer uses all the stored addresses for a peer to attempt
 // to connect to the peer. It creates connection requests if there are
 // currently none for a given address and it removes old connection requests
 // if the associated address is no longer in the latest address list for the
 // peer.
 func (s *server) connectToPersistentPeer(pubKeyStr string) {
+	// If the user set an address override for the peer, only the
+	// override's addresses are used. We look it up before acquiring the
+	// mutex, so that we don't hold it during a database lookup.
+	overrideAddrs := s.addrOverrideDialAddrs(pubKeyStr)
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	addrs := s.persistentPeerAddrs[pubKeyStr]
+	if len(overrideAddrs) > 0 {
+		addrs = overrideAddrs
+	}
+
 	// Create an easy lookup map of the addresses we have stored for the
 	// peer. We will remove entries from this map if we have existing
 	// connection requests for the associated address and then any leftover
 	// entries will indicate which addresses we should create new
 	// connection requests for.
 	addrMap := make(map[string]*lnwire.NetAddress)
-	for _, addr := range s.persistentPeerAddrs[pubKeyStr] {
+	for _, addr := range addrs {
 		addrMap[addr.String()] = addr
 	}
 
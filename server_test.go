@@ -1,9 +1,21 @@
 package lnd
 
 import (
+	"errors"
+	"net"
 	"testing"
+	"time"
 
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/connmgr"
+	"github.com/lightningnetwork/lnd/chainreg"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/clock"
 	"github.com/lightningnetwork/lnd/lncfg"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/peer"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/stretchr/testify/require"
 )
 
 // TestShouldPeerBootstrap tests that we properly skip network bootstrap for
@@ -80,3 +92,104 @@ func TestShouldPeerBootstrap(t *testing.T) {
 		}
 	}
 }
+
+// TestConnectToPersistentPeerAddrOverride asserts that an address override
+// takes precedence over the addresses a persistent peer announced, and that
+// the announced addresses are dialed again once the override expired.
+func TestConnectToPersistentPeerAddrOverride(t *testing.T) {
+	t.Parallel()
+
+	testClock := clock.NewTestClock(time.Unix(1_700_000_000, 0))
+	db, err := channeldb.MakeTestDB(t, channeldb.OptionClock(testClock))
+	require.NoError(t, err)
+
+	// None of the dials succeed, so the connection requests stay pending.
+	cmgr, err := connmgr.New(&connmgr.Config{
+		RetryDuration:  time.Hour,
+		TargetOutbound: 100,
+		Dial: func(net.Addr) (net.Conn, error) {
+			return nil, errors.New("unreachable")
+		},
+	})
+	require.NoError(t, err)
+	cmgr.Start()
+	t.Cleanup(cmgr.Stop)
+
+	s := &server{
+		cfg: &Config{
+			ActiveNetParams: chainreg.BitcoinRegTestNetParams,
+		},
+		miscDB:                 db,
+		connMgr:                cmgr,
+		persistentPeers:        make(map[string]bool),
+		persistentPeerAddrs:    make(map[string][]*lnwire.NetAddress),
+		persistentConnReqs:     make(map[string][]*connmgr.ConnReq),
+		persistentRetryCancels: make(map[string]chan struct{}),
+		peersByPub:             make(map[string]*peer.Brontide),
+		quit:                   make(chan struct{}),
+	}
+	t.Cleanup(func() {
+		close(s.quit)
+	})
+
+	privKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	pubKey := privKey.PubKey()
+	vertex := route.NewVertex(pubKey)
+	pubKeyStr := string(vertex[:])
+
+	announcedAddr := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 9735}
+	overrideAddr := &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 9735}
+
+	s.persistentPeers[pubKeyStr] = true
+	s.persistentPeerAddrs[pubKeyStr] = []*lnwire.NetAddress{{
+		IdentityKey: pubKey,
+		Address:     announcedAddr,
+		ChainNet:    s.cfg.ActiveNetParams.Net,
+	}}
+
+	// dialedAddrs returns the addresses of the pending connection requests
+	// for the peer.
+	dialedAddrs := func() []string {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		var addrs []string
+		for _, connReq := range s.persistentConnReqs[pubKeyStr] {
+			lnAddr := connReq.Addr.(*lnwire.NetAddress)
+			addrs = append(addrs, lnAddr.Address.String())
+		}
+
+		return addrs
+	}
+	assertDialed := func(addr net.Addr) {
+		t.Helper()
+
+		require.Eventually(t, func() bool {
+			addrs := dialedAddrs()
+			return len(addrs) == 1 && addrs[0] == addr.String()
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	// Without an override, the announced address is dialed.
+	s.connectToPersistentPeer(pubKeyStr)
+	assertDialed(announcedAddr)
+
+	// Once an override is set, the pending connection request for the
+	// announced address is replaced by one for the override.
+	err = db.SetAddrOverride(vertex, &channeldb.AddrOverride{
+		Addresses: []net.Addr{overrideAddr},
+		Expiry:    testClock.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	s.refreshPersistentPeerAddrs(vertex)
+	assertDialed(overrideAddr)
+
+	// After the override expired, we fall back to the announced address.
+	testClock.SetTime(testClock.Now().Add(2 * time.Hour))
+	require.Empty(t, s.addrOverrideDialAddrs(pubKeyStr))
+
+	s.connectToPersistentPeer(pubKeyStr)
+	assertDialed(announcedAddr)
+}
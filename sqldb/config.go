@@ -23,21 +23,23 @@ const (
 //
 //nolint:lll
 type SqliteConfig struct {
-	Timeout        time.Duration `long:"timeout" description:"The time after which a database query should be timed out."`
-	BusyTimeout    time.Duration `long:"busytimeout" description:"The maximum amount of time to wait for a database connection to become available for a query."`
-	MaxConnections int           `long:"maxconnections" description:"The maximum number of open connections to the database. Set to zero for unlimited."`
-	PragmaOptions  []string      `long:"pragmaoptions" description:"A list of pragma options to set on a database connection. For example, 'auto_vacuum=incremental'. Note that the flag must be specified multiple times if multiple options are to be set."`
-	SkipMigrations bool          `long:"skipmigrations" description:"Skip applying migrations on startup."`
+	Timeout            time.Duration `long:"timeout" description:"The time after which a database query should be timed out."`
+	BusyTimeout        time.Duration `long:"busytimeout" description:"The maximum amount of time to wait for a database connection to become available for a query."`
+	MaxConnections     int           `long:"maxconnections" description:"The maximum number of open connections to the database. Set to zero for unlimited."`
+	PragmaOptions      []string      `long:"pragmaoptions" description:"A list of pragma options to set on a database connection. For example, 'auto_vacuum=incremental'. Note that the flag must be specified multiple times if multiple options are to be set."`
+	SkipMigrations     bool          `long:"skipmigrations" description:"Skip applying migrations on startup."`
+	ReadOnlyInspection bool          `long:"readonlyinspection" description:"Open the database read-only for inspection, even if its schema was written by a newer version of lnd. No migrations are applied and all writes are rejected."`
 }
 
 // PostgresConfig holds the postgres database configuration.
 //
 //nolint:lll
 type PostgresConfig struct {
-	Dsn            string        `long:"dsn" description:"Database connection string."`
-	Timeout        time.Duration `long:"timeout" description:"Database connection timeout. Set to zero to disable."`
-	MaxConnections int           `long:"maxconnections" description:"The maximum number of open connections to the database. Set to zero for unlimited."`
-	SkipMigrations bool          `long:"skipmigrations" description:"Skip applying migrations on startup."`
+	Dsn                string        `long:"dsn" description:"Database connection string."`
+	Timeout            time.Duration `long:"timeout" description:"Database connection timeout. Set to zero to disable."`
+	MaxConnections     int           `long:"maxconnections" description:"The maximum number of open connections to the database. Set to zero for unlimited."`
+	SkipMigrations     bool          `long:"skipmigrations" description:"Skip applying migrations on startup."`
+	ReadOnlyInspection bool          `long:"readonlyinspection" description:"Open the database read-only for inspection, even if its schema was written by a newer version of lnd. No migrations are applied and all writes are rejected."`
 }
 
 func (p *PostgresConfig) Validate() error {
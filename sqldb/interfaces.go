@@ -361,12 +361,20 @@ type BaseDB struct {
 	*sql.DB
 
 	*sqlc.Queries
+
+	// readOnly is true if the store was opened in read-only inspection
+	// mode, in which case all write transactions are rejected.
+	readOnly bool
 }
 
 // BeginTx wraps the normal sql specific BeginTx method with the TxOptions
 // interface. This interface is then mapped to the concrete sql tx options
 // struct.
 func (s *BaseDB) BeginTx(ctx context.Context, opts TxOptions) (*sql.Tx, error) {
+	if s.readOnly && !opts.ReadOnly() {
+		return nil, ErrReadOnlyStore
+	}
+
 	sqlOptions := sql.TxOptions{
 		Isolation: sql.LevelSerializable,
 		ReadOnly:  opts.ReadOnly(),
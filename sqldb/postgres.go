@@ -1,12 +1,15 @@
 package sqldb
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"net/url"
 	"path"
 	"strings"
 	"time"
 
+	"github.com/golang-migrate/migrate/v4/database"
 	postgres_migrate "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file" // Read migrations from files. // nolint:lll
 	"github.com/lightningnetwork/lnd/sqldb/sqlc"
@@ -21,6 +24,14 @@ var (
 	DefaultPostgresFixtureLifetime = 10 * time.Minute
 )
 
+const (
+	// postgresMigrationsTableExists is the query that returns whether the
+	// migrations table exists in the current schema of a Postgres
+	// database.
+	postgresMigrationsTableExists = "SELECT to_regclass(" +
+		"'schema_migrations') IS NOT NULL"
+)
+
 // replacePasswordInDSN takes a DSN string and returns it with the password
 // replaced by "***".
 func replacePasswordInDSN(dsn string) (string, error) {
@@ -70,6 +81,22 @@ type PostgresStore struct {
 	*BaseDB
 }
 
+// readOnlyDSN returns the given DSN with the default_transaction_read_only
+// run-time parameter set, which makes Postgres reject any write on the
+// connections opened with it.
+func readOnlyDSN(dsn string) (string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set("default_transaction_read_only", "on")
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
 // NewPostgresStore creates a new store that is backed by a Postgres database
 // backend.
 func NewPostgresStore(cfg *PostgresConfig) (*PostgresStore, error) {
@@ -84,7 +111,18 @@ func NewPostgresStore(cfg *PostgresConfig) (*PostgresStore, error) {
 		return nil, err
 	}
 
-	rawDB, err := sql.Open("pgx", cfg.Dsn)
+	// In read-only inspection mode, we let Postgres itself reject any
+	// write on every connection, so that nothing can write to the
+	// database, not even outside of our own transactions.
+	dsn := cfg.Dsn
+	if cfg.ReadOnlyInspection {
+		dsn, err = readOnlyDSN(dsn)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rawDB, err := sql.Open("pgx", dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -98,33 +136,31 @@ func NewPostgresStore(cfg *PostgresConfig) (*PostgresStore, error) {
 	rawDB.SetMaxIdleConns(maxConns)
 	rawDB.SetConnMaxLifetime(connIdleLifetime)
 
-	if !cfg.SkipMigrations {
-		// Now that the database is open, populate the database with
-		// our set of schemas based on our embedded in-memory file
-		// system.
-		//
-		// First, we'll need to open up a new migration instance for
-		// our current target database: Postgres.
-		driver, err := postgres_migrate.WithInstance(
+	postgresFS := newReplacerFS(sqlSchemas, map[string]string{
+		"BLOB":                "BYTEA",
+		"INTEGER PRIMARY KEY": "SERIAL PRIMARY KEY",
+		"BIGINT PRIMARY KEY":  "BIGSERIAL PRIMARY KEY",
+		"TIMESTAMP":           "TIMESTAMP WITHOUT TIME ZONE",
+	})
+
+	// Now that the database is open, we make sure the schema wasn't
+	// advanced by a newer version of lnd before any query runs. Unless
+	// migrations are skipped, we'll then open up a new migration instance
+	// for our current target database: Postgres, and populate the
+	// database with our set of schemas based on our embedded in-memory
+	// file system.
+	newDriver := func() (database.Driver, error) {
+		return postgres_migrate.WithInstance(
 			rawDB, &postgres_migrate.Config{},
 		)
-		if err != nil {
-			return nil, err
-		}
-
-		postgresFS := newReplacerFS(sqlSchemas, map[string]string{
-			"BLOB":                "BYTEA",
-			"INTEGER PRIMARY KEY": "SERIAL PRIMARY KEY",
-			"BIGINT PRIMARY KEY":  "BIGSERIAL PRIMARY KEY",
-			"TIMESTAMP":           "TIMESTAMP WITHOUT TIME ZONE",
-		})
-
-		err = applyMigrations(
-			postgresFS, driver, "sqlc/migrations", dbName,
-		)
-		if err != nil {
-			return nil, err
-		}
+	}
+	err = prepareSchema(
+		postgresFS, "sqlc/migrations", rawDB,
+		postgresMigrationsTableExists, newDriver, dbName,
+		cfg.SkipMigrations, cfg.ReadOnlyInspection,
+	)
+	if err != nil {
+		return nil, err
 	}
 
 	queries := sqlc.New(rawDB)
@@ -132,8 +168,78 @@ func NewPostgresStore(cfg *PostgresConfig) (*PostgresStore, error) {
 	return &PostgresStore{
 		cfg: cfg,
 		BaseDB: &BaseDB{
-			DB:      rawDB,
-			Queries: queries,
+			DB:       rawDB,
+			Queries:  queries,
+			readOnly: cfg.ReadOnlyInspection,
 		},
 	}, nil
 }
+
+// StreamQuery executes the given query and calls the passed scan function for
+// each row of the result. The rows are read from the database one by one
+// instead of being loaded into memory all at once, which makes this method
+// suitable for queries that return a large number of rows. If the scan
+// function returns an error, the iteration is stopped and the error is
+// returned.
+func (s *PostgresStore) StreamQuery(ctx context.Context, query string,
+	args []any, scan func(*sql.Rows) error) error {
+
+	rows, err := s.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	// Make sure the rows are always closed so that the connection is
+	// released back to the pool, even if we bail out early.
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+
+	// Next returns false both when all rows have been read and when an
+	// error occurred while preparing the next row, so we need to check
+	// which of the two it was.
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return rows.Close()
+}
+
+// ExecBatch executes the given statements in order within a single
+// transaction. If any of the statements fails, the transaction is rolled back
+// and the index of the failing statement is returned along with the error. If
+// all statements succeed or the transaction can't be started or committed,
+// the returned index is -1.
+func (s *PostgresStore) ExecBatch(ctx context.Context,
+	stmts []string) (int, error) {
+
+	if s.readOnly {
+		return -1, ErrReadOnlyStore
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return -1, err
+	}
+
+	for i, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			// The statement error is more useful to the caller
+			// than any error of the rollback itself.
+			_ = tx.Rollback()
+
+			return i, fmt.Errorf("statement %d failed: %w", i,
+				MapSQLError(err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return -1, err
+	}
+
+	return -1, nil
+}
@@ -24,8 +24,75 @@ const (
 	testPgPass   = "test"
 	testPgDBName = "test"
 	PostgresTag  = "11"
+
+	// pgTemplateDBName is the name of the template database that new
+	// databases are copied from if no other template is specified.
+	pgTemplateDBName = "template1"
 )
 
+// pgFixtureOptions holds the optional settings of a TestPgFixture.
+type pgFixtureOptions struct {
+	// sharedBuffers is the value of the shared_buffers setting of the
+	// Postgres server. If empty, the Postgres default (128MB) is used.
+	sharedBuffers string
+
+	// workMem is the value of the work_mem setting of the Postgres server.
+	// If empty, the Postgres default (4MB) is used.
+	workMem string
+}
+
+// PgFixtureOption is a functional option that allows us to pass in optional
+// arguments when creating a TestPgFixture.
+type PgFixtureOption func(*pgFixtureOptions)
+
+// WithSharedBuffers is a functional option that sets the shared_buffers
+// setting of the Postgres server, e.g. "32MB". This can be used to run
+// Postgres with constrained memory.
+func WithSharedBuffers(sharedBuffers string) PgFixtureOption {
+	return func(o *pgFixtureOptions) {
+		o.sharedBuffers = sharedBuffers
+	}
+}
+
+// WithWorkMem is a functional option that sets the work_mem setting of the
+// Postgres server, e.g. "64kB". This can be used to run Postgres with
+// constrained memory.
+func WithWorkMem(workMem string) PgFixtureOption {
+	return func(o *pgFixtureOptions) {
+		o.workMem = workMem
+	}
+}
+
+// pgFixtureCmd returns the command used to start the Postgres server of a
+// fixture created with the passed options.
+func pgFixtureCmd(opts ...PgFixtureOption) []string {
+	var fixtureOpts pgFixtureOptions
+	for _, optFunc := range opts {
+		optFunc(&fixtureOpts)
+	}
+
+	cmd := []string{
+		"postgres",
+		"-c", "log_statement=all",
+		"-c", "log_destination=stderr",
+		"-c", "max_connections=1000",
+	}
+
+	if fixtureOpts.sharedBuffers != "" {
+		cmd = append(cmd, "-c", fmt.Sprintf(
+			"shared_buffers=%v", fixtureOpts.sharedBuffers,
+		))
+	}
+
+	if fixtureOpts.workMem != "" {
+		cmd = append(cmd, "-c", fmt.Sprintf(
+			"work_mem=%v", fixtureOpts.workMem,
+		))
+	}
+
+	return cmd
+}
+
 // TestPgFixture is a test fixture that starts a Postgres 11 instance in a
 // docker container.
 type TestPgFixture struct {
@@ -38,8 +105,11 @@ type TestPgFixture struct {
 
 // NewTestPgFixture constructs a new TestPgFixture starting up a docker
 // container running Postgres 11. The started container will expire in after
-// the passed duration.
-func NewTestPgFixture(t *testing.T, expiry time.Duration) *TestPgFixture {
+// the passed duration. By default, the Postgres server runs with its default
+// memory settings, which can be overridden using the passed options.
+func NewTestPgFixture(t *testing.T, expiry time.Duration,
+	opts ...PgFixtureOption) *TestPgFixture {
+
 	// Use a sensible default on Windows (tcp/http) and linux/osx (socket)
 	// by specifying an empty endpoint.
 	pool, err := dockertest.NewPool("")
@@ -55,12 +125,7 @@ func NewTestPgFixture(t *testing.T, expiry time.Duration) *TestPgFixture {
 			fmt.Sprintf("POSTGRES_DB=%v", testPgDBName),
 			"listen_addresses='*'",
 		},
-		Cmd: []string{
-			"postgres",
-			"-c", "log_statement=all",
-			"-c", "log_destination=stderr",
-			"-c", "max_connections=1000",
-		},
+		Cmd: pgFixtureCmd(opts...),
 	}, func(config *docker.HostConfig) {
 		// Set AutoRemove to true so that stopped container goes away
 		// by itself.
@@ -118,6 +183,57 @@ func (f *TestPgFixture) GetConfig(dbName string) *PostgresConfig {
 	}
 }
 
+// ExecAdmin executes the given statement as the admin user of the Postgres
+// server, using the fixture's admin connection. This can be used to set up
+// server-wide objects like roles or tablespaces that a test relies on, e.g.
+// "CREATE ROLE reader".
+func (f *TestPgFixture) ExecAdmin(ctx context.Context, query string,
+	args ...any) (sql.Result, error) {
+
+	return f.db.ExecContext(ctx, query, args...)
+}
+
+// ExecTemplate executes the given statement as the admin user in the template
+// database that new databases are copied from. Database-local objects like
+// extensions created this way, e.g. with "CREATE EXTENSION pgcrypto", are
+// available in every test database that is created afterwards.
+func (f *TestPgFixture) ExecTemplate(ctx context.Context, query string,
+	args ...any) (sql.Result, error) {
+
+	templateDB, err := sql.Open(
+		"postgres", f.GetConfig(pgTemplateDBName).Dsn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// We need to close the connection to the template database once
+	// we're done, as Postgres refuses to create new databases while the
+	// template database is being accessed.
+	defer templateDB.Close()
+
+	return templateDB.ExecContext(ctx, query, args...)
+}
+
+// RawDB returns a connection to the given database of the Postgres server
+// that bypasses the store abstraction, for tests that need to run arbitrary
+// SQL. The database must already exist. The connection is closed once the
+// test finishes.
+func (f *TestPgFixture) RawDB(t *testing.T, dbName string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("postgres", f.GetConfig(dbName).Dsn)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+
+	require.NoError(t, db.Ping())
+
+	return db
+}
+
 // TearDown stops the underlying docker container.
 func (f *TestPgFixture) TearDown(t *testing.T) {
 	err := f.pool.Purge(f.resource)
@@ -0,0 +1,330 @@
+//go:build !js && !(windows && (arm || 386)) && !(linux && (ppc64 || mips || mipsle || mips64)) && !(netbsd || openbsd)
+
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestPgFixtureCmd asserts that the memory settings passed as fixture options
+// are appended to the command used to start the Postgres server.
+func TestPgFixtureCmd(t *testing.T) {
+	t.Parallel()
+
+	defaultCmd := []string{
+		"postgres",
+		"-c", "log_statement=all",
+		"-c", "log_destination=stderr",
+		"-c", "max_connections=1000",
+	}
+
+	// Without any options we should keep the Postgres defaults.
+	require.Equal(t, defaultCmd, pgFixtureCmd())
+
+	cmd := pgFixtureCmd(WithSharedBuffers("32MB"), WithWorkMem("64kB"))
+	require.Equal(t, append(
+		defaultCmd,
+		"-c", "shared_buffers=32MB",
+		"-c", "work_mem=64kB",
+	), cmd)
+
+	cmd = pgFixtureCmd(WithWorkMem("1MB"))
+	require.Equal(t, append(defaultCmd, "-c", "work_mem=1MB"), cmd)
+}
+
+// TestPgFixtureExecAdmin asserts that statements executed using ExecAdmin run
+// as the admin user on the fixture's admin connection.
+func TestPgFixtureExecAdmin(t *testing.T) {
+	t.Parallel()
+
+	pgFixture := NewTestPgFixture(t, DefaultPostgresFixtureLifetime)
+	t.Cleanup(func() {
+		pgFixture.TearDown(t)
+	})
+
+	ctx := context.Background()
+
+	_, err := pgFixture.ExecAdmin(ctx, "CREATE ROLE test_reader")
+	require.NoError(t, err)
+
+	// Roles are server-wide, so the role should be visible from any
+	// database.
+	store := NewTestPostgresDB(t, pgFixture)
+
+	var roleName string
+	err = store.QueryRowContext(
+		ctx, "SELECT rolname FROM pg_roles WHERE rolname = $1",
+		"test_reader",
+	).Scan(&roleName)
+	require.NoError(t, err)
+	require.Equal(t, "test_reader", roleName)
+
+	// The statement must have run on the admin connection's database
+	// rather than on the template database.
+	var dbName string
+	err = pgFixture.db.QueryRowContext(
+		ctx, "SELECT current_database()",
+	).Scan(&dbName)
+	require.NoError(t, err)
+
+	_, err = pgFixture.ExecAdmin(
+		ctx, "CREATE TABLE exec_admin_test (id INTEGER)",
+	)
+	require.NoError(t, err)
+
+	var tableDB string
+	err = pgFixture.db.QueryRowContext(
+		ctx, "SELECT table_catalog FROM information_schema.tables "+
+			"WHERE table_name = $1", "exec_admin_test",
+	).Scan(&tableDB)
+	require.NoError(t, err)
+	require.Equal(t, dbName, tableDB)
+	require.NotEqual(t, pgTemplateDBName, tableDB)
+}
+
+// TestPgFixtureExecTemplate asserts that extensions created using
+// ExecTemplate are available in test databases created afterwards.
+func TestPgFixtureExecTemplate(t *testing.T) {
+	t.Parallel()
+
+	pgFixture := NewTestPgFixture(t, DefaultPostgresFixtureLifetime)
+	t.Cleanup(func() {
+		pgFixture.TearDown(t)
+	})
+
+	ctx := context.Background()
+
+	_, err := pgFixture.ExecTemplate(ctx, "CREATE EXTENSION pgcrypto")
+	require.NoError(t, err)
+
+	store := NewTestPostgresDB(t, pgFixture)
+
+	var extName string
+	err = store.QueryRowContext(
+		ctx, "SELECT extname FROM pg_extension WHERE extname = $1",
+		"pgcrypto",
+	).Scan(&extName)
+	require.NoError(t, err)
+	require.Equal(t, "pgcrypto", extName)
+
+	// The functions of the extension should be usable right away.
+	var digest []byte
+	err = store.QueryRowContext(
+		ctx, "SELECT digest('lnd', 'sha256')",
+	).Scan(&digest)
+	require.NoError(t, err)
+	require.Len(t, digest, 32)
+}
+
+// TestPgFixtureRawDB asserts that RawDB returns a connection to the given
+// database that can be used to run arbitrary SQL.
+func TestPgFixtureRawDB(t *testing.T) {
+	t.Parallel()
+
+	pgFixture := NewTestPgFixture(t, DefaultPostgresFixtureLifetime)
+	t.Cleanup(func() {
+		pgFixture.TearDown(t)
+	})
+
+	ctx := context.Background()
+
+	const dbName = "test_raw_db"
+	_, err := pgFixture.db.ExecContext(ctx, "CREATE DATABASE "+dbName)
+	require.NoError(t, err)
+
+	db := pgFixture.RawDB(t, dbName)
+
+	var name string
+	err = db.QueryRowContext(ctx, "SELECT current_database()").Scan(&name)
+	require.NoError(t, err)
+	require.Equal(t, dbName, name)
+
+	var sum int
+	err = db.QueryRowContext(ctx, "SELECT $1::int + $2::int", 2, 3).Scan(
+		&sum,
+	)
+	require.NoError(t, err)
+	require.Equal(t, 5, sum)
+}
+
+// TestPostgresStreamQuery asserts that StreamQuery passes every row of a large
+// result to the scan function and stops as soon as it returns an error.
+func TestPostgresStreamQuery(t *testing.T) {
+	t.Parallel()
+
+	pgFixture := NewTestPgFixture(t, DefaultPostgresFixtureLifetime)
+	t.Cleanup(func() {
+		pgFixture.TearDown(t)
+	})
+
+	store := NewTestPostgresDB(t, pgFixture)
+
+	const (
+		numRows = 10_000
+		query   = "SELECT generate_series(1, $1)"
+	)
+
+	ctx := context.Background()
+
+	// Stream all the rows and make sure we've seen each of them exactly
+	// once and in order.
+	var count int64
+	err := store.StreamQuery(
+		ctx, query, []any{numRows}, func(rows *sql.Rows) error {
+			var n int64
+			if err := rows.Scan(&n); err != nil {
+				return err
+			}
+
+			count++
+			if n != count {
+				return fmt.Errorf("expected row %d, got %d",
+					count, n)
+			}
+
+			return nil
+		},
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, numRows, count)
+
+	// An error returned by the scan function should stop the iteration
+	// and be returned to the caller.
+	errStop := errors.New("stop")
+	count = 0
+	err = store.StreamQuery(
+		ctx, query, []any{numRows}, func(rows *sql.Rows) error {
+			count++
+			if count == 10 {
+				return errStop
+			}
+
+			return nil
+		},
+	)
+	require.ErrorIs(t, err, errStop)
+	require.EqualValues(t, 10, count)
+}
+
+// TestPgSchemaVersionGuard asserts that a Postgres database whose schema was
+// advanced past the latest known migration is refused, unless it is opened in
+// read-only inspection mode.
+func TestPgSchemaVersionGuard(t *testing.T) {
+	t.Parallel()
+
+	pgFixture := NewTestPgFixture(t, DefaultPostgresFixtureLifetime)
+	t.Cleanup(func() {
+		pgFixture.TearDown(t)
+	})
+
+	ctx := context.Background()
+
+	const dbName = "test_schema_guard"
+	_, err := pgFixture.db.ExecContext(ctx, "CREATE DATABASE "+dbName)
+	require.NoError(t, err)
+
+	latest, err := latestMigrationVersion(sqlSchemas, "sqlc/migrations")
+	require.NoError(t, err)
+
+	cfg := pgFixture.GetConfig(dbName)
+	store, err := NewPostgresStore(cfg)
+	require.NoError(t, err)
+
+	// Simulate a newer version of lnd having migrated the database to a
+	// schema version we don't know about.
+	futureVersion := latest + 1
+	_, err = store.DB.ExecContext(
+		ctx, "UPDATE schema_migrations SET version = $1",
+		futureVersion,
+	)
+	require.NoError(t, err)
+	require.NoError(t, store.DB.Close())
+
+	_, err = NewPostgresStore(cfg)
+
+	var errTooNew *ErrSchemaTooNew
+	require.ErrorAs(t, err, &errTooNew)
+	require.Equal(t, futureVersion, errTooNew.DBVersion)
+	require.Equal(t, latest, errTooNew.LatestVersion)
+
+	// In read-only inspection mode, the store can be opened but write
+	// transactions are rejected.
+	cfg.ReadOnlyInspection = true
+	store, err = NewPostgresStore(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.DB.Close())
+	})
+
+	tx, err := store.BeginTx(ctx, &testTxOptions{readOnly: true})
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	_, err = store.BeginTx(ctx, &testTxOptions{})
+	require.ErrorIs(t, err, ErrReadOnlyStore)
+
+	// Writes that bypass our transactions are rejected by Postgres itself.
+	_, err = store.DB.ExecContext(ctx, "DELETE FROM schema_migrations")
+	require.Error(t, err)
+}
+
+// TestPostgresExecBatch asserts that ExecBatch reports the index of the
+// failing statement and rolls back the statements executed before it.
+func TestPostgresExecBatch(t *testing.T) {
+	t.Parallel()
+
+	pgFixture := NewTestPgFixture(t, DefaultPostgresFixtureLifetime)
+	t.Cleanup(func() {
+		pgFixture.TearDown(t)
+	})
+
+	store := NewTestPostgresDB(t, pgFixture)
+	ctx := context.Background()
+
+	_, err := store.ExecContext(
+		ctx, "CREATE TABLE batch_test (id INTEGER PRIMARY KEY)",
+	)
+	require.NoError(t, err)
+
+	countRows := func() int {
+		var count int
+		err := store.QueryRowContext(
+			ctx, "SELECT COUNT(*) FROM batch_test",
+		).Scan(&count)
+		require.NoError(t, err)
+
+		return count
+	}
+
+	// The third of the four statements violates the primary key
+	// constraint, so none of the rows should be inserted.
+	failedIndex, err := store.ExecBatch(ctx, []string{
+		"INSERT INTO batch_test (id) VALUES (1)",
+		"INSERT INTO batch_test (id) VALUES (2)",
+		"INSERT INTO batch_test (id) VALUES (1)",
+		"INSERT INTO batch_test (id) VALUES (3)",
+	})
+	require.Error(t, err)
+	require.Equal(t, 2, failedIndex)
+
+	var errUnique *ErrSQLUniqueConstraintViolation
+	require.ErrorAs(t, err, &errUnique)
+	require.Zero(t, countRows())
+
+	// A batch without any failing statements is committed as a whole.
+	failedIndex, err = store.ExecBatch(ctx, []string{
+		"INSERT INTO batch_test (id) VALUES (1)",
+		"INSERT INTO batch_test (id) VALUES (2)",
+		"INSERT INTO batch_test (id) VALUES (3)",
+		"INSERT INTO batch_test (id) VALUES (4)",
+	})
+	require.NoError(t, err)
+	require.Equal(t, -1, failedIndex)
+	require.Equal(t, 4, countRows())
+}
@@ -0,0 +1,183 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+var (
+	// schemaReleases maps each schema version to the first lnd release
+	// that ships it. It must be extended whenever a migration is added,
+	// so that a binary that finds a schema it doesn't know can name the
+	// lnd release that is required to open the database.
+	schemaReleases = map[uint]string{
+		1: "v0.18.0-beta",
+		2: "v0.18.0-beta",
+		3: "v0.18.0-beta",
+	}
+
+	// ErrReadOnlyStore is returned when a write transaction is started on
+	// a store that was opened in read-only inspection mode.
+	ErrReadOnlyStore = errors.New("database store is opened in read-only " +
+		"inspection mode")
+)
+
+// ErrSchemaTooNew is returned when the schema of a database has been advanced
+// by a newer version of lnd than the one that is trying to open it.
+type ErrSchemaTooNew struct {
+	// DBVersion is the schema version recorded in the database.
+	DBVersion uint
+
+	// LatestVersion is the latest schema version known to this binary.
+	LatestVersion uint
+
+	// LatestRelease is the lnd release that introduced LatestVersion. As
+	// DBVersion was introduced after it, a release newer than this one is
+	// the minimum that is required to open the database.
+	LatestRelease string
+}
+
+// Error returns the error message.
+func (e *ErrSchemaTooNew) Error() string {
+	return fmt.Sprintf("database schema version %d is newer than the "+
+		"latest version %d supported by this binary: lnd newer than "+
+		"%s is required to open it, or the database can be opened "+
+		"with readonlyinspection for inspection only", e.DBVersion,
+		e.LatestVersion, e.LatestRelease)
+}
+
+// latestMigrationVersion returns the highest version of the migrations found
+// in the given file system under the given path.
+func latestMigrationVersion(migrationFS fs.FS, path string) (uint, error) {
+	entries, err := fs.ReadDir(migrationFS, path)
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		m, err := source.DefaultParse(entry.Name())
+		if err != nil {
+			return 0, fmt.Errorf("unable to parse migration file "+
+				"name %v: %w", entry.Name(), err)
+		}
+
+		if m.Version > latest {
+			latest = m.Version
+		}
+	}
+
+	return latest, nil
+}
+
+// schemaVersion returns the schema version recorded by the migration tool in
+// the given database, or database.NilVersion if none is recorded yet. The
+// given tableExistsQuery must return whether the migrations table exists. This
+// allows the version to be read without creating the migrations table, which
+// creating a migration driver would do.
+func schemaVersion(db *sql.DB, tableExistsQuery string) (int, error) {
+	ctx := context.Background()
+
+	var exists bool
+	err := db.QueryRowContext(ctx, tableExistsQuery).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("unable to look up migrations table: %w",
+			err)
+	}
+
+	if !exists {
+		return database.NilVersion, nil
+	}
+
+	var version int64
+	err = db.QueryRowContext(
+		ctx, "SELECT version FROM schema_migrations LIMIT 1",
+	).Scan(&version)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return database.NilVersion, nil
+
+	case err != nil:
+		return 0, fmt.Errorf("unable to read schema version: %w", err)
+	}
+
+	return int(version), nil
+}
+
+// checkSchemaVersion compares the schema version recorded in the given
+// database with the latest migration found in the given file system. An
+// ErrSchemaTooNew error is returned if the database was migrated past the
+// latest version that this binary knows about, as any query run against such
+// a schema might fail in unexpected ways.
+func checkSchemaVersion(migrationFS fs.FS, path string, db *sql.DB,
+	tableExistsQuery string) error {
+
+	latest, err := latestMigrationVersion(migrationFS, path)
+	if err != nil {
+		return err
+	}
+
+	version, err := schemaVersion(db, tableExistsQuery)
+	if err != nil {
+		return err
+	}
+
+	// A fresh database doesn't have any version recorded yet.
+	if version == database.NilVersion {
+		return nil
+	}
+
+	if uint(version) > latest {
+		return &ErrSchemaTooNew{
+			DBVersion:     uint(version),
+			LatestVersion: latest,
+			LatestRelease: schemaReleases[latest],
+		}
+	}
+
+	return nil
+}
+
+// prepareSchema makes sure the schema of the given database can be used by
+// this binary, applying all migrations found in the given file system under
+// the given path unless skipMigrations is set. The migration driver is only
+// created through newDriver if migrations are applied, as creating it already
+// writes to the database. If readOnly is set, no migrations are applied and a
+// database written by a newer version of lnd is accepted, as it won't be
+// modified.
+func prepareSchema(migrationFS fs.FS, path string, db *sql.DB,
+	tableExistsQuery string, newDriver func() (database.Driver, error),
+	dbName string, skipMigrations, readOnly bool) error {
+
+	err := checkSchemaVersion(migrationFS, path, db, tableExistsQuery)
+
+	var errTooNew *ErrSchemaTooNew
+	switch {
+	case errors.As(err, &errTooNew) && readOnly:
+		log.Warnf("Opening database for read-only inspection: %v", err)
+
+	case err != nil:
+		return err
+	}
+
+	if skipMigrations || readOnly {
+		return nil
+	}
+
+	driver, err := newDriver()
+	if err != nil {
+		return err
+	}
+
+	return applyMigrations(migrationFS, driver, path, dbName)
+}
@@ -0,0 +1,141 @@
+//go:build !js && !(windows && (arm || 386)) && !(linux && (ppc64 || mips || mipsle || mips64))
+
+package sqldb
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testTxOptions is a simple implementation of the TxOptions interface.
+type testTxOptions struct {
+	readOnly bool
+}
+
+// ReadOnly returns true if the transaction should be read only.
+//
+// NOTE: This is part of the TxOptions interface.
+func (t *testTxOptions) ReadOnly() bool {
+	return t.readOnly
+}
+
+// TestSchemaReleases asserts that every migration is mapped to the lnd
+// release that ships it.
+func TestSchemaReleases(t *testing.T) {
+	t.Parallel()
+
+	latest, err := latestMigrationVersion(sqlSchemas, "sqlc/migrations")
+	require.NoError(t, err)
+
+	for version := uint(1); version <= latest; version++ {
+		require.NotEmptyf(t, schemaReleases[version], "no lnd release "+
+			"for schema version %d", version)
+	}
+}
+
+// TestSchemaVersionGuard asserts that a database whose schema was advanced past
+// the latest known migration is refused, unless it is opened in read-only
+// inspection mode.
+func TestSchemaVersionGuard(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "tmp.db")
+
+	latest, err := latestMigrationVersion(sqlSchemas, "sqlc/migrations")
+	require.NoError(t, err)
+	require.NotZero(t, latest)
+
+	// Opening a fresh database applies all migrations, and opening it
+	// again shouldn't be a problem either.
+	store, err := NewSqliteStore(&SqliteConfig{}, dbPath)
+	require.NoError(t, err)
+	require.NoError(t, store.DB.Close())
+
+	store, err = NewSqliteStore(&SqliteConfig{}, dbPath)
+	require.NoError(t, err)
+
+	// Now we simulate a newer version of lnd having migrated the
+	// database to a schema version we don't know about.
+	futureVersion := latest + 1
+	_, err = store.DB.ExecContext(
+		ctx, "UPDATE schema_migrations SET version = $1",
+		futureVersion,
+	)
+	require.NoError(t, err)
+	require.NoError(t, store.DB.Close())
+
+	// Opening the database should now fail with an error naming both
+	// versions.
+	_, err = NewSqliteStore(&SqliteConfig{}, dbPath)
+
+	var errTooNew *ErrSchemaTooNew
+	require.ErrorAs(t, err, &errTooNew)
+	require.Equal(t, futureVersion, errTooNew.DBVersion)
+	require.Equal(t, latest, errTooNew.LatestVersion)
+	require.Equal(t, schemaReleases[latest], errTooNew.LatestRelease)
+	require.ErrorContains(t, err, schemaReleases[latest])
+
+	// Skipping the migrations doesn't get around the guard either.
+	_, err = NewSqliteStore(&SqliteConfig{SkipMigrations: true}, dbPath)
+	require.ErrorAs(t, err, &errTooNew)
+
+	// In read-only inspection mode, the database can be opened and read
+	// from, but all writes are rejected.
+	store, err = NewSqliteStore(
+		&SqliteConfig{ReadOnlyInspection: true}, dbPath,
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.DB.Close())
+	})
+
+	tx, err := store.BeginTx(ctx, &testTxOptions{readOnly: true})
+	require.NoError(t, err)
+
+	var version uint
+	err = tx.QueryRowContext(
+		ctx, "SELECT version FROM schema_migrations",
+	).Scan(&version)
+	require.NoError(t, err)
+	require.Equal(t, futureVersion, version)
+	require.NoError(t, tx.Rollback())
+
+	_, err = store.BeginTx(ctx, &testTxOptions{})
+	require.ErrorIs(t, err, ErrReadOnlyStore)
+
+	// Writes that bypass our transactions are rejected by sqlite itself.
+	_, err = store.DB.ExecContext(ctx, "DELETE FROM schema_migrations")
+	require.Error(t, err)
+}
+
+// TestSchemaVersionGuardNoMigrationsTable asserts that opening a fresh
+// database without applying migrations doesn't create the migrations table.
+func TestSchemaVersionGuardNoMigrationsTable(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	cfgs := []*SqliteConfig{
+		{SkipMigrations: true},
+		{ReadOnlyInspection: true},
+	}
+	for _, cfg := range cfgs {
+		dbPath := filepath.Join(t.TempDir(), "tmp.db")
+
+		store, err := NewSqliteStore(cfg, dbPath)
+		require.NoError(t, err)
+
+		var exists bool
+		err = store.DB.QueryRowContext(
+			ctx, sqliteMigrationsTableExists,
+		).Scan(&exists)
+		require.NoError(t, err)
+		require.False(t, exists)
+
+		require.NoError(t, store.DB.Close())
+	}
+}
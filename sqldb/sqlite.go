@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/golang-migrate/migrate/v4/database"
 	sqlite_migrate "github.com/golang-migrate/migrate/v4/database/sqlite"
 	"github.com/lightningnetwork/lnd/sqldb/sqlc"
 	"github.com/stretchr/testify/require"
@@ -24,6 +25,12 @@ const (
 	// sqliteTxLockImmediate is a dsn option used to ensure that write
 	// transactions are started immediately.
 	sqliteTxLockImmediate = "_txlock=immediate"
+
+	// sqliteMigrationsTableExists is the query that returns whether the
+	// migrations table exists in a sqlite database.
+	sqliteMigrationsTableExists = "SELECT EXISTS (SELECT 1 FROM " +
+		"sqlite_master WHERE type = 'table' AND " +
+		"name = 'schema_migrations')"
 )
 
 // SqliteStore is a database store implementation that uses a sqlite backend.
@@ -79,6 +86,13 @@ func NewSqliteStore(cfg *SqliteConfig, dbPath string) (*SqliteStore, error) {
 		)
 	}
 
+	// In read-only inspection mode, we let sqlite itself reject any change
+	// to the database on every connection, so that nothing can write to
+	// it, not even outside of our own transactions.
+	if cfg.ReadOnlyInspection {
+		sqliteOptions.Add(sqliteOptionPrefix, "query_only=on")
+	}
+
 	// Construct the DSN which is just the database file name, appended
 	// with the series of pragma options as a query URL string. For more
 	// details on the formatting here, see the modernc.org/sqlite docs:
@@ -96,34 +110,31 @@ func NewSqliteStore(cfg *SqliteConfig, dbPath string) (*SqliteStore, error) {
 	db.SetMaxIdleConns(defaultMaxConns)
 	db.SetConnMaxLifetime(connIdleLifetime)
 
-	if !cfg.SkipMigrations {
-		// Now that the database is open, populate the database with
-		// our set of schemas based on our embedded in-memory file
-		// system.
-		//
-		// First, we'll need to open up a new migration instance for
-		// our current target database: sqlite.
-		driver, err := sqlite_migrate.WithInstance(
+	// We use INTEGER PRIMARY KEY for sqlite, because it acts as a ROWID
+	// alias which is 8 bytes big and also autoincrements. It's important
+	// to use the ROWID as a primary key because the key look ups are
+	// almost twice as fast
+	sqliteFS := newReplacerFS(sqlSchemas, map[string]string{
+		"BIGINT PRIMARY KEY": "INTEGER PRIMARY KEY",
+	})
+
+	// Now that the database is open, we make sure the schema wasn't
+	// advanced by a newer version of lnd before any query runs. Unless
+	// migrations are skipped, we'll then open up a new migration instance
+	// for our current target database: sqlite, and populate the database
+	// with our set of schemas based on our embedded in-memory file
+	// system.
+	newDriver := func() (database.Driver, error) {
+		return sqlite_migrate.WithInstance(
 			db, &sqlite_migrate.Config{},
 		)
-		if err != nil {
-			return nil, err
-		}
-
-		// We use INTEGER PRIMARY KEY for sqlite, because it acts as a
-		// ROWID alias which is 8 bytes big and also autoincrements.
-		// It's important to use the ROWID as a primary key because the
-		// key look ups are almost twice as fast
-		sqliteFS := newReplacerFS(sqlSchemas, map[string]string{
-			"BIGINT PRIMARY KEY": "INTEGER PRIMARY KEY",
-		})
-
-		err = applyMigrations(
-			sqliteFS, driver, "sqlc/migrations", "sqlc",
-		)
-		if err != nil {
-			return nil, err
-		}
+	}
+	err = prepareSchema(
+		sqliteFS, "sqlc/migrations", db, sqliteMigrationsTableExists,
+		newDriver, "sqlc", cfg.SkipMigrations, cfg.ReadOnlyInspection,
+	)
+	if err != nil {
+		return nil, err
 	}
 
 	queries := sqlc.New(db)
@@ -131,8 +142,9 @@ func NewSqliteStore(cfg *SqliteConfig, dbPath string) (*SqliteStore, error) {
 	return &SqliteStore{
 		cfg: cfg,
 		BaseDB: &BaseDB{
-			DB:      db,
-			Queries: queries,
+			DB:       db,
+			Queries:  queries,
+			readOnly: cfg.ReadOnlyInspection,
 		},
 	}, nil
 }
@@ -265,6 +265,12 @@ func (s *subRPCServerConfigs) PopulateDependencies(cfg *Config,
 			subCfgValue.FieldByName("GetAlias").Set(
 				reflect.ValueOf(getAlias),
 			)
+			subCfgValue.FieldByName("MaxMemoSize").Set(
+				reflect.ValueOf(int(cfg.Invoices.MaxMemoSize)),
+			)
+			subCfgValue.FieldByName("TruncateMemo").Set(
+				reflect.ValueOf(cfg.Invoices.TruncateMemo),
+			)
 
 		case *neutrinorpc.Config:
 			subCfgValue := extractReflectValue(subCfg)